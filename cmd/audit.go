@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tatun2000/bitcoin-testnet-wallet/infrastructure"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+var auditCommand = &cobra.Command{
+	Use:   "audit",
+	Short: "audit log commands.",
+	Long:  "audit log commands, the audit log records address derivations, send attempts, signed transactions and broadcast results.",
+}
+
+var auditShowCommand = &cobra.Command{
+	Use:                   "show",
+	Short:                 "show audit log records.",
+	Long:                  "show audit log records, oldest first.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		records, err := infrastructure.App.InjectAuditService().Records()
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		if limit > 0 && len(records) > limit {
+			records = records[len(records)-limit:]
+		}
+
+		return printResult(records, func() error {
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "SEQ\tTIME\tEVENT\tDATA")
+			for _, record := range records {
+				fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", record.Seq, time.Unix(record.Time, 0).UTC().Format(time.DateTime), record.Event, record.Data)
+			}
+
+			return writer.Flush()
+		})
+	},
+}
+
+var auditVerifyCommand = &cobra.Command{
+	Use:                   "verify",
+	Short:                 "verify audit log hash chain.",
+	Long:                  "verify that audit log records aren't changed, removed or reordered.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		count, err := infrastructure.App.InjectAuditService().Verify()
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		fmt.Fprintf(os.Stdout, "Audit log is valid: %d records\n", count)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCommand.AddCommand(auditCommand)
+	auditCommand.AddCommand(auditShowCommand)
+	auditCommand.AddCommand(auditVerifyCommand)
+
+	auditShowCommand.Flags().Int("limit", 0, "show only the last records (default all)")
+}
+
+func auditResetFlags() {
+	auditCommand.Flags().Set("help", "")       //nolint:errcheck // err can be always
+	auditShowCommand.Flags().Set("help", "")   //nolint:errcheck // err can be always
+	auditVerifyCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
+
+	auditShowCommand.Flags().Set("limit", "0") //nolint:errcheck // err can be always
+}
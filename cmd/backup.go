@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tatun2000/bitcoin-testnet-wallet/infrastructure"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/utils"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+var walletBackupCommand = &cobra.Command{
+	Use:   "backup",
+	Short: "write encrypted wallet backup.",
+	Long: utils.GenLongMessage("Write wallet backup encrypted by password: mnemonic, descriptors and address indices. "+
+		"secretPassphrase isn't saved, keep it separately", map[string]entities.HelpArg{
+		"file": {
+			Description: "Backup file, it mustn't exist",
+			SeqNumber:   1,
+			Required:    true,
+		},
+	}),
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hint, err := cmd.Flags().GetString("passphrase-hint")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		password, err := askSecret("Backup password: ", constants.BackupPasswordEnv, true)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		result, err := infrastructure.App.InjectBackupService().Backup(cmd.Context(), args[0], password, hint)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(result, func() error {
+			fmt.Fprintf(os.Stdout, "Backup is written to %s\n\tAddress: %s\n\tVersion: %d\n", result.Path, result.Address, result.Version)
+			fmt.Fprintln(os.Stdout, "Keep the backup password and secretPassphrase: the wallet can't be restored without them")
+			return nil
+		})
+	},
+}
+
+var walletRestoreCommand = &cobra.Command{
+	Use:   "restore",
+	Short: "restore wallet from backup.",
+	Long: utils.GenLongMessage("Replace the wallet by backup and rescan it. "+
+		"secretPassphrase in config must be the passphrase of the backed up wallet", map[string]entities.HelpArg{
+		"file": {
+			Description: "Backup file written by wallet backup",
+			SeqNumber:   1,
+			Required:    true,
+		},
+	}),
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		if !yes {
+			ok, err := askConfirmation("The current wallet will be replaced. Restore?")
+			if err != nil {
+				return wrap.Wrap(err)
+			}
+			if !ok {
+				fmt.Fprintln(os.Stderr, "Restore canceled")
+				return nil
+			}
+		}
+
+		password, err := askSecret("Backup password: ", constants.BackupPasswordEnv, false)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		result, err := infrastructure.App.InjectBackupService().Restore(cmd.Context(), args[0], password)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(result, func() error {
+			fmt.Fprintf(os.Stdout, "Wallet is restored: %s\n\t\tAvailable: %d satoshi\n\t\tOn hold: %d satoshi\n\t\tTransactions: %d\n",
+				result.Address, result.Confirmed, result.Unconfirmed, result.Transactions)
+			return nil
+		})
+	},
+}
+
+func init() {
+	walletCommand.AddCommand(walletBackupCommand)
+	walletCommand.AddCommand(walletRestoreCommand)
+
+	walletBackupCommand.Flags().String("passphrase-hint", "", "hint of secretPassphrase saved in the backup")
+	walletRestoreCommand.Flags().BoolP("yes", "y", false, "restore without confirmation")
+}
+
+func backupResetFlags() {
+	walletBackupCommand.Flags().Set("help", "")  //nolint:errcheck // err can be always
+	walletRestoreCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
+
+	walletBackupCommand.Flags().Set("passphrase-hint", "") //nolint:errcheck // err can be always
+	walletRestoreCommand.Flags().Set("yes", "false")       //nolint:errcheck // err can be always
+}
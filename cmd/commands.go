@@ -1,18 +1,35 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"strings"
 
+	"github.com/chzyer/readline"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
 	"github.com/tatun2000/golang-lib/pkg/wrap"
 )
 
-func ExecuteCommand(command string) (err error) {
-	rootCommand.SetArgs(strings.Fields(command))
-	if err := rootCommand.Execute(); err != nil {
+func ExecuteCommand(ctx context.Context, command string) (err error) {
+	if err := ExecuteArgs(ctx, strings.Fields(command)); err != nil {
 		return wrap.Wrap(err)
 	}
 
-	resetHelpFlags()
+	return nil
+}
+
+func ExecuteArgs(ctx context.Context, args []string) (err error) {
+	rootCommand.SetArgs(args)
+	defer cancelCommandTimeout()
+	// flags of a failed command mustn't leak into the next one, e.g. --yes of a failed send
+	defer resetHelpFlags()
+	if err := rootCommand.ExecuteContext(ctx); err != nil {
+		return wrap.Wrap(err)
+	}
 
 	return nil
 }
@@ -20,5 +37,86 @@ func ExecuteCommand(command string) (err error) {
 func resetHelpFlags() {
 	rootCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
 
+	outputResetFlags()
+	timeoutResetFlags()
 	walletResetFlags()
+	txResetFlags()
+	watchResetFlags()
+	auditResetFlags()
+	backupResetFlags()
+	recoverResetFlags()
+	sharesResetFlags()
+}
+
+// askConfirmation asks user a yes/no question in the shell or stdin, default answer is no.
+func askConfirmation(question string) (ok bool, err error) {
+	var line string
+	if shell != nil {
+		shell.SetPrompt(fmt.Sprintf("%s [y/N]: ", question))
+		defer shell.SetPrompt(fmt.Sprintf("%s> ", constants.WalletShell))
+
+		line, err = shell.Readline()
+		if err != nil {
+			return false, wrap.Wrap(err)
+		}
+	} else {
+		// prompt isn't a part of the command result
+		fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+
+		line, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return false, wrap.Wrap(fmt.Errorf("confirmation isn't received (use --yes to skip it): %w", err))
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// askSecret reads a password or mnemonic without echo in the shell or terminal, non-interactive commands
+// get it from env variable. If confirm is set, the secret is asked twice.
+func askSecret(prompt, env string, confirm bool) (secret string, err error) {
+	if value, ok := os.LookupEnv(env); ok {
+		return value, nil
+	}
+
+	readPassword := func(prompt string) (result []byte, err error) {
+		if shell != nil {
+			return shell.ReadPassword(prompt)
+		}
+
+		if !readline.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("secret isn't received: stdin isn't a terminal, set %s", env)
+		}
+
+		// prompt isn't a part of the command result
+		terminal, err := readline.NewEx(&readline.Config{Stdout: os.Stderr})
+		if err != nil {
+			return nil, err
+		}
+		defer terminal.Close()
+
+		return terminal.ReadPassword(prompt)
+	}
+
+	line, err := readPassword(prompt)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	if confirm {
+		repeated, err := readPassword("Repeat: ")
+		if err != nil {
+			return "", wrap.Wrap(err)
+		}
+		if string(repeated) != string(line) {
+			return "", wrap.Wrap(errors.New("entered values don't match"))
+		}
+	}
+
+	return string(line), nil
 }
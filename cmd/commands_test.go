@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+)
+
+// TestExecuteCommandTwice checks that a command gets the context of its run in the shell,
+// not the canceled context of the previous run.
+func TestExecuteCommandTwice(t *testing.T) {
+	var deadlines []bool
+	probeCommand := &cobra.Command{
+		Use:  "context-probe",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, ok := cmd.Context().Deadline()
+			deadlines = append(deadlines, ok)
+			return cmd.Context().Err()
+		},
+	}
+	rootCommand.AddCommand(probeCommand)
+	defer rootCommand.RemoveCommand(probeCommand)
+
+	for i, command := range []string{"context-probe", "context-probe", "context-probe --timeout 1m", "context-probe"} {
+		// every shell line has its own context, it is canceled when the command ends
+		ctx, cancel := context.WithCancel(context.Background())
+		err := ExecuteCommand(ctx, command)
+		cancel()
+		if err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	// --timeout applies only to its run
+	if want := []bool{false, false, true, false}; !slices.Equal(deadlines, want) {
+		t.Fatalf("deadlines of runs: got %v, want %v", deadlines, want)
+	}
+}
+
+// TestFailedCommandResetsFlags checks that flags of a failed command aren't kept by the shell:
+// a plain send after a failed send --yes must ask for confirmation.
+func TestFailedCommandResetsFlags(t *testing.T) {
+	// the amount isn't parsed, so the wallet isn't used
+	err := ExecuteCommand(context.Background(), "wallet send tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx many --yes --subtract-fee --dry-run -o json --timeout 1m")
+	if err == nil {
+		t.Fatal("send with invalid amount succeeded")
+	}
+
+	for _, name := range []string{"yes", "subtract-fee", "dry-run"} {
+		value, err := walletSendToCommand.Flags().GetBool(name)
+		if err != nil {
+			t.Fatalf("get --%s: %v", name, err)
+		}
+		if value {
+			t.Fatalf("--%s is kept after the failed send", name)
+		}
+	}
+
+	if outputFormat != constants.OutputFormatTable || commandTimeout != 0 {
+		t.Fatalf("output %q and timeout %s are kept after the failed send", outputFormat, commandTimeout)
+	}
+}
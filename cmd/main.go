@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"strings"
@@ -25,6 +26,15 @@ var rootCommand = &cobra.Command{
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateOutputFormat(); err != nil {
+			return wrap.Wrap(err)
+		}
+
+		setCommandTimeout(cmd)
+
+		return nil
+	},
 }
 
 func setRootUID() (err error) {
@@ -36,28 +46,90 @@ func setRootUID() (err error) {
 }
 
 func main() {
-	ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Kill, os.Interrupt, syscall.SIGTERM)
+	// Ctrl-C cancels the running command, see listenUserCommands for the shell
+	ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Kill, syscall.SIGTERM)
 	defer cancelFunc()
 
 	infrastructure.App = infrastructure.NewKernel(ctx)
 
+	// non-interactive mode: execute the command from arguments and exit
+	if len(os.Args) > 1 {
+		commandCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		exitCode := constants.ExitCodeOK
+		if err := ExecuteArgs(commandCtx, os.Args[1:]); err != nil {
+			exitCode = constants.ExitCodeError
+		}
+		stop()
+		shutdown()
+		cancelFunc()
+		os.Exit(exitCode)
+	}
+
 	instance, err := initReadline()
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer instance.Close()
 
-	go listenUserCommands(cancelFunc, instance)
+	shell = instance
+	// background logs (watcher, notifier) mustn't corrupt the input line
+	if err = infrastructure.App.InitLogger(instance.Stderr()); err != nil {
+		log.Fatal(err)
+	}
+
+	go listenUserCommands(ctx, cancelFunc, instance)
 
 	<-ctx.Done()
 	// graceful shutdown
+	shutdown()
 }
 
+// shutdown waits for in-flight broadcasts, so sent transactions are saved.
+func shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
+	defer cancel()
+
+	if err := infrastructure.App.Shutdown(ctx); err != nil {
+		slog.Error("in-flight broadcasts aren't finished", "error", err)
+	}
+}
+
+// shell is used by commands which need additional user input.
+var shell *readline.Instance
+
 var completer = readline.NewPrefixCompleter(
 	readline.PcItem("wallet",
 		readline.PcItem("address"),
 		readline.PcItem("balance"),
 		readline.PcItem("send"),
+		readline.PcItem("history"),
+		readline.PcItem("utxos"),
+		readline.PcItem("wait"),
+		readline.PcItem("serve"),
+		readline.PcItem("serve-grpc"),
+		readline.PcItem("serve-rpc"),
+		readline.PcItem("notify"),
+		readline.PcItem("backup"),
+		readline.PcItem("restore"),
+		readline.PcItem("recover"),
+		readline.PcItem("shares",
+			readline.PcItem("create"),
+			readline.PcItem("combine"),
+		),
+	),
+	readline.PcItem("tx",
+		readline.PcItem("show"),
+		readline.PcItem("decode"),
+		readline.PcItem("broadcast"),
+	),
+	readline.PcItem("watch",
+		readline.PcItem("start"),
+		readline.PcItem("stop"),
+		readline.PcItem("status"),
+	),
+	readline.PcItem("audit",
+		readline.PcItem("show"),
+		readline.PcItem("verify"),
 	),
 	readline.PcItem("help"),
 	readline.PcItem("exit"),
@@ -78,7 +150,7 @@ func initReadline() (instance *readline.Instance, err error) {
 	return instance, nil
 }
 
-func listenUserCommands(cancel context.CancelFunc, instance *readline.Instance) {
+func listenUserCommands(ctx context.Context, cancel context.CancelFunc, instance *readline.Instance) {
 	for {
 		line, err := instance.Readline()
 		if err != nil {
@@ -97,7 +169,12 @@ func listenUserCommands(cancel context.CancelFunc, instance *readline.Instance)
 		if lo.IsEmpty(line) {
 			continue
 		}
-		if err = ExecuteCommand(line); err != nil {
+		// the terminal isn't in raw mode while the command is running, so Ctrl-C sends SIGINT
+		commandCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err = ExecuteCommand(commandCtx, line)
+		stop()
+		if err != nil {
+			// the error is the command result for the user, not a log record
 			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		}
 	}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat is bound to the global --output flag.
+var outputFormat = constants.OutputFormatTable
+
+func init() {
+	rootCommand.PersistentFlags().StringVarP(&outputFormat, "output", "o", constants.OutputFormatTable, "output format: table, json or yaml")
+}
+
+// validateOutputFormat is called before the command is run, e.g. transaction mustn't be sent
+// if the result can't be printed.
+func validateOutputFormat() error {
+	switch outputFormat {
+	case constants.OutputFormatTable, constants.OutputFormatJSON, constants.OutputFormatYAML:
+		return nil
+	default:
+		return wrap.Wrap(fmt.Errorf("unknown output format %q", outputFormat))
+	}
+}
+
+func outputResetFlags() {
+	rootCommand.PersistentFlags().Set("output", constants.OutputFormatTable) //nolint:errcheck // err can be always
+}
+
+// isTableOutput reports whether human-readable output is requested.
+func isTableOutput() bool {
+	return outputFormat == constants.OutputFormatTable
+}
+
+// printResult prints result in the requested format, printTable is used for table format.
+func printResult(result any, printTable func() error) (err error) {
+	switch outputFormat {
+	case constants.OutputFormatTable:
+		if err = printTable(); err != nil {
+			return wrap.Wrap(err)
+		}
+	case constants.OutputFormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(result); err != nil {
+			return wrap.Wrap(err)
+		}
+	case constants.OutputFormatYAML:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(result); err != nil {
+			return wrap.Wrap(err)
+		}
+		if err = encoder.Close(); err != nil {
+			return wrap.Wrap(err)
+		}
+	default:
+		return wrap.Wrap(fmt.Errorf("unknown output format %q", outputFormat))
+	}
+
+	return nil
+}
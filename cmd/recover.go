@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/tatun2000/bitcoin-testnet-wallet/infrastructure"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/recovery"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+var walletRecoverCommand = &cobra.Command{
+	Use:   "recover",
+	Short: "find funds of mnemonic and import it.",
+	Long: "Scan BIP44, BIP49, BIP84 and BIP86 accounts of mnemonic for transactions and funds, then import the mnemonic into the wallet. " +
+		fmt.Sprintf("Mnemonic and passphrase are asked without echo or read from %s and %s. ", constants.RecoveryMnemonicEnv, constants.RecoveryPassphraseEnv) +
+		"Empty passphrase means no passphrase, --use-config-passphrase scans with secretPassphrase from config. " +
+		"It isn't a full recovery: the wallet spends only " + recovery.WalletAddressPath + ", so the passphrase must be secretPassphrase from config to import, " +
+		"and import fails if funds are found on other paths unless --allow-partial is set.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		gapLimit, err := cmd.Flags().GetInt("gap-limit")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		accounts, err := cmd.Flags().GetInt("accounts")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		scanOnly, err := cmd.Flags().GetBool("scan-only")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		useConfigPassphrase, err := cmd.Flags().GetBool("use-config-passphrase")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		allowPartial, err := cmd.Flags().GetBool("allow-partial")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		mnemonic, err := askSecret("Mnemonic: ", constants.RecoveryMnemonicEnv, false)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		// empty passphrase is a valid one, it isn't replaced by secretPassphrase from config
+		passphrase := infrastructure.App.Config().SecretPassphrase
+		if !useConfigPassphrase {
+			if passphrase, err = askSecret("BIP39 passphrase (empty for none): ", constants.RecoveryPassphraseEnv, false); err != nil {
+				return wrap.Wrap(err)
+			}
+		}
+
+		recoveryService := infrastructure.App.InjectRecoveryService()
+
+		result, err := recoveryService.Scan(cmd.Context(), mnemonic, passphrase, gapLimit, accounts)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		// in machine-readable formats found accounts are a part of the result
+		if isTableOutput() {
+			printRecoveryScan(result)
+		}
+
+		if scanOnly {
+			return printResult(result, func() error {
+				fmt.Fprintln(os.Stdout, "Scan only: mnemonic wasn't imported")
+				return nil
+			})
+		}
+
+		if result.Unspendable > 0 {
+			fmt.Fprintf(os.Stderr, "WARNING: %d satoshi are on other paths than %s, the wallet can't spend them after import: sweep them by another wallet\n",
+				result.Unspendable, recovery.WalletAddressPath)
+			if !allowPartial {
+				return wrap.Wrap(fmt.Errorf("found funds aren't imported: only %s is spent by the wallet, set --allow-partial to import it anyway",
+					recovery.WalletAddressPath))
+			}
+		}
+
+		if !yes {
+			ok, err := askConfirmation(fmt.Sprintf("The current wallet will be replaced by %s of the mnemonic. Import it?", recovery.WalletAddressPath))
+			if err != nil {
+				return wrap.Wrap(err)
+			}
+			if !ok {
+				return printResult(result, func() error {
+					fmt.Fprintln(os.Stdout, "Import canceled")
+					return nil
+				})
+			}
+		}
+
+		if result.Address, err = recoveryService.Import(cmd.Context(), mnemonic, passphrase); err != nil {
+			return wrap.Wrap(err)
+		}
+		result.Imported = true
+
+		return printResult(result, func() error {
+			fmt.Fprintf(os.Stdout, "Only %s is imported, wallet address: %s\n", recovery.WalletAddressPath, result.Address)
+			return nil
+		})
+	},
+}
+
+func printRecoveryScan(result entities.RecoveryResult) {
+	fmt.Fprintf(os.Stdout, "Scanned %d addresses, found %d used accounts\n", result.Scanned, len(result.Accounts))
+	if len(result.Accounts) == 0 {
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STANDARD\tPATH\tSCRIPT TYPE\tADDRESS\tTRANSACTIONS\tCONFIRMED\tUNCONFIRMED")
+	for _, account := range result.Accounts {
+		transactions := lo.SumBy(account.Addresses, func(found entities.RecoveredAddress) int { return found.Transactions })
+		fmt.Fprintf(writer, "%s\t%s\t%s\t\t%d\t%d\t%d\n",
+			account.Standard, account.Path, account.ScriptType, transactions, account.Confirmed, account.Unconfirmed)
+		for _, found := range account.Addresses {
+			fmt.Fprintf(writer, "\t%s\t\t%s\t%d\t%d\t%d\n",
+				found.Path, found.Address, found.Transactions, found.Confirmed, found.Unconfirmed)
+		}
+	}
+	writer.Flush()
+
+	fmt.Fprintf(os.Stdout, "Total: %d satoshi confirmed, %d satoshi unconfirmed, %d satoshi outside %s\n",
+		result.Confirmed, result.Unconfirmed, result.Unspendable, recovery.WalletAddressPath)
+}
+
+func init() {
+	walletCommand.AddCommand(walletRecoverCommand)
+
+	walletRecoverCommand.Flags().Int("gap-limit", constants.RecoveryGapLimit, "consecutive unused addresses before the chain scan stops")
+	walletRecoverCommand.Flags().Int("accounts", constants.RecoveryMaxAccounts, "maximum number of accounts scanned per derivation scheme")
+	walletRecoverCommand.Flags().Bool("scan-only", false, "report found funds without import")
+	walletRecoverCommand.Flags().BoolP("yes", "y", false, "import without confirmation")
+	walletRecoverCommand.Flags().Bool("use-config-passphrase", false, "use secretPassphrase from config instead of asking the passphrase")
+	walletRecoverCommand.Flags().Bool("allow-partial", false, "import even if funds are found outside the wallet address")
+}
+
+func recoverResetFlags() {
+	walletRecoverCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
+
+	walletRecoverCommand.Flags().Set("gap-limit", strconv.Itoa(constants.RecoveryGapLimit))   //nolint:errcheck // err can be always
+	walletRecoverCommand.Flags().Set("accounts", strconv.Itoa(constants.RecoveryMaxAccounts)) //nolint:errcheck // err can be always
+	walletRecoverCommand.Flags().Set("scan-only", "false")                                    //nolint:errcheck // err can be always
+	walletRecoverCommand.Flags().Set("yes", "false")                                          //nolint:errcheck // err can be always
+	walletRecoverCommand.Flags().Set("use-config-passphrase", "false")                        //nolint:errcheck // err can be always
+	walletRecoverCommand.Flags().Set("allow-partial", "false")                                //nolint:errcheck // err can be always
+}
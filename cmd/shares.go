@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tatun2000/bitcoin-testnet-wallet/infrastructure"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/shares"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+var walletSharesCommand = &cobra.Command{
+	Use:   "shares",
+	Short: "SLIP-39 shares of the wallet seed.",
+	Long:  "SLIP-39 shares of the wallet seed: custody of the seed is split among members, any threshold of shares restore the wallet.",
+}
+
+var walletSharesCreateCommand = &cobra.Command{
+	Use:   "create",
+	Short: "split the wallet seed into shares.",
+	Long: "Split the wallet seed into SLIP-39 mnemonic shares, any threshold of them restore the wallet. " +
+		"secretPassphrase isn't a part of the shares, keep it separately.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		threshold, err := cmd.Flags().GetInt("threshold")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		count, err := cmd.Flags().GetInt("shares")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		result, err := infrastructure.App.InjectSharesService().Create(cmd.Context(), threshold, count)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(result, func() error {
+			fmt.Fprintf(os.Stdout, "Any %d of %d shares restore the wallet together with secretPassphrase\n", result.Threshold, len(result.Shares))
+			for i, share := range result.Shares {
+				fmt.Fprintf(os.Stdout, "\tShare %d: %s\n", i+1, share)
+			}
+			fmt.Fprintln(os.Stdout, "Give every share to one member, the shares aren't saved by the wallet")
+			return nil
+		})
+	},
+}
+
+var walletSharesCombineCommand = &cobra.Command{
+	Use:   "combine",
+	Short: "restore the wallet from shares.",
+	Long: "Restore the wallet from SLIP-39 shares, they are asked one by one without echo until threshold is reached. " +
+		fmt.Sprintf("Non-interactive commands read shares from %s, one per line. ", constants.SharesEnv) +
+		"secretPassphrase in config must be the passphrase of the shared wallet.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		if !yes {
+			ok, err := askConfirmation("The current wallet will be replaced. Combine shares?")
+			if err != nil {
+				return wrap.Wrap(err)
+			}
+			if !ok {
+				fmt.Fprintln(os.Stderr, "Combine canceled")
+				return nil
+			}
+		}
+
+		sharesService := infrastructure.App.InjectSharesService()
+
+		if value, ok := os.LookupEnv(constants.SharesEnv); ok {
+			result, err := sharesService.Combine(cmd.Context(), strings.FieldsFunc(value, func(r rune) bool { return r == '\n' }))
+			if err != nil {
+				return wrap.Wrap(err)
+			}
+
+			return printResult(result, func() error {
+				fmt.Fprintf(os.Stdout, "Wallet is restored from %d shares: %s\n", result.Shares, result.Address)
+				return nil
+			})
+		}
+
+		var mnemonics []string
+		for {
+			share, err := askSecret(fmt.Sprintf("Share %d: ", len(mnemonics)+1), constants.SharesEnv, false)
+			if err != nil {
+				return wrap.Wrap(err)
+			}
+			if strings.TrimSpace(share) == "" {
+				return wrap.Wrap(errors.New("share isn't entered, the wallet isn't changed"))
+			}
+
+			result, err := sharesService.Combine(cmd.Context(), append(mnemonics, share))
+			switch {
+			case errors.Is(err, shares.ErrInvalidShare):
+				// a mistyped share is asked again
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			case errors.Is(err, shares.ErrNotEnoughShares):
+				mnemonics = append(mnemonics, share)
+				continue
+			case err != nil:
+				return wrap.Wrap(err)
+			}
+
+			return printResult(result, func() error {
+				fmt.Fprintf(os.Stdout, "Wallet is restored from %d shares: %s\n", result.Shares, result.Address)
+				return nil
+			})
+		}
+	},
+}
+
+func init() {
+	walletCommand.AddCommand(walletSharesCommand)
+	walletSharesCommand.AddCommand(walletSharesCreateCommand)
+	walletSharesCommand.AddCommand(walletSharesCombineCommand)
+
+	walletSharesCreateCommand.Flags().Int("threshold", constants.DefaultSharesThreshold, "number of shares which restore the wallet")
+	walletSharesCreateCommand.Flags().Int("shares", constants.DefaultSharesCount, "number of shares, at most 16")
+	walletSharesCombineCommand.Flags().BoolP("yes", "y", false, "replace the wallet without confirmation")
+}
+
+func sharesResetFlags() {
+	walletSharesCommand.Flags().Set("help", "")        //nolint:errcheck // err can be always
+	walletSharesCreateCommand.Flags().Set("help", "")  //nolint:errcheck // err can be always
+	walletSharesCombineCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
+
+	walletSharesCreateCommand.Flags().Set("threshold", strconv.Itoa(constants.DefaultSharesThreshold)) //nolint:errcheck // err can be always
+	walletSharesCreateCommand.Flags().Set("shares", strconv.Itoa(constants.DefaultSharesCount))        //nolint:errcheck // err can be always
+	walletSharesCombineCommand.Flags().Set("yes", "false")                                             //nolint:errcheck // err can be always
+}
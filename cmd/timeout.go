@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// commandTimeout is bound to the global --timeout flag.
+	commandTimeout time.Duration
+	// cancelCommandTimeout releases the timer of the last command.
+	cancelCommandTimeout context.CancelFunc = func() {}
+)
+
+func init() {
+	rootCommand.PersistentFlags().DurationVar(&commandTimeout, "timeout", 0, "maximum command duration, e.g. 30s (default no timeout)")
+}
+
+func timeoutResetFlags() {
+	rootCommand.PersistentFlags().Set("timeout", "0s") //nolint:errcheck // err can be always
+}
+
+// setCommandTimeout sets context of the executed command from the root one and limits it by --timeout.
+// Cobra passes the root context only to a subcommand without context, so in the shell a subcommand
+// would keep the canceled context of its first run.
+func setCommandTimeout(cmd *cobra.Command) {
+	ctx := cmd.Root().Context()
+	if commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		cancelCommandTimeout = cancel
+	}
+
+	cmd.SetContext(ctx)
+}
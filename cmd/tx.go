@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tatun2000/bitcoin-testnet-wallet/infrastructure"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/utils"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+var txCommand = &cobra.Command{
+	Use:   "tx",
+	Short: "testnet transaction commands.",
+	Long:  "testnet transaction commands.",
+}
+
+var txShowCommand = &cobra.Command{
+	Use:   "show",
+	Short: "show transaction details.",
+	Long: utils.GenLongMessage("Show transaction details", map[string]entities.HelpArg{
+		"txid": {
+			Description: "Transaction ID",
+			SeqNumber:   1,
+			Required:    true,
+		},
+	}),
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transactionService := infrastructure.App.InjectTransactionService()
+
+		details, err := transactionService.GetTransactionDetails(cmd.Context(), args[0])
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(details, func() error {
+			printDecodedTx(details)
+			return nil
+		})
+	},
+}
+
+var txDecodeCommand = &cobra.Command{
+	Use:   "decode",
+	Short: "decode raw transaction.",
+	Long: utils.GenLongMessage("Decode raw transaction", map[string]entities.HelpArg{
+		"hex": {
+			Description: "Raw transaction in hex",
+			SeqNumber:   1,
+			Required:    true,
+		},
+	}),
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transactionService := infrastructure.App.InjectTransactionService()
+
+		details, err := transactionService.DecodeTransaction(cmd.Context(), args[0])
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(details, func() error {
+			printDecodedTx(details)
+			return nil
+		})
+	},
+}
+
+var txBroadcastCommand = &cobra.Command{
+	Use:   "broadcast",
+	Short: "validate and broadcast raw transaction.",
+	Long: utils.GenLongMessage("Validate and broadcast raw transaction", map[string]entities.HelpArg{
+		"hex|file": {
+			Description: "Raw transaction in hex or path to file with it",
+			SeqNumber:   1,
+			Required:    true,
+		},
+	}),
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hexTx := args[0]
+		if _, err := os.Stat(hexTx); err == nil {
+			content, err := os.ReadFile(hexTx)
+			if err != nil {
+				return wrap.Wrap(err)
+			}
+			hexTx = strings.TrimSpace(string(content))
+		}
+
+		transactionService := infrastructure.App.InjectTransactionService()
+
+		txid, err := transactionService.BroadcastTransaction(cmd.Context(), hexTx)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(entities.BroadcastResult{TxID: txid}, func() error {
+			fmt.Fprintln(os.Stdout, "Successfully broadcasted")
+			fmt.Fprintf(os.Stdout, "Transaction ID: %s\n", txid)
+			return nil
+		})
+	},
+}
+
+func printDecodedTx(details entities.DecodedTx) {
+	fmt.Fprintf(os.Stdout, "Transaction: %s\n", details.TxID)
+	fmt.Fprintf(os.Stdout, "\tWitness ID: %s\n", details.WTxID)
+	if details.Status != nil {
+		if details.Status.Confirmed {
+			fmt.Fprintf(os.Stdout, "\tStatus: confirmed in block %d (%s) at %s\n", details.Status.BlockHeight,
+				details.Status.BlockHash, time.Unix(details.Status.BlockTime, 0).UTC().Format(time.DateTime))
+		} else {
+			fmt.Fprintln(os.Stdout, "\tStatus: mempool")
+		}
+	}
+	fmt.Fprintf(os.Stdout, "\tVersion: %d\n", details.Version)
+	fmt.Fprintf(os.Stdout, "\tLocktime: %d\n", details.LockTime)
+	fmt.Fprintf(os.Stdout, "\tRBF: %t\n", details.RBF)
+	fmt.Fprintf(os.Stdout, "\tSize: %d bytes, weight: %d WU, virtual size: %d vbytes\n", details.Size, details.Weight, details.VSize)
+	if details.FeeKnown {
+		fmt.Fprintf(os.Stdout, "\tFee: %d satoshi (%.2f sat/vbyte)\n", details.Fee, float64(details.Fee)/float64(details.VSize))
+	} else {
+		fmt.Fprintln(os.Stdout, "\tFee: unknown")
+	}
+
+	fmt.Fprintf(os.Stdout, "\tInputs (%d):\n", len(details.Inputs))
+	for idx, input := range details.Inputs {
+		fmt.Fprintf(os.Stdout, "\t\t#%d %s:%d%s\n", idx, input.TxID, input.Vout, walletMark(input.IsWallet))
+		fmt.Fprintf(os.Stdout, "\t\t\tSequence: 0x%08x\n", input.Sequence)
+		if input.Prevout != nil {
+			fmt.Fprintf(os.Stdout, "\t\t\tPrevout: %s %d satoshi (%s)\n", input.Prevout.Address, input.Prevout.Value, input.Prevout.Type)
+			fmt.Fprintf(os.Stdout, "\t\t\tPrevout script: %s\n", input.Prevout.ScriptPubKeyASM)
+		}
+		if input.ScriptSigASM != "" {
+			fmt.Fprintf(os.Stdout, "\t\t\tScriptSig: %s\n", input.ScriptSigASM)
+		}
+		if len(input.Witness) > 0 {
+			fmt.Fprintf(os.Stdout, "\t\t\tWitness: %s\n", strings.Join(input.Witness, " "))
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "\tOutputs (%d):\n", len(details.Outputs))
+	for _, output := range details.Outputs {
+		fmt.Fprintf(os.Stdout, "\t\t#%d %s %d satoshi (%s)%s\n", output.Index, output.Address, output.Value, output.Type, walletMark(output.IsWallet))
+		fmt.Fprintf(os.Stdout, "\t\t\tScript: %s\n", output.ScriptPubKeyASM)
+	}
+}
+
+func walletMark(isWallet bool) string {
+	if isWallet {
+		return " [wallet]"
+	}
+
+	return ""
+}
+
+func init() {
+	rootCommand.AddCommand(txCommand)
+	txCommand.AddCommand(txShowCommand)
+	txCommand.AddCommand(txDecodeCommand)
+	txCommand.AddCommand(txBroadcastCommand)
+}
+
+func txResetFlags() {
+	txCommand.Flags().Set("help", "")          //nolint:errcheck // err can be always
+	txShowCommand.Flags().Set("help", "")      //nolint:errcheck // err can be always
+	txDecodeCommand.Flags().Set("help", "")    //nolint:errcheck // err can be always
+	txBroadcastCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tatun2000/bitcoin-testnet-wallet/infrastructure"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/transport/grpcserver"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/transport/jsonrpc"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/transport/rest"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/utils"
 	"github.com/tatun2000/golang-lib/pkg/wrap"
 )
@@ -27,14 +36,15 @@ var walletAddressCommand = &cobra.Command{
 	RunE: func(cmd *cobra.Command, _ []string) error {
 		walletService := infrastructure.App.InjectWalletService()
 
-		address, err := walletService.GetWalletAddress()
+		address, err := walletService.GetWalletAddress(cmd.Context())
 		if err != nil {
 			return wrap.Wrap(err)
 		}
 
-		fmt.Fprintf(os.Stdout, "Wallet address: %s\n", address)
-
-		return nil
+		return printResult(entities.AddressResult{Address: address}, func() error {
+			fmt.Fprintf(os.Stdout, "Wallet address: %s\n", address)
+			return nil
+		})
 	},
 }
 
@@ -47,14 +57,20 @@ var walletBalanceCommand = &cobra.Command{
 	RunE: func(cmd *cobra.Command, _ []string) error {
 		walletService := infrastructure.App.InjectWalletService()
 
-		confirmedBalance, unconfirmedBalance, err := walletService.GetWalletBalance()
+		confirmedBalance, unconfirmedBalance, err := walletService.GetWalletBalance(cmd.Context())
 		if err != nil {
 			return wrap.Wrap(err)
 		}
 
-		fmt.Fprintf(os.Stdout, "Wallet balance: \n\t\tAvailable: %d satoshi\n\t\tOn hold: %d satoshi\n", confirmedBalance, unconfirmedBalance)
+		result := entities.BalanceResult{
+			Confirmed:   confirmedBalance,
+			Unconfirmed: unconfirmedBalance,
+		}
 
-		return nil
+		return printResult(result, func() error {
+			fmt.Fprintf(os.Stdout, "Wallet balance: \n\t\tAvailable: %d satoshi\n\t\tOn hold: %d satoshi\n", confirmedBalance, unconfirmedBalance)
+			return nil
+		})
 	},
 }
 
@@ -82,30 +98,481 @@ var walletSendToCommand = &cobra.Command{
 			return wrap.Wrap(err)
 		}
 
+		subtractFee, err := cmd.Flags().GetBool("subtract-fee")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
 		walletService := infrastructure.App.InjectWalletService()
 
-		txid, err := walletService.SendTo(address, int64(amount))
+		preview, err := walletService.PrepareSend(cmd.Context(), address, int64(amount), subtractFee)
 		if err != nil {
 			return wrap.Wrap(err)
 		}
 
-		fmt.Fprintf(os.Stdout, "Successfully sent %d satoshi to: %s\n", amount, address)
-		fmt.Fprintf(os.Stdout, "Transaction ID: %s\n", txid)
+		result := entities.SendResult{Transaction: preview}
+
+		// in machine-readable formats preview is a part of the result,
+		// but it is shown on stderr before the confirmation
+		switch {
+		case isTableOutput():
+			printTxPreview(os.Stdout, preview)
+		case !dryRun && !yes:
+			printTxPreview(os.Stderr, preview)
+		}
+
+		if dryRun {
+			walletService.Release(preview)
+			return printResult(result, func() error {
+				fmt.Fprintln(os.Stdout, "Dry run: transaction wasn't broadcasted")
+				return nil
+			})
+		}
+
+		if !yes {
+			ok, err := askConfirmation("Broadcast transaction?")
+			if err != nil {
+				walletService.Release(preview)
+				return wrap.Wrap(err)
+			}
+			if !ok {
+				walletService.Release(preview)
+				return printResult(result, func() error {
+					fmt.Fprintln(os.Stdout, "Sending canceled")
+					return nil
+				})
+			}
+		}
+
+		txid, err := walletService.Broadcast(cmd.Context(), preview)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+		result.Broadcasted = true
+
+		return printResult(result, func() error {
+			if subtractFee {
+				fmt.Fprintf(os.Stdout, "Successfully sent %d satoshi (fee included) to: %s\n", amount, address)
+			} else {
+				fmt.Fprintf(os.Stdout, "Successfully sent %d satoshi to: %s\n", amount, address)
+			}
+			fmt.Fprintf(os.Stdout, "Transaction ID: %s\n", txid)
+			return nil
+		})
+	},
+}
+
+var walletHistoryCommand = &cobra.Command{
+	Use:                   "history",
+	Short:                 "retrieve wallet transactions history.",
+	Long:                  "retrieve wallet transactions history.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		walletService := infrastructure.App.InjectWalletService()
+
+		history, err := walletService.GetHistory(cmd.Context())
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(history, func() error {
+			if len(history) == 0 {
+				fmt.Fprintln(os.Stdout, "Wallet has no transactions")
+				return nil
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "TIME\tTXID\tDIRECTION\tAMOUNT\tFEE\tCONFIRMATIONS\tSTATUS")
+			for _, entry := range history {
+				txTime := "-"
+				if entry.Time != 0 {
+					txTime = time.Unix(entry.Time, 0).UTC().Format(time.DateTime)
+				}
+				status := string(entry.State)
+				if entry.ReplacedBy != "" {
+					status = fmt.Sprintf("%s by %s", entry.State, entry.ReplacedBy)
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%+d\t%d\t%d\t%s\n",
+					txTime, entry.TxID, entry.Direction, entry.Amount, entry.Fee, entry.Confirmations, status)
+			}
+
+			return writer.Flush()
+		})
+	},
+}
+
+var walletUTXOsCommand = &cobra.Command{
+	Use:                   "utxos",
+	Short:                 "retrieve wallet unspent transaction outputs.",
+	Long:                  "retrieve wallet unspent transaction outputs.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		walletService := infrastructure.App.InjectWalletService()
+
+		utxos, err := walletService.GetWalletUTXOs(cmd.Context())
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(utxos, func() error {
+			if len(utxos) == 0 {
+				fmt.Fprintln(os.Stdout, "Wallet has no unspent outputs")
+				return nil
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "OUTPOINT\tVALUE\tSTATUS")
+			for _, utxo := range utxos {
+				status := "mempool"
+				if utxo.Status.Confirmed {
+					status = fmt.Sprintf("confirmed in block %d", utxo.Status.BlockHeight)
+				}
+				fmt.Fprintf(writer, "%s:%d\t%d\t%s\n", utxo.TxID, utxo.Vout, utxo.Value, status)
+			}
+
+			return writer.Flush()
+		})
+	},
+}
+
+var walletWaitCommand = &cobra.Command{
+	Use:   "wait",
+	Short: "wait for transaction confirmations.",
+	Long: utils.GenLongMessage("Wait until transaction has required confirmations, fails on --timeout or if transaction is dropped", map[string]entities.HelpArg{
+		"txid": {
+			Description: "Transaction ID",
+			SeqNumber:   1,
+			Required:    true,
+		},
+	}),
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		txID := args[0]
+
+		confirmations, err := cmd.Flags().GetInt("confs")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+		if confirmations < 0 {
+			return wrap.Wrap(errors.New("confs mustn't be negative"))
+		}
+
+		pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		// progress isn't a part of the result in machine-readable formats
+		progressOutput := os.Stdout
+		if !isTableOutput() {
+			progressOutput = os.Stderr
+		}
+
+		result := entities.WaitResult{TxID: txID}
+		err = infrastructure.App.InjectWalletService().WaitConfirmations(cmd.Context(), txID, confirmations, pollInterval, func(current int) {
+			result.Confirmations = current
+			fmt.Fprintf(progressOutput, "Transaction %s: %d/%d confirmations\n", txID, current, confirmations)
+		})
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return printResult(result, func() error {
+			fmt.Fprintf(os.Stdout, "Transaction %s is confirmed\n", txID)
+			return nil
+		})
+	},
+}
+
+var walletServeCommand = &cobra.Command{
+	Use:                   "serve",
+	Short:                 "serve wallet REST API.",
+	Long:                  "serve wallet REST API until the wallet is stopped, OpenAPI spec is available on /api/v1/openapi.yaml, Prometheus metrics on /metrics.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		listen, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		token, err := apiToken(cmd)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		// watcher syncs wallet state for metrics
+		stopWatcher, err := startWatcher(cmd.Context(), pollInterval)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+		defer stopWatcher()
+
+		server := rest.NewServer(infrastructure.App.InjectWalletService(), listen, token)
+
+		fmt.Fprintf(os.Stdout, "Serving REST API on %s\n", listen)
+		if err = server.Run(cmd.Context()); err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return nil
+	},
+}
+
+var walletServeGRPCCommand = &cobra.Command{
+	Use:                   "serve-grpc",
+	Short:                 "serve wallet gRPC API.",
+	Long:                  "serve wallet gRPC API (api/proto/wallet/v1/wallet.proto) until the wallet is stopped.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		listen, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		token, err := apiToken(cmd)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		// watcher produces events for SubscribeEvents
+		stopWatcher, err := startWatcher(cmd.Context(), pollInterval)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+		defer stopWatcher()
+
+		server := grpcserver.NewServer(infrastructure.App.InjectWalletService(), infrastructure.App.InjectWatcherService(), token)
+
+		fmt.Fprintf(os.Stdout, "Serving gRPC API on %s\n", listen)
+		if err = server.Run(cmd.Context(), listen); err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return nil
+	},
+}
+
+var walletServeRPCCommand = &cobra.Command{
+	Use:                   "serve-rpc",
+	Short:                 "serve bitcoind-compatible wallet JSON-RPC.",
+	Long:                  "serve subset of bitcoind wallet JSON-RPC (getnewaddress, getbalance, listunspent, sendtoaddress, listtransactions, gettransaction, walletprocesspsbt) until the wallet is stopped.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		listen, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		user, err := cmd.Flags().GetString("rpc-user")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+		if user == "" {
+			user = infrastructure.App.Config().RPCUser
+		}
+
+		password, err := cmd.Flags().GetString("rpc-password")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+		if password == "" {
+			password = infrastructure.App.Config().RPCPassword
+		}
+
+		if user == "" || password == "" {
+			return wrap.Wrap(errors.New("RPC credentials aren't set: use --rpc-user and --rpc-password flags or rpcUser and rpcPassword in config"))
+		}
+
+		server := jsonrpc.NewServer(infrastructure.App.InjectWalletService(), listen, user, password)
+
+		fmt.Fprintf(os.Stdout, "Serving JSON-RPC on %s\n", listen)
+		if err = server.Run(cmd.Context()); err != nil {
+			return wrap.Wrap(err)
+		}
+
+		return nil
+	},
+}
+
+var walletNotifyCommand = &cobra.Command{
+	Use:                   "notify",
+	Short:                 "deliver webhook notifications.",
+	Long:                  "watch the wallet and deliver webhook notifications (webhooks in config) until the wallet is stopped.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		if !infrastructure.App.InjectNotifierService().IsConfigured() {
+			return wrap.Wrap(errors.New("webhooks aren't set in config"))
+		}
+
+		stopWatcher, err := startWatcher(cmd.Context(), pollInterval)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+		defer stopWatcher()
+
+		fmt.Fprintf(os.Stdout, "Watching the wallet every %s\n", pollInterval)
+		<-cmd.Context().Done()
 
 		return nil
 	},
 }
 
+// startWatcher starts the watcher and webhook notifier if webhooks are configured.
+func startWatcher(ctx context.Context, pollInterval time.Duration) (stop func(), err error) {
+	watcherService := infrastructure.App.InjectWatcherService()
+	notifierService := infrastructure.App.InjectNotifierService()
+
+	// notifier subscribes before the first poll
+	if notifierService.IsConfigured() {
+		if err = notifierService.Start(ctx); err != nil {
+			return nil, wrap.Wrap(err)
+		}
+	}
+
+	if err = watcherService.Start(ctx, pollInterval); err != nil {
+		notifierService.Stop()
+		return nil, wrap.Wrap(err)
+	}
+
+	return func() {
+		watcherService.Stop()
+		notifierService.Stop()
+	}, nil
+}
+
+// apiToken returns token from --token flag or config.
+func apiToken(cmd *cobra.Command) (token string, err error) {
+	token, err = cmd.Flags().GetString("token")
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+	if token == "" {
+		token = infrastructure.App.Config().APIToken
+	}
+	if token == "" {
+		return "", wrap.Wrap(errors.New("API token isn't set: use --token flag or apiToken in config"))
+	}
+
+	return token, nil
+}
+
+func printTxPreview(writer io.Writer, preview entities.TxPreview) {
+	fmt.Fprintf(writer, "Transaction preview: %s\n", preview.TxID)
+	fmt.Fprintln(writer, "\tInputs:")
+	for _, input := range preview.Inputs {
+		fmt.Fprintf(writer, "\t\t%s:%d %s %d satoshi\n", input.TxID, input.Vout, input.Address, input.Value)
+	}
+	fmt.Fprintln(writer, "\tOutputs:")
+	for _, output := range preview.Outputs {
+		kind := "recipient"
+		if output.IsChange {
+			kind = "change"
+		}
+		fmt.Fprintf(writer, "\t\t%s %d satoshi (%s)\n", output.Address, output.Value, kind)
+	}
+	fmt.Fprintf(writer, "\tVirtual size: %d vbytes\n", preview.VSize)
+	fmt.Fprintf(writer, "\tFee: %d satoshi (%.2f sat/vbyte)\n", preview.Fee, preview.FeeRate)
+	fmt.Fprintf(writer, "\tRaw transaction: %s\n", preview.RawHex)
+}
+
 func init() {
 	rootCommand.AddCommand(walletCommand)
 	walletCommand.AddCommand(walletAddressCommand)
 	walletCommand.AddCommand(walletBalanceCommand)
 	walletCommand.AddCommand(walletSendToCommand)
+	walletCommand.AddCommand(walletHistoryCommand)
+	walletCommand.AddCommand(walletUTXOsCommand)
+	walletCommand.AddCommand(walletWaitCommand)
+	walletCommand.AddCommand(walletServeCommand)
+	walletCommand.AddCommand(walletServeGRPCCommand)
+	walletCommand.AddCommand(walletServeRPCCommand)
+	walletCommand.AddCommand(walletNotifyCommand)
+
+	walletSendToCommand.Flags().Bool("subtract-fee", false, "subtract fee from amount, recipient receives amount - fee")
+	walletSendToCommand.Flags().Bool("dry-run", false, "show transaction preview without broadcasting")
+	walletSendToCommand.Flags().BoolP("yes", "y", false, "broadcast without confirmation")
+
+	walletWaitCommand.Flags().Int("confs", constants.DefaultWaitConfirmations, "required number of confirmations")
+	walletWaitCommand.Flags().Duration("poll-interval", constants.DefaultWaitInterval, "interval of polling the backend")
+
+	walletServeCommand.Flags().String("listen", constants.DefaultAPIListen, "address to listen")
+	walletServeCommand.Flags().String("token", "", "API bearer token (default apiToken from config)")
+	walletServeCommand.Flags().Duration("poll-interval", constants.DefaultWatcherInterval, "interval of syncing wallet state for metrics")
+
+	walletServeGRPCCommand.Flags().String("listen", constants.DefaultGRPCListen, "address to listen")
+	walletServeGRPCCommand.Flags().String("token", "", "API bearer token (default apiToken from config)")
+	walletServeGRPCCommand.Flags().Duration("poll-interval", constants.DefaultWatcherInterval, "interval of polling the backend for events")
+
+	walletNotifyCommand.Flags().Duration("poll-interval", constants.DefaultWatcherInterval, "interval of polling the backend for events")
+
+	walletServeRPCCommand.Flags().String("listen", constants.DefaultRPCListen, "address to listen")
+	walletServeRPCCommand.Flags().String("rpc-user", "", "RPC user (default rpcUser from config)")
+	walletServeRPCCommand.Flags().String("rpc-password", "", "RPC password (default rpcPassword from config)")
 }
 
 func walletResetFlags() {
-	walletCommand.Flags().Set("help", "")        //nolint:errcheck // err can be always
-	walletAddressCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
-	walletBalanceCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
-	walletSendToCommand.Flags().Set("help", "")  //nolint:errcheck // err can be always
+	walletCommand.Flags().Set("help", "")          //nolint:errcheck // err can be always
+	walletAddressCommand.Flags().Set("help", "")   //nolint:errcheck // err can be always
+	walletBalanceCommand.Flags().Set("help", "")   //nolint:errcheck // err can be always
+	walletSendToCommand.Flags().Set("help", "")    //nolint:errcheck // err can be always
+	walletHistoryCommand.Flags().Set("help", "")   //nolint:errcheck // err can be always
+	walletUTXOsCommand.Flags().Set("help", "")     //nolint:errcheck // err can be always
+	walletWaitCommand.Flags().Set("help", "")      //nolint:errcheck // err can be always
+	walletServeCommand.Flags().Set("help", "")     //nolint:errcheck // err can be always
+	walletServeGRPCCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
+	walletServeRPCCommand.Flags().Set("help", "")  //nolint:errcheck // err can be always
+	walletNotifyCommand.Flags().Set("help", "")    //nolint:errcheck // err can be always
+
+	walletSendToCommand.Flags().Set("subtract-fee", "false") //nolint:errcheck // err can be always
+	walletSendToCommand.Flags().Set("dry-run", "false")      //nolint:errcheck // err can be always
+	walletSendToCommand.Flags().Set("yes", "false")          //nolint:errcheck // err can be always
+
+	walletServeCommand.Flags().Set("listen", constants.DefaultAPIListen)                       //nolint:errcheck // err can be always
+	walletServeCommand.Flags().Set("token", "")                                                //nolint:errcheck // err can be always
+	walletServeCommand.Flags().Set("poll-interval", constants.DefaultWatcherInterval.String()) //nolint:errcheck // err can be always
+
+	walletServeGRPCCommand.Flags().Set("listen", constants.DefaultGRPCListen)                      //nolint:errcheck // err can be always
+	walletServeGRPCCommand.Flags().Set("token", "")                                                //nolint:errcheck // err can be always
+	walletServeGRPCCommand.Flags().Set("poll-interval", constants.DefaultWatcherInterval.String()) //nolint:errcheck // err can be always
+
+	walletServeRPCCommand.Flags().Set("listen", constants.DefaultRPCListen) //nolint:errcheck // err can be always
+	walletServeRPCCommand.Flags().Set("rpc-user", "")                       //nolint:errcheck // err can be always
+	walletServeRPCCommand.Flags().Set("rpc-password", "")                   //nolint:errcheck // err can be always
+
+	walletNotifyCommand.Flags().Set("poll-interval", constants.DefaultWatcherInterval.String()) //nolint:errcheck // err can be always
+
+	walletWaitCommand.Flags().Set("confs", strconv.Itoa(constants.DefaultWaitConfirmations)) //nolint:errcheck // err can be always
+	walletWaitCommand.Flags().Set("poll-interval", constants.DefaultWaitInterval.String())   //nolint:errcheck // err can be always
 }
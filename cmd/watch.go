@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/tatun2000/bitcoin-testnet-wallet/infrastructure"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+var (
+	// stopWatch stops the background watcher started by watch start, nil if it isn't running.
+	stopWatch   func()
+	stopWatchMu sync.Mutex
+)
+
+var watchCommand = &cobra.Command{
+	Use:   "watch",
+	Short: "background wallet watcher commands.",
+	Long:  "background wallet watcher commands, the watcher prints notices about wallet transactions into the shell.",
+}
+
+var watchStartCommand = &cobra.Command{
+	Use:                   "start",
+	Short:                 "start background watcher.",
+	Long:                  "start polling the backend in background and printing notices about received, confirmed, replaced and dropped transactions.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		if shell == nil {
+			return wrap.Wrap(errors.New("watcher is available only in the shell, use wallet notify or wallet serve-grpc"))
+		}
+
+		stopWatchMu.Lock()
+		defer stopWatchMu.Unlock()
+
+		if stopWatch != nil {
+			return wrap.Wrap(errors.New("watcher is already running"))
+		}
+
+		// subscribe before the watcher starts, so the first events aren't lost
+		events, unsubscribe := infrastructure.App.InjectWatcherService().Subscribe()
+
+		// the watcher outlives the command (Ctrl-C and --timeout), it is stopped by watch stop or on exit
+		stopWatcher, err := startWatcher(context.WithoutCancel(cmd.Context()), pollInterval)
+		if err != nil {
+			unsubscribe()
+			return wrap.Wrap(err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for event := range events {
+				printWatchNotice(event)
+			}
+		}()
+
+		stopWatch = func() {
+			stopWatcher()
+			unsubscribe()
+			<-done
+		}
+
+		fmt.Fprintf(os.Stdout, "Watcher is started, the wallet is checked every %s\n", pollInterval)
+
+		return nil
+	},
+}
+
+var watchStopCommand = &cobra.Command{
+	Use:                   "stop",
+	Short:                 "stop background watcher.",
+	Long:                  "stop background watcher.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		stopWatchMu.Lock()
+		defer stopWatchMu.Unlock()
+
+		if stopWatch == nil {
+			return wrap.Wrap(errors.New("watcher isn't running"))
+		}
+
+		stopWatch()
+		stopWatch = nil
+
+		fmt.Fprintln(os.Stdout, "Watcher is stopped")
+
+		return nil
+	},
+}
+
+var watchStatusCommand = &cobra.Command{
+	Use:                   "status",
+	Short:                 "show background watcher status.",
+	Long:                  "show background watcher status.",
+	Args:                  cobra.NoArgs,
+	DisableFlagsInUseLine: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if infrastructure.App.InjectWatcherService().IsRunning() {
+			fmt.Fprintln(os.Stdout, "Watcher is running")
+		} else {
+			fmt.Fprintln(os.Stdout, "Watcher isn't running")
+		}
+
+		return nil
+	},
+}
+
+// printWatchNotice prints event above the prompt, readline redraws the input line after it.
+func printWatchNotice(event entities.WalletEvent) {
+	var notice string
+	switch event.Type {
+	case entities.WalletEventNewTransaction:
+		if event.Amount >= 0 {
+			notice = fmt.Sprintf("received %d sat, %d conf (tx %s)", event.Amount, event.Confirmations, shortTxID(event.TxID))
+		} else {
+			notice = fmt.Sprintf("sent %d sat, %d conf (tx %s)", -event.Amount, event.Confirmations, shortTxID(event.TxID))
+		}
+	case entities.WalletEventConfirmation:
+		notice = fmt.Sprintf("tx %s confirmed, %d conf", shortTxID(event.TxID), event.Confirmations)
+	case entities.WalletEventReplaced:
+		notice = fmt.Sprintf("tx %s replaced by %s", shortTxID(event.TxID), shortTxID(event.ReplacedBy))
+	case entities.WalletEventDropped:
+		notice = fmt.Sprintf("tx %s dropped from mempool", shortTxID(event.TxID))
+	case entities.WalletEventBalanceChange:
+		notice = fmt.Sprintf("balance: %d sat available, %d sat on hold", event.Balance.Confirmed, event.Balance.Unconfirmed)
+	default:
+		return
+	}
+
+	fmt.Fprintf(shell.Stdout(), "[watch] %s\n", notice)
+}
+
+func shortTxID(txID string) string {
+	if len(txID) <= 8 {
+		return txID
+	}
+
+	return txID[:8] + "…"
+}
+
+func init() {
+	rootCommand.AddCommand(watchCommand)
+	watchCommand.AddCommand(watchStartCommand)
+	watchCommand.AddCommand(watchStopCommand)
+	watchCommand.AddCommand(watchStatusCommand)
+
+	watchStartCommand.Flags().Duration("poll-interval", constants.DefaultWatcherInterval, "interval of polling the backend")
+}
+
+func watchResetFlags() {
+	watchCommand.Flags().Set("help", "")       //nolint:errcheck // err can be always
+	watchStartCommand.Flags().Set("help", "")  //nolint:errcheck // err can be always
+	watchStopCommand.Flags().Set("help", "")   //nolint:errcheck // err can be always
+	watchStatusCommand.Flags().Set("help", "") //nolint:errcheck // err can be always
+
+	watchStartCommand.Flags().Set("poll-interval", constants.DefaultWatcherInterval.String()) //nolint:errcheck // err can be always
+}
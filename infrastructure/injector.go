@@ -2,9 +2,14 @@ package infrastructure
 
 import (
 	"context"
+	"io"
 	"log"
+	"log/slog"
+	"os"
 
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/config"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/logger"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
 )
 
 var App *Kernel
@@ -19,7 +24,49 @@ func NewKernel(ctx context.Context) *Kernel {
 		log.Fatal(err)
 	}
 
-	return &Kernel{
+	k := &Kernel{
 		cfg: cfg,
 	}
+
+	if err = k.InitLogger(os.Stderr); err != nil {
+		log.Fatal(err)
+	}
+
+	return k
+}
+
+// InitLogger sets default structured logger which writes to w with level and format from config.
+func (k *Kernel) InitLogger(w io.Writer) (err error) {
+	defaultLogger, err := logger.New(w, k.cfg.LogLevel, k.cfg.LogFormat)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+
+	slog.SetDefault(defaultLogger)
+
+	return nil
+}
+
+// Shutdown waits until in-flight broadcasts are finished or ctx is done.
+func (k *Kernel) Shutdown(ctx context.Context) (err error) {
+	// services are created on demand: empty Do marks not created service as done, so it stays nil
+	walletServiceOnce.Do(func() {})
+	if walletService != nil {
+		if err = walletService.WaitBroadcasts(ctx); err != nil {
+			return wrap.Wrap(err)
+		}
+	}
+
+	transactionServiceOnce.Do(func() {})
+	if transactionService != nil {
+		if err = transactionService.WaitBroadcasts(ctx); err != nil {
+			return wrap.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (k *Kernel) Config() config.Config {
+	return *k.cfg
 }
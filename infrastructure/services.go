@@ -3,9 +3,19 @@ package infrastructure
 import (
 	"sync"
 
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/backend"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/config"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/address"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/audit"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/backup"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/notifier"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/recovery"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/shares"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/wallet"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/watcher"
 )
 
 var (
@@ -18,6 +28,8 @@ func (k *Kernel) InjectWalletService() *wallet.Service {
 		walletService = wallet.NewService(
 			k.InjectAddressService(),
 			k.InjectTransactionService(),
+			k.InjectBackendClient(),
+			k.InjectAuditService(),
 		)
 	})
 
@@ -34,6 +46,9 @@ func (k *Kernel) InjectAddressService() *address.Service {
 		addressService = address.NewService(
 			k.cfg.SecretPassphrase,
 			k.cfg.UniqueSeed,
+			constants.WalletMnemonicPath,
+			constants.WalletAddressPath,
+			k.InjectAuditService(),
 		)
 	})
 
@@ -49,8 +64,134 @@ func (k *Kernel) InjectTransactionService() *transaction.Service {
 	transactionServiceOnce.Do(func() {
 		transactionService = transaction.NewService(
 			k.InjectAddressService(),
+			k.InjectBackendClient(),
+			k.InjectAuditService(),
+			constants.WalletTransactionsPath,
 		)
 	})
 
 	return transactionService
 }
+
+var (
+	backupService     *backup.Service
+	backupServiceOnce sync.Once
+)
+
+func (k *Kernel) InjectBackupService() *backup.Service {
+	backupServiceOnce.Do(func() {
+		backupService = backup.NewService(
+			k.InjectAddressService(),
+			k.InjectTransactionService(),
+			k.InjectWalletService(),
+			k.InjectAuditService(),
+		)
+	})
+
+	return backupService
+}
+
+var (
+	recoveryService     *recovery.Service
+	recoveryServiceOnce sync.Once
+)
+
+func (k *Kernel) InjectRecoveryService() *recovery.Service {
+	recoveryServiceOnce.Do(func() {
+		recoveryService = recovery.NewService(
+			k.InjectAddressService(),
+			k.InjectTransactionService(),
+			k.InjectBackendClient(),
+			k.InjectAuditService(),
+		)
+	})
+
+	return recoveryService
+}
+
+var (
+	sharesService     *shares.Service
+	sharesServiceOnce sync.Once
+)
+
+func (k *Kernel) InjectSharesService() *shares.Service {
+	sharesServiceOnce.Do(func() {
+		sharesService = shares.NewService(
+			k.InjectAddressService(),
+			k.InjectTransactionService(),
+			k.InjectAuditService(),
+		)
+	})
+
+	return sharesService
+}
+
+var (
+	watcherService     *watcher.Service
+	watcherServiceOnce sync.Once
+)
+
+func (k *Kernel) InjectWatcherService() *watcher.Service {
+	watcherServiceOnce.Do(func() {
+		watcherService = watcher.NewService(
+			k.InjectWalletService(),
+		)
+	})
+
+	return watcherService
+}
+
+var (
+	notifierService     *notifier.Service
+	notifierServiceOnce sync.Once
+)
+
+func (k *Kernel) InjectNotifierService() *notifier.Service {
+	notifierServiceOnce.Do(func() {
+		confirmations := k.cfg.WebhookConfirmations
+		if confirmations == 0 {
+			confirmations = constants.DefaultWebhookConfirmations
+		}
+
+		notifierService = notifier.NewService(
+			k.InjectWatcherService(),
+			lo.Map(k.cfg.Webhooks, func(webhook config.Webhook, _ int) notifier.Webhook {
+				return notifier.Webhook{URL: webhook.URL, Secret: webhook.Secret}
+			}),
+			confirmations,
+			constants.WebhookQueuePath,
+		)
+	})
+
+	return notifierService
+}
+
+var (
+	auditService     *audit.Service
+	auditServiceOnce sync.Once
+)
+
+func (k *Kernel) InjectAuditService() *audit.Service {
+	auditServiceOnce.Do(func() {
+		auditService = audit.NewService(constants.AuditLogPath)
+	})
+
+	return auditService
+}
+
+var (
+	backendClient     *backend.Client
+	backendClientOnce sync.Once
+)
+
+func (k *Kernel) InjectBackendClient() *backend.Client {
+	backendClientOnce.Do(func() {
+		urls := k.cfg.EsploraURLs
+		if len(urls) == 0 {
+			urls = []string{constants.EsploraTestnetURL, constants.EsploraMempoolTestnetURL}
+		}
+		backendClient = backend.NewClient(urls, constants.BackendRequestTimeout, constants.TxCachePath)
+	})
+
+	return backendClient
+}
@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a circuit breaker of one endpoint. After threshold consecutive failures the endpoint
+// is skipped for cooldown, then one trial request is allowed: success closes the breaker,
+// failure opens it again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // trial request is in flight
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether request can be sent to the endpoint.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(b.openUntil) {
+		return false
+	}
+
+	if b.failures >= b.threshold {
+		// half-open: the only trial request
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	b.openUntil = time.Time{}
+}
+
+func (b *breaker) failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+// abort releases the trial of request which was canceled by the caller.
+func (b *breaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// pause skips the endpoint until the time, e.g. by Retry-After of rate limited response.
+func (b *breaker) pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if until.After(b.openUntil) {
+		b.openUntil = until
+	}
+}
+
+// available reports whether request can be sent to the endpoint without taking the trial.
+func (b *breaker) available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !now.Before(b.openUntil) && (b.failures < b.threshold || !b.trial)
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"golang.org/x/sync/singleflight"
+)
+
+type (
+	cacheItem[T any] struct {
+		value   T
+		expires time.Time
+	}
+
+	// cache keeps values by key (txid) for ttl. Concurrent requests of the same key share one backend call.
+	cache[T any] struct {
+		ttl   time.Duration
+		group singleflight.Group
+
+		mu    sync.Mutex
+		items map[string]cacheItem[T]
+	}
+)
+
+func newCache[T any](ttl time.Duration) *cache[T] {
+	return &cache[T]{
+		ttl:   ttl,
+		items: make(map[string]cacheItem[T]),
+	}
+}
+
+// get returns cached value or calls fetch, errors aren't cached.
+// The shared call isn't canceled by its callers, it is limited by constants.BackendRequestTimeout,
+// so a canceled caller doesn't fail the others. Every caller waits for the result until its ctx is done.
+func (c *cache[T]) get(ctx context.Context, key string, fetch func(ctx context.Context) (T, error)) (result T, err error) {
+	if value, ok := c.lookup(key, time.Now()); ok {
+		return value, nil
+	}
+
+	shared := c.group.DoChan(key, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constants.BackendRequestTimeout)
+		defer cancel()
+
+		value, err := fetch(fetchCtx)
+		if err != nil {
+			return value, err
+		}
+		c.store(key, value, time.Now())
+
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return result, wrap.Wrap(ctx.Err())
+	case res := <-shared:
+		if res.Err != nil {
+			return result, wrap.Wrap(res.Err)
+		}
+		result, _ = res.Val.(T)
+
+		return result, nil
+	}
+}
+
+func (c *cache[T]) lookup(key string, now time.Time) (value T, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok || now.After(item.expires) {
+		return value, false
+	}
+
+	return item.value, true
+}
+
+func (c *cache[T]) store(key string, value T, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, item := range c.items {
+		if now.After(item.expires) {
+			delete(c.items, k)
+		}
+	}
+
+	c.items[key] = cacheItem[T]{value: value, expires: now.Add(c.ttl)}
+}
+
+// clear drops all values, e.g. when a new block is found.
+func (c *cache[T]) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.items)
+}
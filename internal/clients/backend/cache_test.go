@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+// joinDelay is enough for a started goroutine to join the shared call.
+const joinDelay = 50 * time.Millisecond
+
+// fakeEsplora serves transactions, every request waits for release if it is set.
+type fakeEsplora struct {
+	IEsploraClient
+
+	release  chan struct{}
+	calls    atomic.Int32
+	inFlight atomic.Int32
+	maxIn    atomic.Int32
+}
+
+func (f *fakeEsplora) GetTransaction(ctx context.Context, txID string) (result entities.Tx, err error) {
+	f.calls.Add(1)
+	inFlight := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		maxIn := f.maxIn.Load()
+		if inFlight <= maxIn || f.maxIn.CompareAndSwap(maxIn, inFlight) {
+			break
+		}
+	}
+
+	if f.release != nil {
+		<-f.release
+	} else {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return result, err
+	}
+	if txID == "unknown" {
+		return result, esplora.ErrNotFound
+	}
+
+	return entities.Tx{TxID: txID}, nil
+}
+
+func (f *fakeEsplora) GetTipHash(context.Context) (string, error) {
+	return "tip", nil
+}
+
+func (f *fakeEsplora) GetTipHeight(context.Context) (int, error) {
+	return 100, nil
+}
+
+func newFakeClient(t *testing.T, fake *fakeEsplora) *Client {
+	t.Helper()
+
+	c := newTestClient(t)
+	c.endpoints = []*endpoint{{
+		url:     "fake",
+		client:  fake,
+		breaker: newBreaker(constants.BackendBreakerThreshold, constants.BackendBreakerCooldown),
+	}}
+
+	return c
+}
+
+func TestSharedCall(t *testing.T) {
+	fake := &fakeEsplora{release: make(chan struct{})}
+	c := newFakeClient(t, fake)
+
+	// the first caller starts the call and gives up
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.GetTransaction(firstCtx, "aa")
+		firstErr <- err
+	}()
+
+	var wg sync.WaitGroup
+	results := make(chan error, 5)
+	time.Sleep(joinDelay)
+	for range cap(results) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, err := c.GetTransaction(context.Background(), "aa")
+			if err == nil && tx.TxID != "aa" {
+				err = fmt.Errorf("got transaction %s", tx.TxID)
+			}
+			results <- err
+		}()
+	}
+	time.Sleep(joinDelay)
+
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled caller error is %v, want %v", err, context.Canceled)
+	}
+
+	// the shared call isn't canceled with the first caller
+	close(fake.release)
+	wg.Wait()
+	close(results)
+	for err := range results {
+		if err != nil {
+			t.Fatalf("waiting caller: %v", err)
+		}
+	}
+
+	if calls := fake.calls.Load(); calls != 1 {
+		t.Fatalf("%d backend calls, want 1 shared", calls)
+	}
+
+	// the result is cached
+	if _, err := c.GetTransaction(context.Background(), "aa"); err != nil || fake.calls.Load() != 1 {
+		t.Fatalf("cached transaction is requested again, error %v", err)
+	}
+}
+
+func TestGetTransactionsConcurrency(t *testing.T) {
+	fake := &fakeEsplora{}
+	c := newFakeClient(t, fake)
+
+	txIDs := []string{"unknown"}
+	for i := range 3 * constants.BackendConcurrency {
+		txIDs = append(txIDs, fmt.Sprintf("tx%d", i), fmt.Sprintf("tx%d", i))
+	}
+
+	result, err := c.GetTransactions(context.Background(), txIDs)
+	if err != nil {
+		t.Fatalf("get transactions: %v", err)
+	}
+
+	// unknown transaction is missing
+	if len(result) != 3*constants.BackendConcurrency {
+		t.Fatalf("%d transactions, want %d", len(result), 3*constants.BackendConcurrency)
+	}
+	if _, ok := result["unknown"]; ok {
+		t.Fatal("unknown transaction is in the result")
+	}
+
+	// duplicates are requested once
+	if calls := int(fake.calls.Load()); calls != 3*constants.BackendConcurrency+1 {
+		t.Fatalf("%d backend calls, want %d", calls, 3*constants.BackendConcurrency+1)
+	}
+	if maxIn := int(fake.maxIn.Load()); maxIn > constants.BackendConcurrency {
+		t.Fatalf("%d concurrent requests, want at most %d", maxIn, constants.BackendConcurrency)
+	}
+}
@@ -0,0 +1,432 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrUnavailable is returned when every endpoint is skipped by its circuit breaker.
+var ErrUnavailable = errors.New("all backend endpoints are unavailable")
+
+type (
+	IEsploraClient interface {
+		GetTransaction(ctx context.Context, txID string) (result entities.Tx, err error)
+		GetTransactionHex(ctx context.Context, txID string) (result string, err error)
+		GetTransactionStatus(ctx context.Context, txID string) (result entities.TxStatus, err error)
+		GetOutspend(ctx context.Context, txID string, vout uint32) (result entities.Outspend, err error)
+		GetAddressUTXOs(ctx context.Context, address string) (result entities.TxOutputs, err error)
+		GetAddressTransactions(ctx context.Context, address string) (result []entities.Tx, err error)
+		GetTipHeight(ctx context.Context) (result int, err error)
+		GetTipHash(ctx context.Context) (result string, err error)
+		Broadcast(ctx context.Context, hexTx string) (txID string, err error)
+	}
+
+	endpoint struct {
+		url     string
+		client  IEsploraClient
+		breaker *breaker
+	}
+
+	// Client sends every request to the first available endpoint in configured order.
+	// Idempotent requests are retried with exponential backoff, then the next endpoint is used.
+	// Rate limited endpoints are paused for Retry-After and failing ones are skipped by circuit breaker.
+	//
+	// Deeply confirmed transactions are immutable, they are stored in the file. Mempool data, UTXOs and
+	// address transactions are cached for constants.MempoolCacheTTL and dropped when a new block is found.
+	Client struct {
+		endpoints []*endpoint
+		store     *txStore
+
+		txs        *cache[entities.Tx]
+		txHexes    *cache[string]
+		statuses   *cache[entities.TxStatus]
+		utxos      *cache[entities.TxOutputs]
+		addressTxs *cache[[]entities.Tx]
+
+		tipMu      sync.Mutex
+		tipHeight  int
+		tipHash    string
+		tipChecked time.Time
+	}
+)
+
+// NewClient creates client for Esplora endpoints in failover order, confirmed transactions are stored in cachePath.
+func NewClient(urls []string, timeout time.Duration, cachePath string) *Client {
+	c := &Client{
+		endpoints:  make([]*endpoint, 0, len(urls)),
+		store:      newTxStore(cachePath),
+		txs:        newCache[entities.Tx](constants.MempoolCacheTTL),
+		txHexes:    newCache[string](constants.MempoolCacheTTL),
+		statuses:   newCache[entities.TxStatus](constants.MempoolCacheTTL),
+		utxos:      newCache[entities.TxOutputs](constants.MempoolCacheTTL),
+		addressTxs: newCache[[]entities.Tx](constants.MempoolCacheTTL),
+	}
+
+	for _, url := range urls {
+		c.endpoints = append(c.endpoints, &endpoint{
+			url:     url,
+			client:  esplora.NewClient(url, timeout),
+			breaker: newBreaker(constants.BackendBreakerThreshold, constants.BackendBreakerCooldown),
+		})
+	}
+
+	return c
+}
+
+func (c *Client) GetTransaction(ctx context.Context, txID string) (result entities.Tx, err error) {
+	// stored transactions are immutable, they are served even if the backend is unavailable
+	if tx, ok := c.store.get(txID); ok {
+		return tx, nil
+	}
+
+	tipHeight, err := c.syncTip(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = c.txs.get(ctx, txID, func(ctx context.Context) (entities.Tx, error) {
+		return do(ctx, c, true, func(client IEsploraClient) (entities.Tx, error) {
+			return client.GetTransaction(ctx, txID)
+		})
+	})
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.storeConfirmed(result, tipHeight)
+
+	return result, nil
+}
+
+// GetTransactions requests transactions concurrently, at most constants.BackendConcurrency at once.
+// Unknown transactions are missing in the result.
+func (c *Client) GetTransactions(ctx context.Context, txIDs []string) (result map[string]entities.Tx, err error) {
+	txIDs = lo.Uniq(txIDs)
+	result = make(map[string]entities.Tx, len(txIDs))
+
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(constants.BackendConcurrency)
+	for _, txID := range txIDs {
+		group.Go(func() error {
+			tx, err := c.GetTransaction(groupCtx, txID)
+			if errors.Is(err, esplora.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return wrap.Wrap(err)
+			}
+
+			mu.Lock()
+			result[txID] = tx
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err = group.Wait(); err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// GetTransactionHex isn't dropped on a new block: transaction data doesn't depend on its status.
+func (c *Client) GetTransactionHex(ctx context.Context, txID string) (result string, err error) {
+	return c.txHexes.get(ctx, txID, func(ctx context.Context) (string, error) {
+		return do(ctx, c, true, func(client IEsploraClient) (string, error) {
+			return client.GetTransactionHex(ctx, txID)
+		})
+	})
+}
+
+func (c *Client) GetTransactionStatus(ctx context.Context, txID string) (result entities.TxStatus, err error) {
+	if tx, ok := c.store.get(txID); ok {
+		return tx.Status, nil
+	}
+
+	if _, err = c.syncTip(ctx); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return c.statuses.get(ctx, txID, func(ctx context.Context) (entities.TxStatus, error) {
+		return do(ctx, c, true, func(client IEsploraClient) (entities.TxStatus, error) {
+			return client.GetTransactionStatus(ctx, txID)
+		})
+	})
+}
+
+// GetOutspend isn't cached: the output can be spent by any new transaction.
+func (c *Client) GetOutspend(ctx context.Context, txID string, vout uint32) (result entities.Outspend, err error) {
+	return do(ctx, c, true, func(client IEsploraClient) (entities.Outspend, error) {
+		return client.GetOutspend(ctx, txID, vout)
+	})
+}
+
+func (c *Client) GetAddressUTXOs(ctx context.Context, address string) (result entities.TxOutputs, err error) {
+	if _, err = c.syncTip(ctx); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return c.utxos.get(ctx, address, func(ctx context.Context) (entities.TxOutputs, error) {
+		return do(ctx, c, true, func(client IEsploraClient) (entities.TxOutputs, error) {
+			return client.GetAddressUTXOs(ctx, address)
+		})
+	})
+}
+
+func (c *Client) GetAddressTransactions(ctx context.Context, address string) (result []entities.Tx, err error) {
+	tipHeight, err := c.syncTip(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = c.addressTxs.get(ctx, address, func(ctx context.Context) ([]entities.Tx, error) {
+		return do(ctx, c, true, func(client IEsploraClient) ([]entities.Tx, error) {
+			return client.GetAddressTransactions(ctx, address)
+		})
+	})
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	// e.g. previous transactions of the next send
+	for _, tx := range result {
+		c.storeConfirmed(tx, tipHeight)
+	}
+
+	return result, nil
+}
+
+func (c *Client) GetTipHeight(ctx context.Context) (result int, err error) {
+	result, err = c.syncTip(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// Broadcast isn't retried on the same endpoint, but it is sent to the next one if the endpoint
+// is unavailable. The transaction can be already accepted by the previous endpoint, so
+// "already known" rejection means success like in bitcoind.
+func (c *Client) Broadcast(ctx context.Context, hexTx string) (txID string, err error) {
+	txID, err = do(ctx, c, false, func(client IEsploraClient) (string, error) {
+		return client.Broadcast(ctx, hexTx)
+	})
+
+	var rejectErr *esplora.RejectError
+	if errors.As(err, &rejectErr) && isAlreadyKnown(rejectErr) {
+		txID, err = transactionHash(hexTx)
+	}
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	// UTXOs and address transactions are changed
+	c.invalidate()
+
+	return txID, nil
+}
+
+// syncTip returns height of the best block, it is requested at most once per constants.TipCheckInterval.
+// Short-lived cache is dropped when the best block hash is changed, including reorganization.
+func (c *Client) syncTip(ctx context.Context) (height int, err error) {
+	c.tipMu.Lock()
+	defer c.tipMu.Unlock()
+
+	if time.Since(c.tipChecked) < constants.TipCheckInterval {
+		return c.tipHeight, nil
+	}
+
+	hash, err := do(ctx, c, true, func(client IEsploraClient) (string, error) {
+		return client.GetTipHash(ctx)
+	})
+	if err != nil {
+		return height, wrap.Wrap(err)
+	}
+
+	if hash != c.tipHash {
+		height, err = do(ctx, c, true, func(client IEsploraClient) (int, error) {
+			return client.GetTipHeight(ctx)
+		})
+		if err != nil {
+			return height, wrap.Wrap(err)
+		}
+
+		if c.tipHash != "" {
+			slog.Debug("new block is found, cache is dropped", "height", height, "hash", hash)
+			c.invalidate()
+		}
+		c.tipHash, c.tipHeight = hash, height
+	}
+	c.tipChecked = time.Now()
+
+	return c.tipHeight, nil
+}
+
+func (c *Client) invalidate() {
+	c.txs.clear()
+	c.statuses.clear()
+	c.utxos.clear()
+	c.addressTxs.clear()
+}
+
+// storeConfirmed stores transaction with at least constants.TxCacheMinConfirmations confirmations,
+// it can't be changed by reorganization in practice.
+func (c *Client) storeConfirmed(tx entities.Tx, tipHeight int) {
+	if tx.Status.Confirmed && tipHeight-tx.Status.BlockHeight+1 >= constants.TxCacheMinConfirmations {
+		c.store.put(tx)
+	}
+}
+
+func do[T any](ctx context.Context, c *Client, idempotent bool, call func(client IEsploraClient) (T, error)) (result T, err error) {
+	attempts := 1
+	if idempotent {
+		attempts = constants.BackendMaxAttempts
+	}
+
+	var lastErr error
+	for idx, ep := range c.endpoints {
+		var wait time.Duration
+		for attempt := 1; attempt <= attempts && ep.breaker.allow(time.Now()); attempt++ {
+			if err = sleep(ctx, wait); err != nil {
+				ep.breaker.abort()
+				return result, wrap.Wrap(err)
+			}
+
+			result, err = call(ep.client)
+			if err != nil && ctx.Err() != nil {
+				// canceled request says nothing about the endpoint
+				ep.breaker.abort()
+				return result, wrap.Wrap(err)
+			}
+			if !isTemporary(err) {
+				// not found and rejected transaction are answers of available endpoint
+				ep.breaker.success()
+				return result, err
+			}
+
+			lastErr = fmt.Errorf("%s: %w", ep.url, err)
+			slog.Warn("backend request failed", "endpoint", ep.url, "attempt", attempt, "error", err)
+
+			wait = backoff(attempt)
+
+			var httpErr *esplora.HTTPError
+			if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+				retryAfter := max(httpErr.RetryAfter, wait)
+				// rate limited endpoint is paused, the next one is used if it is available
+				if c.hasAvailable(idx+1) || retryAfter > constants.BackendRetryMax {
+					ep.breaker.pause(time.Now().Add(retryAfter))
+					break
+				}
+				wait = retryAfter
+				continue
+			}
+
+			ep.breaker.failure(time.Now())
+		}
+	}
+
+	if lastErr == nil {
+		return result, wrap.Wrap(ErrUnavailable)
+	}
+
+	return result, wrap.Wrap(lastErr)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return wrap.Wrap(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// hasAvailable reports whether any endpoint starting from idx can be used now.
+func (c *Client) hasAvailable(idx int) bool {
+	now := time.Now()
+	for _, ep := range c.endpoints[idx:] {
+		if ep.breaker.available(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTemporary reports whether the request can succeed later or on another endpoint.
+func isTemporary(err error) bool {
+	if err == nil || errors.Is(err, esplora.ErrNotFound) {
+		return false
+	}
+
+	var rejectErr *esplora.RejectError
+	if errors.As(err, &rejectErr) {
+		return false
+	}
+
+	var httpErr *esplora.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Temporary()
+	}
+
+	// network error or timeout
+	return true
+}
+
+// backoff returns exponential delay with jitter before the next attempt.
+func backoff(attempt int) time.Duration {
+	delay := min(constants.BackendRetryBase<<(attempt-1), constants.BackendRetryMax)
+
+	return delay/2 + rand.N(delay/2+1) //nolint:gosec // jitter doesn't need crypto random
+}
+
+func isAlreadyKnown(err *esplora.RejectError) bool {
+	message := strings.ToLower(err.Message)
+
+	return strings.Contains(message, "txn-already-known") ||
+		strings.Contains(message, "txn-already-in-mempool") ||
+		strings.Contains(message, "already in block chain")
+}
+
+func transactionHash(hexTx string) (txID string, err error) {
+	rawTx, err := hex.DecodeString(strings.TrimSpace(hexTx))
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	var tx wire.MsgTx
+	if err = tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	return tx.TxHash().String(), nil
+}
@@ -0,0 +1,390 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora/esploratest"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+)
+
+// recipientAddress is a testnet P2WPKH address from BIP173 test vectors.
+const recipientAddress = "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+
+func newServer(t *testing.T) *esploratest.Server {
+	t.Helper()
+
+	server := esploratest.NewServer()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newTestClient creates client for servers in failover order.
+func newTestClient(t *testing.T, servers ...*esploratest.Server) *Client {
+	t.Helper()
+
+	urls := make([]string, 0, len(servers))
+	for _, server := range servers {
+		urls = append(urls, server.BaseURL())
+	}
+
+	return NewClient(urls, 5*time.Second, filepath.Join(t.TempDir(), "tx_cache"))
+}
+
+func retryAfter(seconds string) http.Header {
+	return http.Header{"Retry-After": []string{seconds}}
+}
+
+func TestDo(t *testing.T) {
+	tipHash := func(ctx context.Context, c *Client) error {
+		_, err := do(ctx, c, true, func(client IEsploraClient) (string, error) {
+			return client.GetTipHash(ctx)
+		})
+		return err
+	}
+
+	tests := []struct {
+		name  string
+		setup func(first, second *esploratest.Server)
+		// request is tipHash by default
+		request     func(ctx context.Context, c *Client) error
+		path        string
+		calls       int
+		want        error
+		minDuration time.Duration
+		// requests of the endpoints
+		first, second int
+	}{
+		{
+			name:  "the first endpoint is used",
+			first: 1,
+		},
+		{
+			name: "retry on the same endpoint",
+			setup: func(first, _ *esploratest.Server) {
+				first.Fail(http.MethodGet, "/blocks/tip/hash", http.StatusServiceUnavailable, 2)
+			},
+			first: constants.BackendMaxAttempts,
+		},
+		{
+			name: "failover after max attempts",
+			setup: func(first, _ *esploratest.Server) {
+				first.Fail(http.MethodGet, "/blocks/tip/hash", http.StatusBadGateway, 0)
+			},
+			first:  constants.BackendMaxAttempts,
+			second: 1,
+		},
+		{
+			name: "not found isn't retried",
+			request: func(ctx context.Context, c *Client) error {
+				_, err := do(ctx, c, true, func(client IEsploraClient) (string, error) {
+					return client.GetTransactionHex(ctx, strings.Repeat("00", 32))
+				})
+				return err
+			},
+			path:  "/tx/",
+			want:  esplora.ErrNotFound,
+			first: 1,
+		},
+		{
+			name: "rate limited endpoint is paused",
+			setup: func(first, _ *esploratest.Server) {
+				first.FailWithHeader(http.MethodGet, "/blocks/tip/hash", http.StatusTooManyRequests, 0, retryAfter("60"))
+			},
+			calls:  2,
+			first:  1,
+			second: 2,
+		},
+		{
+			name: "the last endpoint waits for Retry-After",
+			setup: func(first, second *esploratest.Server) {
+				first.FailWithHeader(http.MethodGet, "/blocks/tip/hash", http.StatusTooManyRequests, 0, retryAfter("60"))
+				second.FailWithHeader(http.MethodGet, "/blocks/tip/hash", http.StatusTooManyRequests, 1, retryAfter("1"))
+			},
+			minDuration: time.Second,
+			first:       1,
+			second:      2,
+		},
+		{
+			name: "every endpoint is paused",
+			setup: func(first, second *esploratest.Server) {
+				first.FailWithHeader(http.MethodGet, "/blocks/tip/hash", http.StatusTooManyRequests, 0, retryAfter("60"))
+				second.FailWithHeader(http.MethodGet, "/blocks/tip/hash", http.StatusTooManyRequests, 0, retryAfter("60"))
+			},
+			calls:  2,
+			want:   ErrUnavailable,
+			first:  1,
+			second: 1,
+		},
+		{
+			name: "circuit breaker skips failing endpoint",
+			setup: func(first, _ *esploratest.Server) {
+				first.Fail(http.MethodGet, "/blocks/tip/hash", http.StatusInternalServerError, 0)
+			},
+			calls: 3,
+			// the breaker is opened by the second call
+			first:  constants.BackendBreakerThreshold,
+			second: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := newServer(t), newServer(t)
+			if tt.setup != nil {
+				tt.setup(first, second)
+			}
+			request := tt.request
+			if request == nil {
+				request = tipHash
+			}
+			path := tt.path
+			if path == "" {
+				path = "/blocks/tip/hash"
+			}
+
+			c := newTestClient(t, first, second)
+			start := time.Now()
+			var err error
+			for range max(tt.calls, 1) {
+				err = request(context.Background(), c)
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error is %v, want %v", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minDuration {
+				t.Fatalf("requests took %s, want at least %s", elapsed, tt.minDuration)
+			}
+			if got := first.Requests(http.MethodGet, path); got != tt.first {
+				t.Fatalf("the first endpoint got %d requests, want %d", got, tt.first)
+			}
+			if got := second.Requests(http.MethodGet, path); got != tt.second {
+				t.Fatalf("the second endpoint got %d requests, want %d", got, tt.second)
+			}
+		})
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	first, second := newServer(t), newServer(t)
+	ctx := context.Background()
+
+	// the transaction is already in mempool of the second endpoint
+	txID, err := second.Fund(recipientAddress, 10_000)
+	if err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+	hexTx, err := second.GetTransactionHex(ctx, txID)
+	if err != nil {
+		t.Fatalf("get hex: %v", err)
+	}
+
+	t.Run("already known is success", func(t *testing.T) {
+		result, err := newTestClient(t, second).Broadcast(ctx, hexTx)
+		if err != nil || result != txID {
+			t.Fatalf("broadcast gives %s, error %v, want %s", result, err, txID)
+		}
+	})
+
+	t.Run("broadcast isn't retried on the same endpoint", func(t *testing.T) {
+		first.Fail(http.MethodPost, "/tx", http.StatusServiceUnavailable, 0)
+
+		result, err := newTestClient(t, first, second).Broadcast(ctx, hexTx)
+		if err != nil || result != txID {
+			t.Fatalf("broadcast gives %s, error %v, want %s", result, err, txID)
+		}
+		if requests := first.Requests(http.MethodPost, "/tx"); requests != 1 {
+			t.Fatalf("the first endpoint got %d broadcasts, want 1", requests)
+		}
+	})
+
+	t.Run("rejected transaction isn't sent to the next endpoint", func(t *testing.T) {
+		var rejectErr *esplora.RejectError
+		if _, err := newTestClient(t, second, first).Broadcast(ctx, "00"); !errors.As(err, &rejectErr) {
+			t.Fatalf("broadcast error is %v, want rejection", err)
+		}
+		if requests := first.Requests(http.MethodPost, "/tx"); requests != 1 {
+			t.Fatalf("the next endpoint got %d broadcasts, want only the previous one", requests)
+		}
+	})
+}
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 10 * time.Second
+	b := newBreaker(2, cooldown)
+	now := time.Now()
+
+	if !b.allow(now) {
+		t.Fatal("closed breaker doesn't allow request")
+	}
+	b.failure(now)
+	if !b.allow(now) {
+		t.Fatal("breaker is opened before threshold")
+	}
+	b.failure(now)
+
+	// open
+	if b.allow(now.Add(cooldown-time.Second)) || b.available(now.Add(cooldown-time.Second)) {
+		t.Fatal("open breaker allows request")
+	}
+
+	// half-open: the only trial request
+	now = now.Add(cooldown)
+	if !b.allow(now) {
+		t.Fatal("half-open breaker doesn't allow trial request")
+	}
+	if b.allow(now) || b.available(now) {
+		t.Fatal("half-open breaker allows the second trial request")
+	}
+
+	// canceled trial is released
+	b.abort()
+	if !b.allow(now) {
+		t.Fatal("aborted trial isn't released")
+	}
+
+	// failed trial opens the breaker again
+	b.failure(now)
+	if b.allow(now.Add(cooldown - time.Second)) {
+		t.Fatal("breaker isn't opened by failed trial")
+	}
+
+	// successful trial closes the breaker
+	now = now.Add(cooldown)
+	if !b.allow(now) {
+		t.Fatal("half-open breaker doesn't allow trial request")
+	}
+	b.success()
+	if !b.allow(now) || !b.allow(now) {
+		t.Fatal("breaker isn't closed by successful trial")
+	}
+
+	// paused endpoint is skipped until the time
+	b.pause(now.Add(time.Minute))
+	if b.allow(now.Add(time.Minute-time.Second)) || !b.allow(now.Add(time.Minute)) {
+		t.Fatal("pause isn't applied")
+	}
+}
+
+// recheckTip makes the next request check the best block.
+func recheckTip(c *Client) {
+	c.tipMu.Lock()
+	defer c.tipMu.Unlock()
+
+	c.tipChecked = time.Time{}
+}
+
+func TestTipInvalidation(t *testing.T) {
+	server := newServer(t)
+	c := newTestClient(t, server)
+	ctx := context.Background()
+
+	utxos := func() int {
+		t.Helper()
+
+		result, err := c.GetAddressUTXOs(ctx, recipientAddress)
+		if err != nil {
+			t.Fatalf("get utxos: %v", err)
+		}
+
+		return len(result)
+	}
+
+	if _, err := server.Fund(recipientAddress, 10_000); err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+	if got := utxos(); got != 1 {
+		t.Fatalf("%d utxos, want 1", got)
+	}
+
+	// the cache isn't dropped without a new block
+	if _, err := server.Fund(recipientAddress, 10_000); err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+	recheckTip(c)
+	if got := utxos(); got != 1 {
+		t.Fatalf("%d utxos, want cached 1", got)
+	}
+
+	// a new block drops the cache
+	server.Mine()
+	recheckTip(c)
+	if got := utxos(); got != 2 {
+		t.Fatalf("%d utxos after a new block, want 2", got)
+	}
+
+	// reorganization changes the best block hash
+	if _, err := server.Fund(recipientAddress, 10_000); err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+	if err := server.Reorg(1); err != nil {
+		t.Fatalf("reorg: %v", err)
+	}
+	recheckTip(c)
+	if got := utxos(); got != 3 {
+		t.Fatalf("%d utxos after reorganization, want 3", got)
+	}
+
+	if requests := server.Requests(http.MethodGet, "/address/"); requests != 3 {
+		t.Fatalf("%d utxo requests, want 3", requests)
+	}
+}
+
+func TestStoredTransactions(t *testing.T) {
+	server := newServer(t)
+	ctx := context.Background()
+	cachePath := filepath.Join(t.TempDir(), "tx_cache")
+
+	deep, err := server.Fund(recipientAddress, 10_000)
+	if err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+	for range constants.TxCacheMinConfirmations - 1 {
+		server.Mine()
+	}
+	shallow, err := server.Fund(recipientAddress, 10_000)
+	if err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+	server.Mine()
+
+	c := NewClient([]string{server.BaseURL()}, 5*time.Second, cachePath)
+	for _, txID := range []string{deep, shallow} {
+		if _, err = c.GetTransaction(ctx, txID); err != nil {
+			t.Fatalf("get transaction: %v", err)
+		}
+	}
+
+	// the backend is unavailable, but the deep transaction is stored
+	server.Fail(http.MethodGet, "/", http.StatusServiceUnavailable, 0)
+	tipRequests, txRequests := server.Requests(http.MethodGet, "/blocks/"), server.Requests(http.MethodGet, "/tx/")
+
+	restarted := NewClient([]string{server.BaseURL()}, 5*time.Second, cachePath)
+	tx, err := restarted.GetTransaction(ctx, deep)
+	if err != nil || tx.TxID != deep || !tx.Status.Confirmed {
+		t.Fatalf("stored transaction is %+v, error %v", tx, err)
+	}
+	if status, err := restarted.GetTransactionStatus(ctx, deep); err != nil || !status.Confirmed {
+		t.Fatalf("stored status is %+v, error %v", status, err)
+	}
+	if server.Requests(http.MethodGet, "/blocks/") != tipRequests || server.Requests(http.MethodGet, "/tx/") != txRequests {
+		t.Fatal("stored transaction is requested")
+	}
+
+	// the shallow transaction can be changed by reorganization, it isn't stored
+	server.ClearFailures()
+	if _, err = restarted.GetTransaction(ctx, shallow); err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if server.Requests(http.MethodGet, "/tx/"+shallow) != 2 {
+		t.Fatal("shallow transaction is stored")
+	}
+}
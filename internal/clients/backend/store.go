@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// txStore keeps deeply confirmed transactions in a file, one JSON per line.
+// The file is only a cache: read and write errors are logged, the transaction is requested again.
+type txStore struct {
+	path string
+
+	once sync.Once
+	mu   sync.Mutex
+	txs  map[string]entities.Tx
+}
+
+func newTxStore(path string) *txStore {
+	return &txStore{
+		path: path,
+		txs:  make(map[string]entities.Tx),
+	}
+}
+
+func (s *txStore) get(txID string) (tx entities.Tx, ok bool) {
+	s.once.Do(s.load)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, ok = s.txs[txID]
+
+	return tx, ok
+}
+
+func (s *txStore) put(tx entities.Tx) {
+	s.once.Do(s.load)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.txs[tx.TxID]; ok {
+		return
+	}
+	s.txs[tx.TxID] = tx
+
+	if err := s.append(tx); err != nil {
+		slog.Warn("transaction isn't cached", "txid", tx.TxID, "error", err)
+	}
+}
+
+func (s *txStore) load() {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("transaction cache isn't loaded", "path", s.path, "error", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var tx entities.Tx
+		if err = decoder.Decode(&tx); err != nil {
+			// e.g. the last line is cut, loaded transactions are still valid
+			slog.Warn("transaction cache is broken", "path", s.path, "error", err)
+			return
+		}
+		s.txs[tx.TxID] = tx
+	}
+}
+
+func (s *txStore) append(tx entities.Tx) (err error) {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+	defer file.Close()
+
+	if err = json.NewEncoder(file).Encode(tx); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package backend
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+func TestTxStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tx_cache")
+	first := entities.Tx{TxID: "aa", Status: entities.TxStatus{Confirmed: true, BlockHeight: 10}}
+	second := entities.Tx{TxID: "bb", Status: entities.TxStatus{Confirmed: true, BlockHeight: 11}}
+
+	store := newTxStore(path)
+	if _, ok := store.get(first.TxID); ok {
+		t.Fatal("empty store has transaction")
+	}
+	store.put(first)
+	store.put(first)
+	store.put(second)
+
+	// the file is loaded by a new store
+	reloaded := newTxStore(path)
+	for _, want := range []entities.Tx{first, second} {
+		if tx, ok := reloaded.get(want.TxID); !ok || tx.Status != want.Status {
+			t.Fatalf("stored %s is %+v (found %v), want %+v", want.TxID, tx, ok, want)
+		}
+	}
+
+	// a transaction is written once
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Fatalf("store has %d lines, want 2", lines)
+	}
+
+	// the cut last line doesn't break loaded transactions
+	if err = os.WriteFile(path, data[:len(data)-10], 0o600); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+	broken := newTxStore(path)
+	if _, ok := broken.get(first.TxID); !ok {
+		t.Fatal("transaction before the broken line isn't loaded")
+	}
+	if _, ok := broken.get(second.TxID); ok {
+		t.Fatal("transaction of the broken line is loaded")
+	}
+
+	// the store is only a cache, unwritable file isn't an error
+	unwritable := newTxStore(filepath.Join(t.TempDir(), "missing", "tx_cache"))
+	unwritable.put(first)
+	if _, ok := unwritable.get(first.TxID); !ok {
+		t.Fatal("transaction isn't kept in memory")
+	}
+}
@@ -0,0 +1,322 @@
+package esplora
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/metrics"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// ErrNotFound is returned when the requested object is unknown to the backend (or was evicted from mempool).
+var ErrNotFound = errors.New("not found")
+
+// RejectError is returned when the backend node rejects a broadcasted transaction.
+type RejectError struct {
+	Code    int    // bitcoind RPC error code, e.g. -26 (RPC_VERIFY_REJECTED)
+	Message string // bitcoind reject reason, e.g. "min relay fee not met"
+}
+
+func (e *RejectError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("transaction rejected: %s", e.Message)
+	}
+
+	return fmt.Sprintf("transaction rejected (code %d): %s", e.Code, e.Message)
+}
+
+// HTTPError is returned for unexpected response status, e.g. rate limiting or server error.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // from Retry-After header, 0 if it isn't set
+}
+
+func (e *HTTPError) Error() string {
+	if e.Status == "" {
+		return fmt.Sprintf("%d: api error", e.StatusCode)
+	}
+
+	return fmt.Sprintf("%s: api error", e.Status) // status contains code
+}
+
+// Temporary reports whether the request can succeed later: rate limiting or server error.
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// Client is a client for Esplora HTTP API (https://github.com/Blockstream/esplora/blob/master/API.md).
+type Client struct {
+	client *resty.Client
+}
+
+// NewClient creates client for baseURL, every request is limited by timeout.
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	basePath := "/"
+	if parsed, err := url.Parse(baseURL); err == nil {
+		basePath = parsed.Path
+	}
+
+	return &Client{
+		client: resty.New().
+			SetBaseURL(baseURL).
+			SetTimeout(timeout).
+			OnSuccess(func(_ *resty.Client, resp *resty.Response) {
+				endpoint := requestEndpoint(resp.Request, basePath)
+				slog.Debug("backend request", "method", resp.Request.Method, "endpoint", endpoint,
+					"status", resp.StatusCode(), "duration", resp.Time())
+				metrics.BackendRequestDuration.WithLabelValues(endpoint).Observe(resp.Time().Seconds())
+				// not found and rejected transactions are regular answers
+				if resp.StatusCode() >= http.StatusInternalServerError || resp.StatusCode() == http.StatusTooManyRequests {
+					metrics.BackendRequestErrors.WithLabelValues(endpoint).Inc()
+				}
+			}).
+			OnError(func(req *resty.Request, err error) {
+				endpoint := requestEndpoint(req, basePath)
+				slog.Debug("backend request failed", "method", req.Method, "endpoint", endpoint, "error", err)
+				metrics.BackendRequestErrors.WithLabelValues(endpoint).Inc()
+			}),
+	}
+}
+
+// requestEndpoint returns metrics label of the request, e.g. "tx/:txid/status".
+func requestEndpoint(req *resty.Request, basePath string) string {
+	path := req.URL
+	if parsed, err := url.Parse(req.URL); err == nil {
+		path = parsed.Path
+	}
+
+	return metrics.Endpoint(strings.TrimPrefix(path, strings.TrimSuffix(basePath, "/")))
+}
+
+func (c *Client) GetTransaction(ctx context.Context, txID string) (result entities.Tx, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("tx/%s", txID))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+func (c *Client) GetTransactionHex(ctx context.Context, txID string) (result string, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		Get(fmt.Sprintf("tx/%s/hex", txID))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return strings.TrimSpace(resp.String()), nil
+}
+
+func (c *Client) GetTransactionStatus(ctx context.Context, txID string) (result entities.TxStatus, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("tx/%s/status", txID))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// GetOutspend returns spending status of the transaction output.
+func (c *Client) GetOutspend(ctx context.Context, txID string, vout uint32) (result entities.Outspend, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("tx/%s/outspend/%d", txID, vout))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+func (c *Client) GetAddressUTXOs(ctx context.Context, address string) (result entities.TxOutputs, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetResult(&result).
+		Get(fmt.Sprintf("address/%s/utxo", address))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// GetAddressTransactions returns all mempool and confirmed transactions of address, newest first.
+// First page contains up to 50 mempool and 25 confirmed transactions, next pages - 25 confirmed transactions.
+func (c *Client) GetAddressTransactions(ctx context.Context, address string) (result []entities.Tx, err error) {
+	path := fmt.Sprintf("address/%s/txs", address)
+	for {
+		var page []entities.Tx
+		resp, err := c.client.R().
+			SetContext(ctx).
+			SetResult(&page).
+			Get(path)
+		if err != nil {
+			return result, wrap.Wrap(err)
+		}
+
+		if err = checkResponse(resp); err != nil {
+			return result, wrap.Wrap(err)
+		}
+
+		result = append(result, page...)
+
+		confirmed := 0
+		for _, tx := range page {
+			if tx.Status.Confirmed {
+				confirmed++
+			}
+		}
+		if confirmed < 25 {
+			return result, nil
+		}
+
+		path = fmt.Sprintf("address/%s/txs/chain/%s", address, page[len(page)-1].TxID)
+	}
+}
+
+func (c *Client) GetTipHeight(ctx context.Context) (result int, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		Get("blocks/tip/height")
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = strconv.Atoi(strings.TrimSpace(resp.String()))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// GetTipHash returns hash of the best block.
+func (c *Client) GetTipHash(ctx context.Context) (result string, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		Get("blocks/tip/hash")
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return strings.TrimSpace(resp.String()), nil
+}
+
+// Broadcast sends raw transaction in hex and returns its ID.
+func (c *Client) Broadcast(ctx context.Context, hexTx string) (txID string, err error) {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "text/plain").
+		SetBody(hexTx).
+		Post("tx")
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	if resp.StatusCode() == http.StatusTooManyRequests || resp.StatusCode() >= http.StatusInternalServerError {
+		return "", wrap.Wrap(checkResponse(resp))
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return "", wrap.Wrap(parseRejectError(resp.String()))
+	}
+
+	return strings.TrimSpace(resp.String()), nil
+}
+
+// parseRejectError parses error like:
+// sendrawtransaction RPC error: {"code":-26,"message":"min relay fee not met, 100 < 141"}.
+func parseRejectError(body string) *RejectError {
+	var rpcError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	if idx := strings.Index(body, "{"); idx >= 0 {
+		if err := json.Unmarshal([]byte(body[idx:]), &rpcError); err == nil && rpcError.Message != "" {
+			return &RejectError{Code: rpcError.Code, Message: rpcError.Message}
+		}
+	}
+
+	return &RejectError{Message: strings.TrimSpace(body)}
+}
+
+func checkResponse(resp *resty.Response) error {
+	if resp.StatusCode() == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if resp.IsError() {
+		return &HTTPError{
+			StatusCode: resp.StatusCode(),
+			Status:     resp.Status(),
+			RetryAfter: parseRetryAfter(resp.Header().Get("Retry-After")),
+		}
+	}
+
+	return nil
+}
+
+// parseRetryAfter parses Retry-After header in seconds or HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
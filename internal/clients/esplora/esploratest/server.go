@@ -0,0 +1,276 @@
+// Package esploratest provides in-process fake Esplora HTTP API for tests.
+//
+// Server serves simulator.Chain: addresses are funded by Fund, mempool transactions are confirmed by Mine.
+// Broadcasted transactions are validated like by a node, API errors are simulated by Fail.
+package esploratest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/simulator"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+const pageSize = 25 // confirmed transactions per page of address transactions
+
+type (
+	failure struct {
+		method string
+		path   string
+		status int
+		header http.Header
+		times  int // 0 - always
+	}
+
+	// Server is a fake Esplora API, it is safe for concurrent use.
+	// Chain methods, e.g. Fund, Mine and Reorg, change the served chain.
+	Server struct {
+		*httptest.Server
+		*simulator.Chain
+
+		mu       sync.Mutex
+		fees     map[string]float64
+		failures []*failure
+		requests map[string]int // "METHOD path" -> count
+	}
+)
+
+// NewServer starts fake Esplora API with a new chain, it must be closed by Close.
+func NewServer() *Server {
+	s := &Server{
+		Chain:    simulator.NewChain(),
+		fees:     map[string]float64{"1": 20, "3": 10, "6": 5, "144": 1},
+		requests: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tx/{txid}", s.handleTx)
+	mux.HandleFunc("GET /tx/{txid}/hex", s.handleTxHex)
+	mux.HandleFunc("GET /tx/{txid}/status", s.handleTxStatus)
+	mux.HandleFunc("GET /tx/{txid}/outspend/{vout}", s.handleOutspend)
+	mux.HandleFunc("GET /address/{address}/utxo", s.handleUTXOs)
+	mux.HandleFunc("GET /address/{address}/txs", s.handleAddressTxs)
+	mux.HandleFunc("GET /address/{address}/txs/chain/{last}", s.handleAddressTxs)
+	mux.HandleFunc("GET /blocks/tip/height", s.handleTipHeight)
+	mux.HandleFunc("GET /blocks/tip/hash", s.handleTipHash)
+	mux.HandleFunc("GET /fee-estimates", s.handleFeeEstimates)
+	mux.HandleFunc("POST /tx", s.handleBroadcast)
+
+	s.Server = httptest.NewServer(s.intercept(mux))
+
+	return s
+}
+
+// BaseURL returns API URL for esplora.NewClient.
+func (s *Server) BaseURL() string {
+	return s.URL + "/"
+}
+
+// Fail makes next times requests answer with status, path is matched by prefix, e.g. "/address/".
+// If times is 0, all requests fail until ClearFailures.
+func (s *Server) Fail(method, path string, status, times int) {
+	s.FailWithHeader(method, path, status, times, nil)
+}
+
+// FailWithHeader is like Fail, header is added to the failed responses, e.g. Retry-After.
+func (s *Server) FailWithHeader(method, path string, status, times int, header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, &failure{method: method, path: path, status: status, header: header, times: times})
+}
+
+// ClearFailures removes all failures set by Fail.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = nil
+}
+
+// Requests returns number of requests with method whose path starts with prefix.
+func (s *Server) Requests(method, prefix string) (count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, n := range s.requests {
+		if strings.HasPrefix(key, method+" "+prefix) {
+			count += n
+		}
+	}
+
+	return count
+}
+
+// SetFeeEstimates replaces fee estimates: confirmation target -> sat/vbyte.
+func (s *Server) SetFeeEstimates(fees map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fees = fees
+}
+
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.Method+" "+r.URL.Path]++
+		var failed *failure
+		for _, f := range s.failures {
+			if f.method == r.Method && strings.HasPrefix(r.URL.Path, f.path) {
+				failed = f
+				break
+			}
+		}
+		if failed != nil && failed.times > 0 {
+			failed.times--
+			if failed.times == 0 {
+				s.failures = slices.DeleteFunc(s.failures, func(f *failure) bool { return f == failed })
+			}
+		}
+		s.mu.Unlock()
+
+		if failed != nil {
+			for key, values := range failed.header {
+				w.Header()[key] = values
+			}
+			http.Error(w, http.StatusText(failed.status), failed.status)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleTx(w http.ResponseWriter, r *http.Request) {
+	result, err := s.GetTransaction(r.Context(), r.PathValue("txid"))
+	writeResult(w, result, err)
+}
+
+func (s *Server) handleTxHex(w http.ResponseWriter, r *http.Request) {
+	result, err := s.GetTransactionHex(r.Context(), r.PathValue("txid"))
+	writeText(w, result, err)
+}
+
+func (s *Server) handleTxStatus(w http.ResponseWriter, r *http.Request) {
+	result, err := s.GetTransactionStatus(r.Context(), r.PathValue("txid"))
+	writeResult(w, result, err)
+}
+
+func (s *Server) handleOutspend(w http.ResponseWriter, r *http.Request) {
+	vout, err := strconv.ParseUint(r.PathValue("vout"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid vout", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.GetOutspend(r.Context(), r.PathValue("txid"), uint32(vout))
+	writeResult(w, result, err)
+}
+
+func (s *Server) handleUTXOs(w http.ResponseWriter, r *http.Request) {
+	result, err := s.GetAddressUTXOs(r.Context(), r.PathValue("address"))
+	writeResult(w, result, err)
+}
+
+// handleAddressTxs returns mempool transactions and the first page of confirmed ones, newest first.
+// Next pages of confirmed transactions are requested after the last seen txid.
+func (s *Server) handleAddressTxs(w http.ResponseWriter, r *http.Request) {
+	txs, err := s.GetAddressTransactions(r.Context(), r.PathValue("address"))
+	if err != nil {
+		writeResult(w, nil, err)
+		return
+	}
+
+	idx := slices.IndexFunc(txs, func(tx entities.Tx) bool { return tx.Status.Confirmed })
+	if idx < 0 {
+		idx = len(txs)
+	}
+	result, confirmed := txs[:idx], txs[idx:]
+
+	if last := r.PathValue("last"); last != "" {
+		result = nil
+		idx = slices.IndexFunc(confirmed, func(tx entities.Tx) bool { return tx.TxID == last })
+		confirmed = confirmed[idx+1:]
+	}
+	result = append([]entities.Tx{}, result...)
+	result = append(result, confirmed[:min(pageSize, len(confirmed))]...)
+
+	writeResult(w, result, nil)
+}
+
+func (s *Server) handleTipHeight(w http.ResponseWriter, r *http.Request) {
+	result, err := s.GetTipHeight(r.Context())
+	writeText(w, strconv.Itoa(result), err)
+}
+
+func (s *Server) handleTipHash(w http.ResponseWriter, r *http.Request) {
+	result, err := s.GetTipHash(r.Context())
+	writeText(w, result, err)
+}
+
+func (s *Server) handleFeeEstimates(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeResult(w, s.fees, nil)
+}
+
+func (s *Server) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// like a node, validation isn't stopped when the client is gone
+	txID, err := s.Broadcast(context.WithoutCancel(r.Context()), strings.TrimSpace(string(body)))
+	var rejectErr *esplora.RejectError
+	if errors.As(err, &rejectErr) {
+		message, _ := json.Marshal(map[string]any{"code": rejectErr.Code, "message": rejectErr.Message})
+		http.Error(w, fmt.Sprintf("sendrawtransaction RPC error: %s", message), http.StatusBadRequest)
+		return
+	}
+
+	writeText(w, txID, err)
+}
+
+func writeResult(w http.ResponseWriter, result any, err error) {
+	if writeError(w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result) //nolint:errcheck // client can be disconnected
+}
+
+func writeText(w http.ResponseWriter, result string, err error) {
+	if writeError(w, err) {
+		return
+	}
+
+	io.WriteString(w, result) //nolint:errcheck // client can be disconnected
+}
+
+func writeError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, esplora.ErrNotFound):
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+
+	return true
+}
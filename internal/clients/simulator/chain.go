@@ -0,0 +1,322 @@
+// Package simulator provides deterministic in-memory testnet chain for tests.
+//
+// Chain has the same methods as the backend client, so wallet services work with it without network or bitcoind.
+// Blocks are produced by Mine, broadcasted transactions are validated like by a node: inputs must exist and be
+// unspent, scripts are verified by txscript, fee must cover min relay fee. Conflicting mempool transactions are
+// replaced by fee (full RBF), Reorg moves confirmed transactions back to mempool.
+package simulator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+const (
+	// StartHeight is the tip height of a new chain.
+	StartHeight = 100
+	// MinRelayFeeRate is min fee rate of broadcasted transactions and min fee rate increase of replacements, sat/vbyte.
+	MinRelayFeeRate = 1
+
+	genesisTime = 1700000000
+	blockPeriod = 600 // seconds
+)
+
+var (
+	// ErrNotInMempool is returned by Evict for unknown or confirmed transaction.
+	ErrNotInMempool = errors.New("transaction isn't in mempool")
+	// ErrReorgTooDeep is returned by Reorg if there are less blocks than reorg depth.
+	ErrReorgTooDeep = errors.New("reorg is deeper than the chain")
+)
+
+type (
+	txEntry struct {
+		tx       *wire.MsgTx
+		prevOuts []*wire.TxOut // nil for funding transactions
+		fee      int64
+		height   int // 0 for mempool transaction
+		seq      int // order of arrival
+	}
+
+	// Chain is in-memory chain with mempool, it is safe for concurrent use.
+	Chain struct {
+		mu     sync.Mutex
+		blocks []string // block hashes, index 0 is the block at StartHeight
+		txs    map[string]*txEntry
+		spent  map[wire.OutPoint]string // outpoint -> spending txid, mempool spends too
+		seq    int
+		branch int // number of reorgs, it makes hashes of new blocks differ
+	}
+)
+
+// NewChain creates chain with tip at StartHeight and empty mempool.
+func NewChain() *Chain {
+	c := &Chain{
+		txs:   make(map[string]*txEntry),
+		spent: make(map[wire.OutPoint]string),
+	}
+	c.blocks = []string{c.blockHash(StartHeight)}
+
+	return c
+}
+
+// Fund sends value satoshi to address by a transaction from outside, it stays in mempool until Mine.
+func (c *Chain) Fund(address string, value int64) (txID string, err error) {
+	decoded, err := btcutil.DecodeAddress(address, &chaincfg.TestNet3Params)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(decoded)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the input spends an output which is unknown to the chain
+	c.seq++
+	source := chainhash.Hash(sha256.Sum256([]byte("funding " + strconv.Itoa(c.seq))))
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&source, 0), []byte{txscript.OP_TRUE}, nil))
+	tx.AddTxOut(wire.NewTxOut(value, pkScript))
+
+	txID = tx.TxHash().String()
+	c.txs[txID] = &txEntry{tx: tx, seq: c.seq}
+
+	return txID, nil
+}
+
+// Mine confirms all mempool transactions in a new block and returns its height.
+func (c *Chain) Mine() (height int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	height = c.tipHeight() + 1
+	c.blocks = append(c.blocks, c.blockHash(height))
+	for _, entry := range c.txs {
+		if entry.height == 0 {
+			entry.height = height
+		}
+	}
+
+	return height
+}
+
+// Reorg replaces depth last blocks by depth+1 empty blocks of another branch.
+// Transactions of disconnected blocks are moved back to mempool, so they have no confirmations until Mine.
+func (c *Chain) Reorg(depth int) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if depth <= 0 || depth >= len(c.blocks) {
+		return wrap.Wrap(fmt.Errorf("%w: depth %d", ErrReorgTooDeep, depth))
+	}
+
+	forkHeight := c.tipHeight() - depth
+	for _, entry := range c.txs {
+		if entry.height > forkHeight {
+			entry.height = 0
+		}
+	}
+
+	c.branch++
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+	for height := forkHeight + 1; height <= forkHeight+depth+1; height++ {
+		c.blocks = append(c.blocks, c.blockHash(height))
+	}
+
+	return nil
+}
+
+// Evict removes mempool transaction and its descendants, like expiration or conflict with a block.
+func (c *Chain) Evict(txID string) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.txs[txID]
+	if !ok || entry.height != 0 {
+		return wrap.Wrap(fmt.Errorf("%w: %s", ErrNotInMempool, txID))
+	}
+
+	c.remove(txID)
+
+	return nil
+}
+
+// Mempool returns IDs of unconfirmed transactions in order of arrival.
+func (c *Chain) Mempool() (txIDs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.sortedTxs() {
+		if entry.height == 0 {
+			txIDs = append(txIDs, entry.tx.TxHash().String())
+		}
+	}
+
+	return txIDs
+}
+
+// Transaction returns known transaction.
+func (c *Chain) Transaction(txID string) (tx *wire.MsgTx, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.txs[txID]
+	if !ok {
+		return nil, false
+	}
+
+	return entry.tx.Copy(), true
+}
+
+// accept validates transaction like a node and adds it into mempool, conflicting transactions are replaced.
+func (c *Chain) accept(hexTx string) (txID string, err error) {
+	rawTx, err := hex.DecodeString(hexTx)
+	if err != nil {
+		return "", &esplora.RejectError{Code: -22, Message: "TX decode failed"}
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err = tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return "", &esplora.RejectError{Code: -22, Message: "TX decode failed"}
+	}
+
+	txID = tx.TxHash().String()
+	if entry, ok := c.txs[txID]; ok {
+		if entry.height == 0 {
+			return "", &esplora.RejectError{Code: -26, Message: "txn-already-in-mempool"}
+		}
+		return "", &esplora.RejectError{Code: -27, Message: "Transaction already in block chain"}
+	}
+
+	prevOuts := make([]*wire.TxOut, 0, len(tx.TxIn))
+	conflicts := make(map[string]struct{})
+	var inputValue, outputValue int64
+	for _, txIn := range tx.TxIn {
+		prev, ok := c.txs[txIn.PreviousOutPoint.Hash.String()]
+		if !ok || int(txIn.PreviousOutPoint.Index) >= len(prev.tx.TxOut) {
+			return "", &esplora.RejectError{Code: -25, Message: "bad-txns-inputs-missingorspent"}
+		}
+		if spendingTxID, ok := c.spent[txIn.PreviousOutPoint]; ok {
+			if c.txs[spendingTxID].height != 0 {
+				return "", &esplora.RejectError{Code: -25, Message: "bad-txns-inputs-missingorspent"}
+			}
+			conflicts[spendingTxID] = struct{}{}
+		}
+		prevOut := prev.tx.TxOut[txIn.PreviousOutPoint.Index]
+		prevOuts = append(prevOuts, prevOut)
+		inputValue += prevOut.Value
+	}
+
+	for _, txOut := range tx.TxOut {
+		if mempool.IsDust(txOut, mempool.DefaultMinRelayTxFee) {
+			return "", &esplora.RejectError{Code: -26, Message: "dust"}
+		}
+		outputValue += txOut.Value
+	}
+
+	if outputValue > inputValue {
+		return "", &esplora.RejectError{Code: -26, Message: "bad-txns-in-belowout"}
+	}
+
+	sigHashes := txscript.NewTxSigHashes(tx)
+	for idx := range tx.TxIn {
+		engine, err := txscript.NewEngine(prevOuts[idx].PkScript, tx, idx, txscript.StandardVerifyFlags, nil, sigHashes, prevOuts[idx].Value)
+		if err == nil {
+			err = engine.Execute()
+		}
+		if err != nil {
+			return "", &esplora.RejectError{Code: -26, Message: fmt.Sprintf("mandatory-script-verify-flag-failed (%s)", err)}
+		}
+	}
+
+	fee := inputValue - outputValue
+	vsize := mempool.GetTxVirtualSize(btcutil.NewTx(tx))
+	if minFee := vsize * MinRelayFeeRate; fee < minFee {
+		return "", &esplora.RejectError{Code: -26, Message: fmt.Sprintf("min relay fee not met, %d < %d", fee, minFee)}
+	}
+
+	// BIP125: replacement pays for itself and has higher fee rate than replaced transactions
+	for conflictTxID := range conflicts {
+		conflict := c.txs[conflictTxID]
+		conflictVSize := mempool.GetTxVirtualSize(btcutil.NewTx(conflict.tx))
+		if fee*conflictVSize <= conflict.fee*vsize {
+			return "", &esplora.RejectError{Code: -26, Message: "insufficient fee, rejecting replacement " + txID}
+		}
+		if fee < conflict.fee+vsize*MinRelayFeeRate {
+			return "", &esplora.RejectError{Code: -26, Message: "insufficient fee, rejecting replacement " + txID}
+		}
+	}
+	for conflictTxID := range conflicts {
+		c.remove(conflictTxID)
+	}
+
+	c.seq++
+	c.txs[txID] = &txEntry{tx: tx, prevOuts: prevOuts, fee: fee, seq: c.seq}
+	for _, txIn := range tx.TxIn {
+		c.spent[txIn.PreviousOutPoint] = txID
+	}
+
+	return txID, nil
+}
+
+// remove drops transaction with its descendants and frees its inputs.
+func (c *Chain) remove(txID string) {
+	entry, ok := c.txs[txID]
+	if !ok {
+		return
+	}
+
+	hash := entry.tx.TxHash()
+	for idx := range entry.tx.TxOut {
+		if spendingTxID, ok := c.spent[*wire.NewOutPoint(&hash, uint32(idx))]; ok {
+			c.remove(spendingTxID)
+		}
+	}
+
+	for _, txIn := range entry.tx.TxIn {
+		if c.spent[txIn.PreviousOutPoint] == txID {
+			delete(c.spent, txIn.PreviousOutPoint)
+		}
+	}
+	delete(c.txs, txID)
+}
+
+// sortedTxs returns transactions in order of arrival.
+func (c *Chain) sortedTxs() []*txEntry {
+	result := make([]*txEntry, 0, len(c.txs))
+	for _, entry := range c.txs {
+		result = append(result, entry)
+	}
+	slices.SortFunc(result, func(a, b *txEntry) int { return a.seq - b.seq })
+
+	return result
+}
+
+func (c *Chain) tipHeight() int {
+	return StartHeight + len(c.blocks) - 1
+}
+
+func (c *Chain) blockHash(height int) string {
+	hash := chainhash.Hash(sha256.Sum256([]byte(fmt.Sprintf("block %d branch %d", height, c.branch))))
+
+	return hash.String()
+}
@@ -0,0 +1,263 @@
+package simulator_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/simulator"
+)
+
+type account struct {
+	key      *btcec.PrivateKey
+	address  string
+	pkScript []byte
+}
+
+func newAccount(t *testing.T, seed string) account {
+	t.Helper()
+
+	hash := sha256.Sum256([]byte(seed))
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), hash[:])
+
+	address, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("create address: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatalf("create script: %v", err)
+	}
+
+	return account{key: key, address: address.EncodeAddress(), pkScript: pkScript}
+}
+
+// spend signs transaction which spends output vout of prevTx to outputs.
+func (a account) spend(t *testing.T, prevTx *wire.MsgTx, vout uint32, outputs ...*wire.TxOut) *wire.MsgTx {
+	t.Helper()
+
+	prevHash := prevTx.TxHash()
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, vout), nil, nil))
+	for _, txOut := range outputs {
+		tx.AddTxOut(txOut)
+	}
+
+	witness, err := txscript.WitnessSignature(tx, txscript.NewTxSigHashes(tx), 0, prevTx.TxOut[vout].Value, a.pkScript, txscript.SigHashAll, a.key, true)
+	if err != nil {
+		t.Fatalf("sign transaction: %v", err)
+	}
+	tx.TxIn[0].Witness = witness
+
+	return tx
+}
+
+// funded returns confirmed transaction which sends value to account.
+func funded(t *testing.T, chain *simulator.Chain, to account, value int64) *wire.MsgTx {
+	t.Helper()
+
+	txID, err := chain.Fund(to.address, value)
+	if err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+	chain.Mine()
+
+	tx, _ := chain.Transaction(txID)
+
+	return tx
+}
+
+func encode(t *testing.T, tx *wire.MsgTx) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func rejectReason(err error) string {
+	var rejectErr *esplora.RejectError
+	if !errors.As(err, &rejectErr) {
+		return ""
+	}
+
+	return rejectErr.Message
+}
+
+func TestBroadcastValidation(t *testing.T) {
+	ctx := context.Background()
+	chain := simulator.NewChain()
+	alice, bob := newAccount(t, "alice"), newAccount(t, "bob")
+	fundingTx := funded(t, chain, alice, 10_000)
+
+	valid := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_000, bob.pkScript))
+	forged := bob.spend(t, fundingTx, 0, wire.NewTxOut(9_000, bob.pkScript))
+	unknownInput := alice.spend(t, valid, 0, wire.NewTxOut(8_000, bob.pkScript))
+	overspend := alice.spend(t, fundingTx, 0, wire.NewTxOut(11_000, bob.pkScript))
+	lowFee := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_990, bob.pkScript))
+	dust := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_000, bob.pkScript), wire.NewTxOut(100, alice.pkScript))
+
+	for _, tc := range []struct {
+		name   string
+		hexTx  string
+		reason string
+	}{
+		{name: "not a transaction", hexTx: "00", reason: "TX decode failed"},
+		{name: "wrong key", hexTx: encode(t, forged), reason: "mandatory-script-verify-flag-failed"},
+		{name: "unknown input", hexTx: encode(t, unknownInput), reason: "bad-txns-inputs-missingorspent"},
+		{name: "outputs above inputs", hexTx: encode(t, overspend), reason: "bad-txns-in-belowout"},
+		{name: "fee below min relay fee", hexTx: encode(t, lowFee), reason: "min relay fee not met"},
+		{name: "dust output", hexTx: encode(t, dust), reason: "dust"},
+		{name: "valid", hexTx: encode(t, valid)},
+		{name: "already in mempool", hexTx: encode(t, valid), reason: "txn-already-in-mempool"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			txID, err := chain.Broadcast(ctx, tc.hexTx)
+			if tc.reason == "" {
+				if err != nil || txID != valid.TxHash().String() {
+					t.Fatalf("broadcast: txid %s, error %v", txID, err)
+				}
+				return
+			}
+			if reason := rejectReason(err); !strings.HasPrefix(reason, tc.reason) {
+				t.Fatalf("reject reason is %q (error %v), want %q", reason, err, tc.reason)
+			}
+		})
+	}
+
+	chain.Mine()
+	doubleSpend := alice.spend(t, fundingTx, 0, wire.NewTxOut(5_000, alice.pkScript))
+	if _, err := chain.Broadcast(ctx, encode(t, doubleSpend)); rejectReason(err) != "bad-txns-inputs-missingorspent" {
+		t.Fatalf("spend of confirmed spent output: error %v", err)
+	}
+}
+
+func TestReplaceByFee(t *testing.T) {
+	ctx := context.Background()
+	chain := simulator.NewChain()
+	alice, bob := newAccount(t, "alice"), newAccount(t, "bob")
+	fundingTx := funded(t, chain, alice, 10_000)
+
+	original := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_800, alice.pkScript))
+	child := alice.spend(t, original, 0, wire.NewTxOut(9_600, bob.pkScript))
+	for _, tx := range []*wire.MsgTx{original, child} {
+		if _, err := chain.Broadcast(ctx, encode(t, tx)); err != nil {
+			t.Fatalf("broadcast: %v", err)
+		}
+	}
+
+	smallBump := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_750, bob.pkScript))
+	if _, err := chain.Broadcast(ctx, encode(t, smallBump)); rejectReason(err) == "" {
+		t.Fatalf("replacement without fee for its size: error %v, want rejection", err)
+	}
+
+	replacement := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_000, bob.pkScript))
+	replacementTxID, err := chain.Broadcast(ctx, encode(t, replacement))
+	if err != nil {
+		t.Fatalf("broadcast replacement: %v", err)
+	}
+
+	// the replaced transaction is evicted with its child
+	for _, tx := range []*wire.MsgTx{original, child} {
+		if _, err = chain.GetTransaction(ctx, tx.TxHash().String()); !errors.Is(err, esplora.ErrNotFound) {
+			t.Fatalf("replaced transaction %s: error %v, want not found", tx.TxHash(), err)
+		}
+	}
+
+	outspend, err := chain.GetOutspend(ctx, fundingTx.TxHash().String(), 0)
+	if err != nil || !outspend.Spent || outspend.TxID != replacementTxID {
+		t.Fatalf("outspend is %+v, error %v, want spent by %s", outspend, err, replacementTxID)
+	}
+}
+
+func TestReorg(t *testing.T) {
+	ctx := context.Background()
+	chain := simulator.NewChain()
+	alice, bob := newAccount(t, "alice"), newAccount(t, "bob")
+	fundingTx := funded(t, chain, alice, 10_000)
+
+	spendTx := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_000, bob.pkScript))
+	spendTxID, err := chain.Broadcast(ctx, encode(t, spendTx))
+	if err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+	minedHeight := chain.Mine()
+
+	status, err := chain.GetTransactionStatus(ctx, spendTxID)
+	if err != nil || !status.Confirmed || status.BlockHeight != minedHeight {
+		t.Fatalf("status is %+v, error %v, want confirmed at %d", status, err, minedHeight)
+	}
+	orphanedHash, _ := chain.GetTipHash(ctx)
+
+	if err = chain.Reorg(1); err != nil {
+		t.Fatalf("reorg: %v", err)
+	}
+
+	// the new branch is one block longer, the transaction is back in mempool
+	if height, _ := chain.GetTipHeight(ctx); height != minedHeight+1 {
+		t.Fatalf("tip height is %d, want %d", height, minedHeight+1)
+	}
+	if status, _ = chain.GetTransactionStatus(ctx, spendTxID); status.Confirmed {
+		t.Fatalf("reorged transaction is still confirmed: %+v", status)
+	}
+	if mempool := chain.Mempool(); len(mempool) != 1 || mempool[0] != spendTxID {
+		t.Fatalf("mempool is %v, want %s", mempool, spendTxID)
+	}
+	if status, _ = chain.GetTransactionStatus(ctx, fundingTx.TxHash().String()); status.BlockHeight != simulator.StartHeight+1 {
+		t.Fatalf("transaction below the fork is moved: %+v", status)
+	}
+
+	chain.Mine()
+	status, _ = chain.GetTransactionStatus(ctx, spendTxID)
+	if !status.Confirmed || status.BlockHash == orphanedHash {
+		t.Fatalf("transaction isn't confirmed in the new branch: %+v", status)
+	}
+
+	if err = chain.Reorg(1_000); !errors.Is(err, simulator.ErrReorgTooDeep) {
+		t.Fatalf("deep reorg: error %v, want %v", err, simulator.ErrReorgTooDeep)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	ctx := context.Background()
+	chain := simulator.NewChain()
+	alice, bob := newAccount(t, "alice"), newAccount(t, "bob")
+	fundingTx := funded(t, chain, alice, 10_000)
+
+	parent := alice.spend(t, fundingTx, 0, wire.NewTxOut(9_800, alice.pkScript))
+	child := alice.spend(t, parent, 0, wire.NewTxOut(9_600, bob.pkScript))
+	for _, tx := range []*wire.MsgTx{parent, child} {
+		if _, err := chain.Broadcast(ctx, encode(t, tx)); err != nil {
+			t.Fatalf("broadcast: %v", err)
+		}
+	}
+
+	if err := chain.Evict(parent.TxHash().String()); err != nil {
+		t.Fatalf("evict: %v", err)
+	}
+	if mempool := chain.Mempool(); len(mempool) != 0 {
+		t.Fatalf("mempool is %v, want empty", mempool)
+	}
+
+	utxos, err := chain.GetAddressUTXOs(ctx, alice.address)
+	if err != nil || len(utxos) != 1 || utxos[0].TxID != fundingTx.TxHash().String() {
+		t.Fatalf("UTXOs are %+v, error %v, want the funding output", utxos, err)
+	}
+
+	if err = chain.Evict(fundingTx.TxHash().String()); !errors.Is(err, simulator.ErrNotInMempool) {
+		t.Fatalf("evict confirmed transaction: error %v, want %v", err, simulator.ErrNotInMempool)
+	}
+}
@@ -0,0 +1,306 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// Methods below mirror the backend client: unknown transactions are esplora.ErrNotFound,
+// rejected broadcasts are *esplora.RejectError.
+
+func (c *Chain) GetTransaction(ctx context.Context, txID string) (result entities.Tx, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.txs[txID]
+	if !ok {
+		return result, wrap.Wrap(fmt.Errorf("transaction %s: %w", txID, esplora.ErrNotFound))
+	}
+
+	return c.txJSON(entry), nil
+}
+
+// GetTransactions returns found transactions by txid, unknown transactions are omitted.
+func (c *Chain) GetTransactions(ctx context.Context, txIDs []string) (result map[string]entities.Tx, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result = make(map[string]entities.Tx, len(txIDs))
+	for _, txID := range txIDs {
+		if entry, ok := c.txs[txID]; ok {
+			result[txID] = c.txJSON(entry)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Chain) GetTransactionHex(ctx context.Context, txID string) (result string, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.txs[txID]
+	if !ok {
+		return result, wrap.Wrap(fmt.Errorf("transaction %s: %w", txID, esplora.ErrNotFound))
+	}
+
+	var buf bytes.Buffer
+	if err = entry.tx.Serialize(&buf); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+func (c *Chain) GetTransactionStatus(ctx context.Context, txID string) (result entities.TxStatus, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.txs[txID]
+	if !ok {
+		return result, wrap.Wrap(fmt.Errorf("transaction %s: %w", txID, esplora.ErrNotFound))
+	}
+
+	return c.status(entry), nil
+}
+
+func (c *Chain) GetOutspend(ctx context.Context, txID string, vout uint32) (result entities.Outspend, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	hash, err := chainhash.NewHashFromStr(txID)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	spendingTxID, ok := c.spent[*wire.NewOutPoint(hash, vout)]
+	if !ok {
+		return result, nil
+	}
+
+	spending := c.txs[spendingTxID]
+	result = entities.Outspend{Spent: true, TxID: spendingTxID, Status: c.status(spending)}
+	for idx, txIn := range spending.tx.TxIn {
+		if txIn.PreviousOutPoint.Hash == *hash && txIn.PreviousOutPoint.Index == vout {
+			result.Vin = idx
+		}
+	}
+
+	return result, nil
+}
+
+// GetAddressUTXOs returns unspent outputs of address, outputs spent in mempool aren't returned.
+func (c *Chain) GetAddressUTXOs(ctx context.Context, address string) (result entities.TxOutputs, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result = entities.TxOutputs{}
+	for _, entry := range c.sortedTxs() {
+		hash := entry.tx.TxHash()
+		for idx, txOut := range entry.tx.TxOut {
+			if addressOf(txOut.PkScript) != address {
+				continue
+			}
+			if _, ok := c.spent[*wire.NewOutPoint(&hash, uint32(idx))]; ok {
+				continue
+			}
+			result = append(result, entities.TxOutput{
+				TxID:   hash.String(),
+				Vout:   idx,
+				Status: c.status(entry),
+				Value:  txOut.Value,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// GetAddressTransactions returns mempool transactions of address and then confirmed ones, newest first.
+func (c *Chain) GetAddressTransactions(ctx context.Context, address string) (result []entities.Tx, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var confirmed []entities.Tx
+	sorted := c.sortedTxs()
+	for i := len(sorted) - 1; i >= 0; i-- {
+		entry := sorted[i]
+		if !touches(entry, address) {
+			continue
+		}
+		if entry.height == 0 {
+			result = append(result, c.txJSON(entry))
+		} else {
+			confirmed = append(confirmed, c.txJSON(entry))
+		}
+	}
+
+	return append(result, confirmed...), nil
+}
+
+func (c *Chain) GetTipHeight(ctx context.Context) (result int, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tipHeight(), nil
+}
+
+func (c *Chain) GetTipHash(ctx context.Context) (result string, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.blocks[len(c.blocks)-1], nil
+}
+
+func (c *Chain) Broadcast(ctx context.Context, hexTx string) (txID string, err error) {
+	if err = ctx.Err(); err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	txID, err = c.accept(hexTx)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	return txID, nil
+}
+
+func (c *Chain) status(entry *txEntry) entities.TxStatus {
+	if entry.height == 0 {
+		return entities.TxStatus{}
+	}
+
+	return entities.TxStatus{
+		Confirmed:   true,
+		BlockHeight: entry.height,
+		BlockHash:   c.blocks[entry.height-StartHeight],
+		BlockTime:   genesisTime + int64(entry.height)*blockPeriod,
+	}
+}
+
+func (c *Chain) txJSON(entry *txEntry) entities.Tx {
+	result := entities.Tx{
+		TxID:     entry.tx.TxHash().String(),
+		Version:  int(entry.tx.Version),
+		LockTime: int(entry.tx.LockTime),
+		Vin:      make([]entities.Vin, 0, len(entry.tx.TxIn)),
+		Vout:     make([]entities.Vout, 0, len(entry.tx.TxOut)),
+		Size:     entry.tx.SerializeSize(),
+		Fee:      entry.fee,
+		Status:   c.status(entry),
+	}
+
+	for idx, txIn := range entry.tx.TxIn {
+		vin := entities.Vin{
+			TxID:     txIn.PreviousOutPoint.Hash.String(),
+			Vout:     txIn.PreviousOutPoint.Index,
+			Sequence: txIn.Sequence,
+		}
+		for _, item := range txIn.Witness {
+			vin.Witness = append(vin.Witness, hex.EncodeToString(item))
+		}
+		if idx < len(entry.prevOuts) {
+			prevout := voutJSON(entry.prevOuts[idx])
+			vin.Prevout = &prevout
+		}
+		result.Vin = append(result.Vin, vin)
+	}
+
+	for _, txOut := range entry.tx.TxOut {
+		result.Vout = append(result.Vout, voutJSON(txOut))
+	}
+
+	return result
+}
+
+func touches(entry *txEntry, address string) bool {
+	for _, txOut := range entry.tx.TxOut {
+		if addressOf(txOut.PkScript) == address {
+			return true
+		}
+	}
+	for _, prevOut := range entry.prevOuts {
+		if addressOf(prevOut.PkScript) == address {
+			return true
+		}
+	}
+
+	return false
+}
+
+func voutJSON(txOut *wire.TxOut) entities.Vout {
+	scriptType := map[txscript.ScriptClass]string{
+		txscript.PubKeyHashTy:          "p2pkh",
+		txscript.ScriptHashTy:          "p2sh",
+		txscript.WitnessV0PubKeyHashTy: "v0_p2wpkh",
+		txscript.WitnessV0ScriptHashTy: "v0_p2wsh",
+	}[txscript.GetScriptClass(txOut.PkScript)]
+	if scriptType == "" {
+		scriptType = "unknown"
+	}
+
+	return entities.Vout{
+		ScriptPubKey:        hex.EncodeToString(txOut.PkScript),
+		ScriptPubKeyType:    scriptType,
+		ScriptPubKeyAddress: addressOf(txOut.PkScript),
+		Value:               txOut.Value,
+	}
+}
+
+func addressOf(pkScript []byte) string {
+	_, addresses, _, err := txscript.ExtractPkScriptAddrs(pkScript, &chaincfg.TestNet3Params)
+	if err != nil || len(addresses) != 1 {
+		return ""
+	}
+
+	return addresses[0].EncodeAddress()
+}
@@ -9,8 +9,24 @@ import (
 )
 
 type Config struct {
-	SecretPassphrase string `mapstructure:"secretPassphrase" validate:"min=10,max=100"`
-	UniqueSeed       bool   `mapstructure:"uniqueSeed"`
+	SecretPassphrase string    `mapstructure:"secretPassphrase" validate:"min=10,max=100"`
+	UniqueSeed       bool      `mapstructure:"uniqueSeed"`
+	LogLevel         string    `mapstructure:"logLevel" validate:"omitempty,oneof=debug info warn error"`
+	LogFormat        string    `mapstructure:"logFormat" validate:"omitempty,oneof=text json"`
+	APIToken         string    `mapstructure:"apiToken" validate:"omitempty,min=16"`
+	RPCUser          string    `mapstructure:"rpcUser"`
+	RPCPassword      string    `mapstructure:"rpcPassword" validate:"omitempty,min=16"`
+	Webhooks         []Webhook `mapstructure:"webhooks" validate:"dive"`
+	// WebhookConfirmations is number of confirmations for transaction_confirmed notification,
+	// the watcher doesn't track deeper transactions.
+	WebhookConfirmations int `mapstructure:"webhookConfirmations" validate:"omitempty,min=1,max=6"`
+	// EsploraURLs are chain backend endpoints in failover order, blockstream and mempool.space by default.
+	EsploraURLs []string `mapstructure:"esploraURLs" validate:"dive,url"`
+}
+
+type Webhook struct {
+	URL    string `mapstructure:"url" validate:"url"`
+	Secret string `mapstructure:"secret" validate:"min=16"` // HMAC-SHA256 key
 }
 
 func NewConfig(ctx context.Context) (cfg *Config, err error) {
@@ -1,18 +1,122 @@
 package constants
 
+import "time"
+
 const (
-	WalletAddressPath = "/app/wallet_address"
-	DefaultMnemonic   = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	WalletMnemonicPath     = "/app/wallet_mnemonic"
+	WalletAddressPath      = "/app/wallet_address"
+	WalletTransactionsPath = "/app/wallet_transactions"
+	WebhookQueuePath       = "/app/webhook_queue"
+	AuditLogPath           = "/app/audit_log"
+	TxCachePath            = "/app/tx_cache"
+	DefaultMnemonic        = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
 )
 
 const (
 	WalletShell = "wallsh"
 )
 
+const (
+	BackupVersion           = 1 // version of backup file format, older versions are restored too
+	BackupNetwork           = "testnet"
+	BackupPasswordEnv       = "WALLET_BACKUP_PASSWORD" // backup password for non-interactive use instead of prompt
+	BackupMinPasswordLength = 8
+	BackupScryptN           = 1 << 15 // scrypt cost parameters of backup key derivation
+	BackupScryptR           = 8
+	BackupScryptP           = 1
+)
+
+const (
+	RecoveryGapLimit      = 20 // consecutive unused addresses before the chain scan stops
+	RecoveryMaxAccounts   = 10 // accounts scanned per derivation scheme
+	RecoveryMnemonicEnv   = "WALLET_RECOVER_MNEMONIC"
+	RecoveryPassphraseEnv = "WALLET_RECOVER_PASSPHRASE"
+)
+
+const (
+	DefaultSharesThreshold = 2
+	DefaultSharesCount     = 3
+	SharesEnv              = "WALLET_SHARES" // SLIP-39 shares for non-interactive combine, one per line
+)
+
+const (
+	EsploraTestnetURL        = "https://blockstream.info/testnet/api/"
+	EsploraMempoolTestnetURL = "https://mempool.space/testnet/api/"
+)
+
+const (
+	BackendRequestTimeout   = 15 * time.Second
+	BackendMaxAttempts      = 3                      // attempts of idempotent request per endpoint
+	BackendRetryBase        = 500 * time.Millisecond // doubled after every failed attempt
+	BackendRetryMax         = 5 * time.Second
+	BackendBreakerThreshold = 5 // consecutive failures before the endpoint is skipped
+	BackendBreakerCooldown  = 30 * time.Second
+	BackendConcurrency      = 4 // concurrent requests of one operation, e.g. previous transactions
+)
+
+const (
+	MempoolCacheTTL         = 15 * time.Second // mempool transactions, UTXOs and address transactions
+	TipCheckInterval        = 10 * time.Second // short-lived cache is dropped when a new block is found
+	TxCacheMinConfirmations = 6                // deeper transactions are immutable and stored in TxCachePath
+)
+
+const (
+	BroadcastTimeout = 30 * time.Second // broadcast isn't canceled by the caller, see transaction.BroadcastTransaction
+	ShutdownTimeout  = 35 * time.Second // in-flight broadcasts are finished before exit
+	// UTXOs of prepared transaction aren't selected again until it is broadcasted, released or the reservation expires
+	SendReservationTTL = 10 * time.Minute
+)
+
+const (
+	DefaultAPIListen  = ":8080"
+	DefaultGRPCListen = ":9090"
+	DefaultRPCListen  = ":18332"
+)
+
+const (
+	DefaultWatcherInterval  = 30 * time.Second
+	WatcherMaxConfirmations = 6 // confirmation events aren't sent for deeper transactions
+)
+
+const (
+	DefaultWaitConfirmations = 1
+	DefaultWaitInterval      = 10 * time.Second
+	WaitNotFoundPolls        = 6 // consecutive polls which don't find never seen transaction before it is dropped
+)
+
+const (
+	DefaultWebhookConfirmations = 1
+	WebhookTimeout              = 10 * time.Second
+	WebhookRetryBase            = 5 * time.Second // doubled after every failed attempt
+	WebhookRetryMax             = time.Hour
+	WebhookMaxAttempts          = 12 // delivery is dropped after that
+	WebhookSignatureHeader      = "X-Wallet-Signature"
+	WebhookEventHeader          = "X-Wallet-Event"
+	WebhookDeliveryHeader       = "X-Wallet-Delivery"
+)
+
+const (
+	DefaultLogLevel = "info"
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+)
+
 const (
 	EOFCommand = "exit"
 )
 
 const (
-	DefaultFeeRate = 2 // sat/vbyte
+	OutputFormatTable = "table"
+	OutputFormatJSON  = "json"
+	OutputFormatYAML  = "yaml"
+)
+
+const (
+	ExitCodeOK    = 0
+	ExitCodeError = 1
+)
+
+const (
+	DefaultFeeRate      = 2      // sat/vbyte
+	MaxStandardTxWeight = 400000 // weight units
 )
@@ -0,0 +1,78 @@
+package address
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// descriptor returns BIP380 output descriptor of the account addresses on chain (0 - receive, 1 - change),
+// e.g. wpkh([d34db33f/84h/1h/0h]tpub.../0/*)#checksum.
+func descriptor(masterKey *bip32.Key, chain int) (result string, err error) {
+	account, err := accountKey(masterKey)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	xpub := account.PublicKey()
+	xpub.Version = chaincfg.TestNet3Params.HDPublicKeyID[:] // tpub
+
+	fingerprint := hex.EncodeToString(btcutil.Hash160(masterKey.PublicKey().Key)[:4])
+	result = fmt.Sprintf("wpkh([%s/84h/1h/0h]%s/%d/*)", fingerprint, xpub.B58Serialize(), chain)
+
+	return result + "#" + descriptorChecksum(result), nil
+}
+
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// descriptorChecksum returns BIP380 checksum of descriptor, it consists of ASCII characters only.
+func descriptorChecksum(desc string) string {
+	polymod := func(chk uint64, value uint64) uint64 {
+		generator := [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+		top := chk >> 35
+		chk = (chk&0x7ffffffff)<<5 ^ value
+		for i, g := range generator {
+			if (top>>i)&1 == 1 {
+				chk ^= g
+			}
+		}
+		return chk
+	}
+
+	chk := uint64(1)
+	var groups []uint64
+	for _, c := range desc {
+		pos := uint64(strings.IndexRune(descriptorInputCharset, c))
+		chk = polymod(chk, pos&31)
+		groups = append(groups, pos>>5)
+		if len(groups) == 3 {
+			chk = polymod(chk, groups[0]*9+groups[1]*3+groups[2])
+			groups = groups[:0]
+		}
+	}
+	switch len(groups) {
+	case 1:
+		chk = polymod(chk, groups[0])
+	case 2:
+		chk = polymod(chk, groups[0]*3+groups[1])
+	}
+	for range 8 {
+		chk = polymod(chk, 0)
+	}
+	chk ^= 1
+
+	result := make([]byte, 8)
+	for i := range result {
+		result[i] = descriptorChecksumCharset[(chk>>(5*(7-i)))&31]
+	}
+
+	return string(result)
+}
@@ -2,57 +2,123 @@ package address
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
 	"github.com/tatun2000/golang-lib/pkg/wrap"
 	"github.com/tyler-smith/go-bip32"
 	"github.com/tyler-smith/go-bip39"
 )
 
-type Service struct {
-	masterPrivateKey *bip32.Key
-}
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	// ErrPassphraseMismatch is returned if the mnemonic with secretPassphrase from config doesn't give the expected address.
+	ErrPassphraseMismatch = errors.New("secretPassphrase doesn't match the wallet")
+	// ErrMnemonicMissing is returned if uniqueSeed is set, but the existing wallet has no saved mnemonic:
+	// it was created from constants.DefaultMnemonic before uniqueSeed was enabled.
+	ErrMnemonicMissing = errors.New("the wallet has no saved mnemonic")
+)
 
-func generateSeed(secretPhrase string, uniqueSeed bool) (seed []byte, err error) {
-	var mnemonic string
-	if uniqueSeed {
-		entropy, err := bip39.NewEntropy(256)
-		if err != nil {
-			return seed, wrap.Wrap(err)
-		}
+type (
+	IAuditService interface {
+		Record(event entities.AuditEventType, data map[string]any) (err error)
+	}
 
-		mnemonic, err = bip39.NewMnemonic(entropy)
-		if err != nil {
-			return seed, wrap.Wrap(err)
-		}
-	} else {
-		mnemonic = constants.DefaultMnemonic
+	Service struct {
+		secretPhrase string
+		mnemonicPath string
+		addressPath  string
+		auditService IAuditService
+
+		mu               sync.RWMutex // the wallet can be replaced by Restore
+		mnemonic         string
+		masterPrivateKey *bip32.Key
+	}
+)
+
+// loadMnemonic returns mnemonic saved in mnemonicPath. If there is no saved mnemonic, unique one is generated
+// and saved when uniqueSeed is set, otherwise constants.DefaultMnemonic is used.
+// Unique mnemonic isn't generated for the existing wallet in addressPath, its funds would be lost.
+func loadMnemonic(mnemonicPath, addressPath string, uniqueSeed bool) (mnemonic string, err error) {
+	data, err := os.ReadFile(mnemonicPath)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", wrap.Wrap(err)
+	}
+
+	if !uniqueSeed {
+		return constants.DefaultMnemonic, nil
 	}
 
-	seed = bip39.NewSeed(mnemonic, secretPhrase)
-	return seed, nil
+	if _, err = os.Stat(addressPath); err == nil {
+		return "", wrap.Wrap(fmt.Errorf("%w: %s exists, but %s doesn't. Disable uniqueSeed, or move the funds and remove %s "+
+			"to create a new wallet, or save the wallet mnemonic into %s", ErrMnemonicMissing, addressPath, mnemonicPath, addressPath, mnemonicPath))
+	} else if !os.IsNotExist(err) {
+		return "", wrap.Wrap(err)
+	}
+
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	mnemonic, err = bip39.NewMnemonic(entropy)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	if err = writeFile(mnemonicPath, mnemonic); err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	return mnemonic, nil
 }
 
-func NewService(secretPhrase string, uniqueSeed bool) *Service {
-	seed, err := generateSeed(secretPhrase, uniqueSeed)
+func newMasterKey(mnemonic, secretPhrase string) (result *bip32.Key, err error) {
+	result, err = bip32.NewMasterKey(bip39.NewSeed(mnemonic, secretPhrase))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// NewService derives the wallet address and saves it into addressPath on the first start.
+// Unique mnemonic is saved into mnemonicPath, it is the wallet backup together with secretPhrase.
+func NewService(secretPhrase string, uniqueSeed bool, mnemonicPath, addressPath string, auditService IAuditService) *Service {
+	mnemonic, err := loadMnemonic(mnemonicPath, addressPath, uniqueSeed)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	masterKey, err := bip32.NewMasterKey(seed)
+	masterKey, err := newMasterKey(mnemonic, secretPhrase)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	s := &Service{
+		secretPhrase:     secretPhrase,
+		mnemonicPath:     mnemonicPath,
+		addressPath:      addressPath,
+		auditService:     auditService,
+		mnemonic:         mnemonic,
 		masterPrivateKey: masterKey,
 	}
 
-	if err := s.SaveAddress(); err != nil {
+	// the address is derived once on the first start
+	if err := s.SaveAddress(context.Background()); err != nil {
 		log.Fatal(err)
 	}
 
@@ -65,62 +131,53 @@ func NewService(secretPhrase string, uniqueSeed bool) *Service {
 // 0 - account
 // 0 - external addresses
 // 0 - first address in leaf
-func (s *Service) GetChildBIP32Key() (result *bip32.Key, err error) {
-	key84, err := s.masterPrivateKey.NewChildKey(bip32.FirstHardenedChild + 84)
-	if err != nil {
+func (s *Service) GetChildBIP32Key(ctx context.Context) (result *bip32.Key, err error) {
+	if err = ctx.Err(); err != nil {
 		return result, wrap.Wrap(err)
 	}
 
-	key1, err := key84.NewChildKey(bip32.FirstHardenedChild + 1) // testnet
-	if err != nil {
-		return result, wrap.Wrap(err)
-	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	key0, err := key1.NewChildKey(bip32.FirstHardenedChild + 0)
+	result, err = addressKey(s.masterPrivateKey)
 	if err != nil {
 		return result, wrap.Wrap(err)
 	}
 
-	ext, err := key0.NewChildKey(0)
-	if err != nil {
-		return result, wrap.Wrap(err)
-	}
+	return result, nil
+}
 
-	addrKey, err := ext.NewChildKey(0)
+func (s *Service) GenerateBitcoinBIP84AddressForTestNet(ctx context.Context) (result string, err error) {
+	key, err := s.GetChildBIP32Key(ctx)
 	if err != nil {
 		return result, wrap.Wrap(err)
 	}
 
-	return addrKey, nil
-}
-
-func (s *Service) GenerateBitcoinBIP84AddressForTestNet() (result string, err error) {
-	key, err := s.GetChildBIP32Key()
+	result, err = p2wpkhAddress(key)
 	if err != nil {
 		return result, wrap.Wrap(err)
 	}
 
-	pubKeyHash := btcutil.Hash160(key.PublicKey().Key)
-
-	// Generate P2WPKH (bech32) address
-	address, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, &chaincfg.TestNet3Params)
-	if err != nil {
+	if err = s.auditService.Record(entities.AuditEventAddressDerived, map[string]any{
+		"path":    "m/84'/1'/0'/0/0",
+		"address": result,
+	}); err != nil {
 		return result, wrap.Wrap(err)
 	}
 
-	return address.EncodeAddress(), nil
+	return result, nil
 }
 
-func (s *Service) SaveAddress() (err error) {
-	if _, err := os.Stat(constants.WalletAddressPath); err != nil {
+func (s *Service) SaveAddress(ctx context.Context) (err error) {
+	if _, err := os.Stat(s.addressPath); err != nil {
 		if os.IsNotExist(err) {
-			file, err := os.Create(constants.WalletAddressPath)
+			file, err := os.Create(s.addressPath)
 			if err != nil {
 				return wrap.Wrap(err)
 			}
 			defer file.Close()
 
-			address, err := s.GenerateBitcoinBIP84AddressForTestNet()
+			address, err := s.GenerateBitcoinBIP84AddressForTestNet(ctx)
 			if err != nil {
 				return wrap.Wrap(err)
 			}
@@ -136,8 +193,12 @@ func (s *Service) SaveAddress() (err error) {
 	return nil
 }
 
-func (s *Service) RetrieveAddress() (result string, err error) {
-	file, err := os.Open(constants.WalletAddressPath)
+func (s *Service) RetrieveAddress(ctx context.Context) (result string, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	file, err := os.Open(s.addressPath)
 	if err != nil {
 		return result, wrap.Wrap(err)
 	}
@@ -152,3 +213,219 @@ func (s *Service) RetrieveAddress() (result string, err error) {
 
 	return result, nil
 }
+
+// Mnemonic returns BIP39 mnemonic of the wallet, the seed is derived from it with secretPassphrase.
+func (s *Service) Mnemonic(ctx context.Context) (result string, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.mnemonic, nil
+}
+
+// Account returns descriptors and used address indices of the wallet account m/84'/1'/0'.
+func (s *Service) Account(ctx context.Context) (result entities.BackupAccount, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if result.ReceiveDescriptor, err = descriptor(s.masterPrivateKey, 0); err != nil {
+		return result, wrap.Wrap(err)
+	}
+	if result.ChangeDescriptor, err = descriptor(s.masterPrivateKey, 1); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	key, err := addressKey(s.masterPrivateKey)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	if result.Address, err = p2wpkhAddress(key); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	// the wallet uses the first receive address for change too
+	result.ReceiveIndex = 0
+	result.ChangeIndex = 0
+
+	return result, nil
+}
+
+// Restore replaces the wallet by mnemonic. The mnemonic with secretPassphrase from config must give address,
+// so funds aren't lost because of a wrong passphrase.
+func (s *Service) Restore(ctx context.Context, mnemonic, address string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return wrap.Wrap(ErrInvalidMnemonic)
+	}
+
+	masterKey, err := newMasterKey(mnemonic, s.secretPhrase)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+
+	key, err := addressKey(masterKey)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+	derived, err := p2wpkhAddress(key)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+	if derived != address {
+		return wrap.Wrap(ErrPassphraseMismatch)
+	}
+
+	if err = s.replace(mnemonic, masterKey, address); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+// Import replaces the wallet by mnemonic with secretPassphrase from config, the expected address is unknown.
+func (s *Service) Import(ctx context.Context, mnemonic string) (result string, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return result, wrap.Wrap(ErrInvalidMnemonic)
+	}
+
+	masterKey, err := newMasterKey(mnemonic, s.secretPhrase)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	key, err := addressKey(masterKey)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	result, err = p2wpkhAddress(key)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = s.replace(mnemonic, masterKey, result); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// replace saves mnemonic and address of the new wallet and switches to its key.
+func (s *Service) replace(mnemonic string, masterKey *bip32.Key, address string) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = writeFile(s.mnemonicPath, mnemonic); err != nil {
+		return wrap.Wrap(err)
+	}
+	if err = writeFile(s.addressPath, address); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	s.mnemonic = mnemonic
+	s.masterPrivateKey = masterKey
+
+	if err = s.auditService.Record(entities.AuditEventAddressDerived, map[string]any{
+		"path":    "m/84'/1'/0'/0/0",
+		"address": address,
+	}); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+// addressKey returns key of the wallet address m/84'/1'/0'/0/0.
+func addressKey(masterKey *bip32.Key) (result *bip32.Key, err error) {
+	account, err := accountKey(masterKey)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	ext, err := account.NewChildKey(0)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = ext.NewChildKey(0)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// accountKey returns key of the account m/84'/1'/0'.
+func accountKey(masterKey *bip32.Key) (result *bip32.Key, err error) {
+	key84, err := masterKey.NewChildKey(bip32.FirstHardenedChild + 84)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	key1, err := key84.NewChildKey(bip32.FirstHardenedChild + 1) // testnet
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = key1.NewChildKey(bip32.FirstHardenedChild + 0)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// p2wpkhAddress returns bech32 testnet address of key.
+func p2wpkhAddress(key *bip32.Key) (result string, err error) {
+	pubKeyHash := btcutil.Hash160(key.PublicKey().Key)
+
+	address, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, &chaincfg.TestNet3Params)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return address.EncodeAddress(), nil
+}
+
+// writeFile replaces file content atomically, the file is readable only by owner.
+func writeFile(path, content string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // the file is renamed on success
+
+	if _, err = tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return wrap.Wrap(err)
+	}
+	// the file must be on disk before it replaces the previous one
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return wrap.Wrap(err)
+	}
+	if err = tmp.Close(); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// genesisHash is previous hash of the first record.
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+// maxRecordSize limits reading of the last record.
+const maxRecordSize = 64 * 1024
+
+// Service appends records to the hash-chained audit log. The file is locked while appending,
+// so records from several wallet processes stay in one chain.
+type Service struct {
+	path string
+}
+
+func NewService(path string) *Service {
+	return &Service{
+		path: path,
+	}
+}
+
+// Record appends event with data to the audit log.
+func (s *Service) Record(event entities.AuditEventType, data map[string]any) (err error) {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+	defer file.Close()
+
+	if err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return wrap.Wrap(err)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN) //nolint:errcheck // file is closed anyway
+
+	record := entities.AuditRecord{
+		Time:     time.Now().Unix(),
+		Event:    event,
+		PrevHash: genesisHash,
+	}
+
+	last, err := lastRecord(file)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+	if last != nil {
+		record.Seq = last.Seq + 1
+		record.PrevHash = last.Hash
+	}
+
+	if record.Data, err = json.Marshal(data); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	if record.Hash, err = hashRecord(record); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+// Records returns all records of the audit log.
+func (s *Service) Records() (result []entities.AuditRecord, err error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, wrap.Wrap(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, maxRecordSize), maxRecordSize)
+	for scanner.Scan() {
+		var record entities.AuditRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return result, wrap.Wrap(fmt.Errorf("record %d: %w", len(result), err))
+		}
+		result = append(result, record)
+	}
+	if err = scanner.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// Verify checks sequence numbers and hash chain of the audit log, it returns number of valid records.
+func (s *Service) Verify() (count int, err error) {
+	records, err := s.Records()
+	if err != nil {
+		return 0, wrap.Wrap(err)
+	}
+
+	prevHash := genesisHash
+	for i, record := range records {
+		if record.Seq != uint64(i) {
+			return i, wrap.Wrap(fmt.Errorf("record %d: sequence number is %d", i, record.Seq))
+		}
+		if record.PrevHash != prevHash {
+			return i, wrap.Wrap(fmt.Errorf("record %d: previous hash doesn't match, records are removed or changed", i))
+		}
+
+		hash, err := hashRecord(record)
+		if err != nil {
+			return i, wrap.Wrap(err)
+		}
+		if record.Hash != hash {
+			return i, wrap.Wrap(fmt.Errorf("record %d: hash doesn't match, record is changed", i))
+		}
+
+		prevHash = record.Hash
+	}
+
+	return len(records), nil
+}
+
+func hashRecord(record entities.AuditRecord) (result string, err error) {
+	record.Hash = ""
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastRecord reads the last line of the file, it returns nil for empty file.
+func lastRecord(file *os.File) (result *entities.AuditRecord, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, wrap.Wrap(err)
+	}
+	if info.Size() == 0 {
+		return nil, nil
+	}
+
+	offset := max(info.Size()-maxRecordSize, 0)
+	tail := make([]byte, info.Size()-offset)
+	if _, err = file.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return nil, wrap.Wrap(err)
+	}
+
+	tail = bytes.TrimRight(tail, "\n")
+	if idx := bytes.LastIndexByte(tail, '\n'); idx >= 0 {
+		tail = tail[idx+1:]
+	}
+
+	result = &entities.AuditRecord{}
+	if err = json.Unmarshal(tail, result); err != nil {
+		return nil, wrap.Wrap(fmt.Errorf("last audit record is corrupted: %w", err))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,334 @@
+package backup
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/address"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	kdfName    = "scrypt"
+	cipherName = "aes-256-gcm"
+	keySize    = 32
+	saltSize   = 16
+	maxScryptN = 1 << 20 // parameters are read from the file, they mustn't exhaust memory
+)
+
+var (
+	ErrShortPassword      = fmt.Errorf("backup password must have at least %d characters", constants.BackupMinPasswordLength)
+	ErrWrongPassword      = errors.New("wrong backup password or damaged backup file")
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+)
+
+type (
+	IAddressService interface {
+		Mnemonic(ctx context.Context) (result string, err error)
+		Account(ctx context.Context) (result entities.BackupAccount, err error)
+		Restore(ctx context.Context, mnemonic, address string) (err error)
+	}
+
+	ITransactionService interface {
+		ClearBroadcastedTransactions() (err error)
+	}
+
+	IWalletService interface {
+		GetWalletAddress(ctx context.Context) (result string, err error)
+		GetWalletBalance(ctx context.Context) (confirmed, unconfirmed int64, err error)
+		GetHistory(ctx context.Context) (result []entities.HistoryEntry, err error)
+	}
+
+	IAuditService interface {
+		Record(event entities.AuditEventType, data map[string]any) (err error)
+	}
+
+	// Service writes encrypted wallet backups and restores the wallet from them.
+	Service struct {
+		addressService     IAddressService
+		transactionService ITransactionService
+		walletService      IWalletService
+		auditService       IAuditService
+	}
+
+	// file is a backup file: entities.WalletBackup JSON encrypted by AES-256-GCM
+	// with key derived from the password by scrypt.
+	file struct {
+		Version    int       `json:"version"`
+		KDF        kdfParams `json:"kdf"`
+		Cipher     string    `json:"cipher"`
+		Nonce      []byte    `json:"nonce"`
+		Ciphertext []byte    `json:"ciphertext"`
+	}
+
+	kdfParams struct {
+		Name string `json:"name"`
+		Salt []byte `json:"salt"`
+		N    int    `json:"n"`
+		R    int    `json:"r"`
+		P    int    `json:"p"`
+	}
+)
+
+func NewService(addressService IAddressService, transactionService ITransactionService, walletService IWalletService, auditService IAuditService) *Service {
+	return &Service{
+		addressService:     addressService,
+		transactionService: transactionService,
+		walletService:      walletService,
+		auditService:       auditService,
+	}
+}
+
+// Backup writes encrypted backup of the wallet into a new file. The BIP39 passphrase isn't saved,
+// passphraseHint can remind it.
+func (s *Service) Backup(ctx context.Context, path, password, passphraseHint string) (result entities.BackupResult, err error) {
+	if len(password) < constants.BackupMinPasswordLength {
+		return result, wrap.Wrap(ErrShortPassword)
+	}
+
+	mnemonic, err := s.addressService.Mnemonic(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	account, err := s.addressService.Account(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	plaintext, err := json.Marshal(entities.WalletBackup{
+		Version:        constants.BackupVersion,
+		CreatedAt:      time.Now().Unix(),
+		Network:        constants.BackupNetwork,
+		Mnemonic:       mnemonic,
+		PassphraseHint: passphraseHint,
+		Accounts:       []entities.BackupAccount{account},
+		Labels:         map[string]string{},
+		FrozenUTXOs:    []string{},
+	})
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	backupFile, err := encrypt(plaintext, password)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	data, err := json.MarshalIndent(backupFile, "", "  ")
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	// existing backup isn't overwritten
+	output, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if _, err = output.Write(append(data, '\n')); err == nil {
+		err = output.Sync()
+	}
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// partial backup mustn't be kept, it can't be restored
+		os.Remove(path) //nolint:errcheck // the write error is more important
+		return result, wrap.Wrap(err)
+	}
+
+	result = entities.BackupResult{
+		Path:    path,
+		Version: constants.BackupVersion,
+		Address: account.Address,
+	}
+
+	if err = s.auditService.Record(entities.AuditEventBackupCreated, map[string]any{
+		"path":    path,
+		"version": constants.BackupVersion,
+		"address": account.Address,
+	}); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// Restore replaces the wallet by the backup and rescans it. Records of own transactions are removed
+// if the backup is another wallet, the history is rebuilt from the chain.
+func (s *Service) Restore(ctx context.Context, path, password string) (result entities.RestoreResult, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	var backupFile file
+	if err = json.Unmarshal(data, &backupFile); err != nil {
+		return result, wrap.Wrap(fmt.Errorf("backup file: %w", err))
+	}
+
+	plaintext, err := decrypt(backupFile, password)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	var backup entities.WalletBackup
+	if err = json.Unmarshal(plaintext, &backup); err != nil {
+		return result, wrap.Wrap(err)
+	}
+	if backup.Network != constants.BackupNetwork {
+		return result, wrap.Wrap(fmt.Errorf("backup is for %s, the wallet supports only %s", backup.Network, constants.BackupNetwork))
+	}
+	if len(backup.Accounts) == 0 {
+		return result, wrap.Wrap(errors.New("backup has no accounts"))
+	}
+	account := backup.Accounts[0]
+
+	previousAddress, err := s.walletService.GetWalletAddress(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = s.addressService.Restore(ctx, backup.Mnemonic, account.Address); err != nil {
+		if errors.Is(err, address.ErrPassphraseMismatch) && backup.PassphraseHint != "" {
+			return result, wrap.Wrap(fmt.Errorf("%w: set secretPassphrase in config, its hint is %q", err, backup.PassphraseHint))
+		}
+		return result, wrap.Wrap(err)
+	}
+
+	if previousAddress != account.Address {
+		if err = s.transactionService.ClearBroadcastedTransactions(); err != nil {
+			return result, wrap.Wrap(err)
+		}
+	}
+
+	if err = s.auditService.Record(entities.AuditEventWalletRestored, map[string]any{
+		"path":             path,
+		"version":          backup.Version,
+		"address":          account.Address,
+		"previous_address": previousAddress,
+	}); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = s.rescan(ctx, account.Address)
+	if err != nil {
+		return result, wrap.Wrap(fmt.Errorf("wallet is restored, but rescan failed: %w", err))
+	}
+
+	return result, nil
+}
+
+// rescan requests balance and transactions of the restored wallet from the backend.
+func (s *Service) rescan(ctx context.Context, walletAddress string) (result entities.RestoreResult, err error) {
+	confirmed, unconfirmed, err := s.walletService.GetWalletBalance(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	history, err := s.walletService.GetHistory(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return entities.RestoreResult{
+		Address:      walletAddress,
+		Confirmed:    confirmed,
+		Unconfirmed:  unconfirmed,
+		Transactions: len(history),
+	}, nil
+}
+
+func encrypt(plaintext []byte, password string) (result file, err error) {
+	result = file{
+		Version: constants.BackupVersion,
+		KDF: kdfParams{
+			Name: kdfName,
+			Salt: make([]byte, saltSize),
+			N:    constants.BackupScryptN,
+			R:    constants.BackupScryptR,
+			P:    constants.BackupScryptP,
+		},
+		Cipher: cipherName,
+	}
+	if _, err = rand.Read(result.KDF.Salt); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	aead, err := newAEAD(result.KDF, password)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(result.Nonce); err != nil {
+		return result, wrap.Wrap(err)
+	}
+	result.Ciphertext = aead.Seal(nil, result.Nonce, plaintext, additionalData(result.Version))
+
+	return result, nil
+}
+
+func decrypt(backupFile file, password string) (result []byte, err error) {
+	if backupFile.Version < 1 || backupFile.Version > constants.BackupVersion {
+		return result, wrap.Wrap(fmt.Errorf("%w: %d", ErrUnsupportedVersion, backupFile.Version))
+	}
+	if backupFile.KDF.Name != kdfName || backupFile.Cipher != cipherName {
+		return result, wrap.Wrap(fmt.Errorf("%w: %s with %s", ErrUnsupportedVersion, backupFile.Cipher, backupFile.KDF.Name))
+	}
+
+	if params := backupFile.KDF; params.N <= 1 || params.N > maxScryptN || params.R <= 0 || params.P <= 0 || params.R*params.P > maxScryptN/params.N {
+		return result, wrap.Wrap(fmt.Errorf("%w: invalid scrypt parameters", ErrUnsupportedVersion))
+	}
+
+	aead, err := newAEAD(backupFile.KDF, password)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	if len(backupFile.Nonce) != aead.NonceSize() {
+		return result, wrap.Wrap(ErrWrongPassword)
+	}
+
+	result, err = aead.Open(nil, backupFile.Nonce, backupFile.Ciphertext, additionalData(backupFile.Version))
+	if err != nil {
+		return result, wrap.Wrap(ErrWrongPassword)
+	}
+
+	return result, nil
+}
+
+func newAEAD(params kdfParams, password string) (result cipher.AEAD, err error) {
+	key, err := scrypt.Key([]byte(password), params.Salt, params.N, params.R, params.P, keySize)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = cipher.NewGCM(block)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// additionalData authenticates the format version, so it can't be changed without the password.
+func additionalData(version int) []byte {
+	return []byte("bitcoin-testnet-wallet backup v" + strconv.Itoa(version))
+}
@@ -0,0 +1,240 @@
+package backup_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/backend"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora/esploratest"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/address"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/audit"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/backup"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/wallet"
+)
+
+const password = "correct horse battery"
+
+// newBackupService creates wallet with unique mnemonic and secretPhrase, its files are in a new directory.
+func newBackupService(t *testing.T, server *esploratest.Server, secretPhrase string) (*backup.Service, *wallet.Service) {
+	t.Helper()
+
+	dir := t.TempDir()
+	client := backend.NewClient([]string{server.BaseURL()}, 5*time.Second, filepath.Join(dir, "tx_cache"))
+	auditService := audit.NewService(filepath.Join(dir, "audit_log"))
+	addressService := address.NewService(secretPhrase, true, filepath.Join(dir, "wallet_mnemonic"), filepath.Join(dir, "wallet_address"), auditService)
+	transactionService := transaction.NewService(addressService, client, auditService, filepath.Join(dir, "wallet_transactions"))
+	walletService := wallet.NewService(addressService, transactionService, client, auditService)
+
+	return backup.NewService(addressService, transactionService, walletService, auditService), walletService
+}
+
+func newServer(t *testing.T) *esploratest.Server {
+	t.Helper()
+
+	server := esploratest.NewServer()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// writeBackup writes backup of a new wallet with secretPhrase and returns the file path and the wallet address.
+func writeBackup(t *testing.T, server *esploratest.Server, secretPhrase, hint string) (path, walletAddress string) {
+	t.Helper()
+
+	service, walletService := newBackupService(t, server, secretPhrase)
+	path = filepath.Join(t.TempDir(), "wallet.backup")
+
+	result, err := service.Backup(context.Background(), path, password, hint)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+
+	walletAddress, err = walletService.GetWalletAddress(context.Background())
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+	if result.Address != walletAddress {
+		t.Fatalf("backup address is %s, want %s", result.Address, walletAddress)
+	}
+
+	return path, walletAddress
+}
+
+// changeBackup rewrites JSON field of the backup file.
+func changeBackup(t *testing.T, path string, change func(fields map[string]any)) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	var fields map[string]any
+	if err = json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("parse backup: %v", err)
+	}
+
+	change(fields)
+
+	if data, err = json.Marshal(fields); err != nil {
+		t.Fatalf("marshal backup: %v", err)
+	}
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	server := newServer(t)
+	ctx := context.Background()
+	path, walletAddress := writeBackup(t, server, "secret", "")
+
+	// the backup isn't overwritten
+	service, walletService := newBackupService(t, server, "secret")
+	if _, err := service.Backup(ctx, path, password, ""); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("backup error is %v, want %v", err, os.ErrExist)
+	}
+
+	result, err := service.Restore(ctx, path, password)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if result.Address != walletAddress {
+		t.Fatalf("restored address is %s, want %s", result.Address, walletAddress)
+	}
+
+	restoredAddress, err := walletService.GetWalletAddress(ctx)
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+	if restoredAddress != walletAddress {
+		t.Fatalf("wallet address is %s, want %s", restoredAddress, walletAddress)
+	}
+}
+
+func TestBackupShortPassword(t *testing.T) {
+	service, _ := newBackupService(t, newServer(t), "secret")
+
+	_, err := service.Backup(context.Background(), filepath.Join(t.TempDir(), "wallet.backup"), "short", "")
+	if !errors.Is(err, backup.ErrShortPassword) {
+		t.Fatalf("backup error is %v, want %v", err, backup.ErrShortPassword)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		password string
+		change   func(fields map[string]any)
+		want     error
+	}{
+		{
+			name:     "wrong password",
+			password: "wrong password",
+			want:     backup.ErrWrongPassword,
+		},
+		{
+			name:   "tampered ciphertext",
+			change: func(fields map[string]any) { fields["ciphertext"] = tamper(fields["ciphertext"].(string)) },
+			want:   backup.ErrWrongPassword,
+		},
+		{
+			name:   "tampered nonce",
+			change: func(fields map[string]any) { fields["nonce"] = tamper(fields["nonce"].(string)) },
+			want:   backup.ErrWrongPassword,
+		},
+		{
+			name:   "unknown version",
+			change: func(fields map[string]any) { fields["version"] = 2 },
+			want:   backup.ErrUnsupportedVersion,
+		},
+		{
+			name:   "zero version",
+			change: func(fields map[string]any) { fields["version"] = 0 },
+			want:   backup.ErrUnsupportedVersion,
+		},
+		{
+			name:   "unknown cipher",
+			change: func(fields map[string]any) { fields["cipher"] = "aes-128-cbc" },
+			want:   backup.ErrUnsupportedVersion,
+		},
+		{
+			name:   "too large scrypt N",
+			change: func(fields map[string]any) { fields["kdf"].(map[string]any)["n"] = 1 << 30 },
+			want:   backup.ErrUnsupportedVersion,
+		},
+		{
+			name:   "too large scrypt r*p",
+			change: func(fields map[string]any) { fields["kdf"].(map[string]any)["p"] = 1 << 20 },
+			want:   backup.ErrUnsupportedVersion,
+		},
+		{
+			name:   "zero scrypt r",
+			change: func(fields map[string]any) { fields["kdf"].(map[string]any)["r"] = 0 },
+			want:   backup.ErrUnsupportedVersion,
+		},
+	}
+
+	server := newServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := writeBackup(t, server, "secret", "")
+			if tt.change != nil {
+				changeBackup(t, path, tt.change)
+			}
+			restorePassword := password
+			if tt.password != "" {
+				restorePassword = tt.password
+			}
+
+			service, walletService := newBackupService(t, server, "secret")
+			walletAddress, err := walletService.GetWalletAddress(ctx)
+			if err != nil {
+				t.Fatalf("get address: %v", err)
+			}
+
+			if _, err = service.Restore(ctx, path, restorePassword); !errors.Is(err, tt.want) {
+				t.Fatalf("restore error is %v, want %v", err, tt.want)
+			}
+
+			// the wallet isn't changed by failed restore
+			if current, err := walletService.GetWalletAddress(ctx); err != nil || current != walletAddress {
+				t.Fatalf("wallet address is %s (error %v), want %s", current, err, walletAddress)
+			}
+		})
+	}
+}
+
+func TestRestorePassphraseMismatch(t *testing.T) {
+	server := newServer(t)
+	path, _ := writeBackup(t, server, "secret", "the guy who lost his car")
+
+	service, _ := newBackupService(t, server, "another secret")
+	_, err := service.Restore(context.Background(), path, password)
+	if !errors.Is(err, address.ErrPassphraseMismatch) {
+		t.Fatalf("restore error is %v, want %v", err, address.ErrPassphraseMismatch)
+	}
+	if !strings.Contains(err.Error(), "the guy who lost his car") {
+		t.Fatalf("restore error %q doesn't show the passphrase hint", err)
+	}
+}
+
+// tamper flips a bit of base64-encoded bytes.
+func tamper(b64 string) string {
+	data := []byte(b64)
+	if data[0] == 'A' {
+		data[0] = 'B'
+	} else {
+		data[0] = 'A'
+	}
+
+	return string(data)
+}
@@ -0,0 +1,432 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+type (
+	IWatcherService interface {
+		SubscribeFunc(baseline *entities.WalletSnapshot, handle func(events []entities.WalletEvent, current entities.WalletSnapshot) error) (unsubscribe func())
+	}
+
+	Webhook struct {
+		URL    string
+		Secret string
+	}
+
+	// Service converts watcher events to webhook notifications and delivers them with retries.
+	// Undelivered notifications are kept in the queue file together with the wallet state they are detected from,
+	// so they survive restarts and changes while the notifier is stopped are notified after start.
+	Service struct {
+		watcherService IWatcherService
+		webhooks       map[string]Webhook // by URL
+		confirmations  int
+		queuePath      string
+		client         *resty.Client
+
+		mu     sync.Mutex
+		state  state
+		wake   chan struct{}
+		cancel context.CancelFunc // nil if notifier isn't running
+		done   chan struct{}
+	}
+
+	state struct {
+		Queue []entities.WebhookDelivery `json:"queue"`
+		// Confirmed contains transactions which were notified with enough confirmations,
+		// transactions are removed when the watcher stops tracking them.
+		Confirmed []string `json:"confirmed"`
+		// Snapshot is the wallet state whose events are queued, the watcher compares it with the current state after restart.
+		Snapshot *entities.WalletSnapshot `json:"snapshot,omitempty"`
+	}
+)
+
+func NewService(watcherService IWatcherService, webhooks []Webhook, confirmations int, queuePath string) *Service {
+	s := &Service{
+		watcherService: watcherService,
+		webhooks:       make(map[string]Webhook, len(webhooks)),
+		confirmations:  confirmations,
+		queuePath:      queuePath,
+		client: resty.New().
+			SetTimeout(constants.WebhookTimeout),
+		wake: make(chan struct{}, 1),
+	}
+
+	for _, webhook := range webhooks {
+		s.webhooks[webhook.URL] = webhook
+	}
+
+	return s
+}
+
+// IsConfigured reports whether any webhook URL is set.
+func (s *Service) IsConfigured() bool {
+	return len(s.webhooks) > 0
+}
+
+// Start subscribes to watcher events and delivers notifications in background until Stop is called or ctx is done.
+func (s *Service) Start(ctx context.Context) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return wrap.Wrap(errors.New("notifier is already running"))
+	}
+
+	if err = s.load(); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	unsubscribe := s.watcherService.SubscribeFunc(s.state.Snapshot, s.notify)
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+		defer unsubscribe()
+		s.deliver(ctx)
+	}(s.done)
+
+	return nil
+}
+
+// Stop stops delivering and waits for the active delivery.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// notify enqueues notifications for the watcher events, saves them with the wallet state and wakes up delivery.
+// The watcher repeats the events if they aren't saved, so the state isn't changed then.
+func (s *Service) notify(events []entities.WalletEvent, current entities.WalletSnapshot) (err error) {
+	s.mu.Lock()
+	confirmed := slices.Clone(s.state.Confirmed)
+	s.mu.Unlock()
+
+	var deliveries []entities.WebhookDelivery
+	for _, event := range events {
+		var payloads []entities.WebhookPayload
+		payloads, confirmed, err = s.payloads(event, confirmed)
+		if err != nil {
+			return wrap.Wrap(err)
+		}
+
+		for _, payload := range payloads {
+			for url := range s.webhooks {
+				deliveries = append(deliveries, entities.WebhookDelivery{
+					URL:         url,
+					Payload:     payload,
+					NextAttempt: event.Time,
+				})
+			}
+		}
+	}
+
+	// replaced and dropped transactions don't get confirmations anymore
+	confirmed = slices.DeleteFunc(confirmed, func(txID string) bool {
+		return !slices.ContainsFunc(current.History, func(entry entities.HistoryEntry) bool {
+			return entry.TxID == txID && entry.State != entities.TxStateReplaced && entry.State != entities.TxStateDropped
+		})
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued, previous, previousConfirmed := len(s.state.Queue), s.state.Snapshot, s.state.Confirmed
+	s.state.Queue = append(s.state.Queue, deliveries...)
+	s.state.Snapshot = &current
+	s.state.Confirmed = confirmed
+
+	if err = s.save(); err != nil {
+		s.state.Queue, s.state.Snapshot, s.state.Confirmed = s.state.Queue[:queued], previous, previousConfirmed
+		return wrap.Wrap(err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// payloads maps watcher event to webhook payloads, most events don't produce any.
+// confirmed is the list of notified confirmed transactions, its updated version is returned.
+func (s *Service) payloads(event entities.WalletEvent, confirmed []string) (result []entities.WebhookPayload, _ []string, err error) {
+	payload := entities.WebhookPayload{
+		Time:          event.Time,
+		TxID:          event.TxID,
+		Amount:        event.Amount,
+		Confirmations: event.Confirmations,
+		ReplacedBy:    event.ReplacedBy,
+	}
+
+	var isNew bool
+	switch event.Type {
+	case entities.WalletEventNewTransaction:
+		if event.Amount > 0 {
+			payload.Event = entities.WebhookEventReceived
+			result = append(result, payload)
+		}
+		// transaction can be detected already confirmed
+		if event.Confirmations >= s.confirmations {
+			if confirmed, isNew = markConfirmed(confirmed, event); isNew {
+				payload.Event = entities.WebhookEventConfirmed
+				result = append(result, payload)
+			}
+		}
+	case entities.WalletEventConfirmation:
+		if event.Confirmations >= s.confirmations {
+			if confirmed, isNew = markConfirmed(confirmed, event); isNew {
+				payload.Event = entities.WebhookEventConfirmed
+				result = append(result, payload)
+			}
+		}
+	case entities.WalletEventReplaced:
+		payload.Event = entities.WebhookEventReplaced
+		result = append(result, payload)
+	case entities.WalletEventDropped:
+		payload.Event = entities.WebhookEventDropped
+		result = append(result, payload)
+	}
+
+	for i := range result {
+		if result[i].ID, err = newDeliveryID(); err != nil {
+			return nil, confirmed, wrap.Wrap(err)
+		}
+	}
+
+	return result, confirmed, nil
+}
+
+// markConfirmed adds the notified transaction to confirmed and reports whether it wasn't notified before.
+func markConfirmed(confirmed []string, event entities.WalletEvent) (_ []string, isNew bool) {
+	if idx := slices.Index(confirmed, event.TxID); idx >= 0 {
+		// the watcher doesn't send events for deeper transactions
+		if event.Confirmations >= constants.WatcherMaxConfirmations {
+			confirmed = slices.Delete(confirmed, idx, idx+1)
+		}
+		return confirmed, false
+	}
+
+	if event.Confirmations < constants.WatcherMaxConfirmations {
+		confirmed = append(confirmed, event.TxID)
+	}
+
+	return confirmed, true
+}
+
+func (s *Service) deliver(ctx context.Context) {
+	for {
+		wait := s.deliverDue(ctx)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// deliverDue sends due deliveries and returns duration until the next one.
+func (s *Service) deliverDue(ctx context.Context) (wait time.Duration) {
+	wait = constants.WebhookRetryMax
+
+	for {
+		delivery, ok := s.nextDue(time.Now().Unix())
+		if !ok {
+			break
+		}
+		if ctx.Err() != nil {
+			return wait
+		}
+
+		err := s.send(ctx, delivery)
+		if ctx.Err() != nil {
+			// interrupted attempt isn't counted, it is repeated after restart
+			return wait
+		}
+
+		s.mu.Lock()
+		s.complete(delivery, err)
+		if saveErr := s.save(); saveErr != nil {
+			slog.Error("webhook queue isn't saved", "error", saveErr)
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Unix()
+	for _, delivery := range s.state.Queue {
+		if next := time.Duration(delivery.NextAttempt-now) * time.Second; next < wait {
+			wait = next
+		}
+	}
+
+	return max(wait, time.Second)
+}
+
+func (s *Service) nextDue(now int64) (result entities.WebhookDelivery, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delivery := range s.state.Queue {
+		if delivery.NextAttempt <= now {
+			return delivery, true
+		}
+	}
+
+	return result, false
+}
+
+// complete removes delivered item from the queue or schedules the next attempt with exponential backoff.
+func (s *Service) complete(delivery entities.WebhookDelivery, sendErr error) {
+	idx := -1
+	for i, item := range s.state.Queue {
+		if item.URL == delivery.URL && item.Payload.ID == delivery.Payload.ID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return
+	}
+
+	if sendErr == nil {
+		slog.Debug("webhook is delivered", "url", delivery.URL, "delivery", delivery.Payload.ID, "event", delivery.Payload.Event)
+		s.state.Queue = append(s.state.Queue[:idx], s.state.Queue[idx+1:]...)
+		return
+	}
+
+	item := &s.state.Queue[idx]
+	item.Attempts++
+	item.LastError = sendErr.Error()
+	slog.Warn("webhook delivery failed", "url", item.URL, "delivery", item.Payload.ID, "attempts", item.Attempts, "error", sendErr)
+
+	if item.Attempts >= constants.WebhookMaxAttempts {
+		slog.Error("webhook delivery is dropped", "url", item.URL, "delivery", item.Payload.ID,
+			"attempts", item.Attempts, "error", item.LastError)
+		s.state.Queue = append(s.state.Queue[:idx], s.state.Queue[idx+1:]...)
+		return
+	}
+
+	backoff := constants.WebhookRetryBase << (item.Attempts - 1)
+	if backoff <= 0 || backoff > constants.WebhookRetryMax {
+		backoff = constants.WebhookRetryMax
+	}
+	item.NextAttempt = time.Now().Add(backoff).Unix()
+}
+
+func (s *Service) send(ctx context.Context, delivery entities.WebhookDelivery) (err error) {
+	webhook, ok := s.webhooks[delivery.URL]
+	if !ok {
+		// webhook is removed from config, delivery is dropped by max attempts
+		return wrap.Wrap(errors.New("webhook isn't configured"))
+	}
+
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader(constants.WebhookEventHeader, string(delivery.Payload.Event)).
+		SetHeader(constants.WebhookDeliveryHeader, delivery.Payload.ID).
+		SetHeader(constants.WebhookSignatureHeader, Sign(webhook.Secret, body)).
+		SetBody(body).
+		Post(webhook.URL)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+
+	if resp.IsError() {
+		return wrap.Wrap(fmt.Errorf("%s: webhook error", resp.Status()))
+	}
+
+	return nil
+}
+
+// Sign returns signature header value: "sha256=" and hex of HMAC-SHA256 of the body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) load() (err error) {
+	data, err := os.ReadFile(s.queuePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return wrap.Wrap(err)
+	}
+
+	if err = json.Unmarshal(data, &s.state); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+// save rewrites the queue file atomically, s.mu must be held.
+func (s *Service) save() (err error) {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+
+	tmpPath := s.queuePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	if err = os.Rename(tmpPath, s.queuePath); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+func newDeliveryID() (result string, err error) {
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,316 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+const secret = "webhook secret key"
+
+// fakeWatcher keeps the handler, tests call it instead of polls.
+type fakeWatcher struct {
+	baseline *entities.WalletSnapshot
+	handle   func(events []entities.WalletEvent, current entities.WalletSnapshot) error
+}
+
+func (w *fakeWatcher) SubscribeFunc(baseline *entities.WalletSnapshot, handle func(events []entities.WalletEvent, current entities.WalletSnapshot) error) (unsubscribe func()) {
+	w.baseline, w.handle = baseline, handle
+	return func() {}
+}
+
+// receiver is a webhook endpoint which checks signatures and responds with status.
+type receiver struct {
+	server   *httptest.Server
+	status   atomic.Int32
+	requests atomic.Int32
+	payloads chan entities.WebhookPayload
+}
+
+func newReceiver(t *testing.T) *receiver {
+	t.Helper()
+
+	r := &receiver{payloads: make(chan entities.WebhookPayload, 100)}
+	r.status.Store(http.StatusOK)
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get(constants.WebhookSignatureHeader) != want {
+			t.Errorf("signature is %q, want %q", req.Header.Get(constants.WebhookSignatureHeader), want)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var payload entities.WebhookPayload
+		if err = json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("parse payload: %v", err)
+		}
+		if req.Header.Get(constants.WebhookEventHeader) != string(payload.Event) || req.Header.Get(constants.WebhookDeliveryHeader) != payload.ID {
+			t.Errorf("headers %v don't match payload %+v", req.Header, payload)
+		}
+
+		status := int(r.status.Load())
+		if status == http.StatusOK {
+			r.payloads <- payload
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+
+	return r
+}
+
+func (r *receiver) wait(t *testing.T) entities.WebhookPayload {
+	t.Helper()
+
+	select {
+	case payload := <-r.payloads:
+		return payload
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook isn't delivered")
+		return entities.WebhookPayload{}
+	}
+}
+
+func newNotifier(watcher *fakeWatcher, url, queuePath string, confirmations int) *Service {
+	return NewService(watcher, []Webhook{{URL: url, Secret: secret}}, confirmations, queuePath)
+}
+
+func queuedEvents(s *Service) (result []entities.WebhookEventType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delivery := range s.state.Queue {
+		result = append(result, delivery.Payload.Event)
+	}
+
+	return result
+}
+
+func TestNotifyEvents(t *testing.T) {
+	const txID = "aa"
+
+	tests := []struct {
+		name          string
+		confirmations int
+		events        []entities.WalletEvent
+		want          []entities.WebhookEventType
+	}{
+		{
+			name:          "received",
+			confirmations: 1,
+			events:        []entities.WalletEvent{{Type: entities.WalletEventNewTransaction, TxID: txID, Amount: 1_000}},
+			want:          []entities.WebhookEventType{entities.WebhookEventReceived},
+		},
+		{
+			name:          "outgoing transaction isn't received",
+			confirmations: 1,
+			events:        []entities.WalletEvent{{Type: entities.WalletEventNewTransaction, TxID: txID, Amount: -1_000}},
+		},
+		{
+			name:          "received already confirmed",
+			confirmations: 1,
+			events:        []entities.WalletEvent{{Type: entities.WalletEventNewTransaction, TxID: txID, Amount: 1_000, Confirmations: 2}},
+			want:          []entities.WebhookEventType{entities.WebhookEventReceived, entities.WebhookEventConfirmed},
+		},
+		{
+			name:          "confirmed at N once",
+			confirmations: 3,
+			events: []entities.WalletEvent{
+				{Type: entities.WalletEventConfirmation, TxID: txID, Confirmations: 1},
+				{Type: entities.WalletEventConfirmation, TxID: txID, Confirmations: 2},
+				{Type: entities.WalletEventConfirmation, TxID: txID, Confirmations: 3},
+				{Type: entities.WalletEventConfirmation, TxID: txID, Confirmations: 4},
+			},
+			want: []entities.WebhookEventType{entities.WebhookEventConfirmed},
+		},
+		{
+			name:          "replaced",
+			confirmations: 1,
+			events:        []entities.WalletEvent{{Type: entities.WalletEventReplaced, TxID: txID, ReplacedBy: "bb"}},
+			want:          []entities.WebhookEventType{entities.WebhookEventReplaced},
+		},
+		{
+			name:          "dropped",
+			confirmations: 1,
+			events:        []entities.WalletEvent{{Type: entities.WalletEventDropped, TxID: txID}},
+			want:          []entities.WebhookEventType{entities.WebhookEventDropped},
+		},
+		{
+			name:          "balance change isn't notified",
+			confirmations: 1,
+			events:        []entities.WalletEvent{{Type: entities.WalletEventBalanceChange, Balance: &entities.BalanceResult{Confirmed: 1_000}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newNotifier(&fakeWatcher{}, "http://localhost", filepath.Join(t.TempDir(), "queue"), tt.confirmations)
+			current := entities.WalletSnapshot{History: []entities.HistoryEntry{{TxID: txID, State: entities.TxStateMempool}}}
+
+			// events come one by one like from polls
+			for _, event := range tt.events {
+				if err := s.notify([]entities.WalletEvent{event}, current); err != nil {
+					t.Fatalf("notify: %v", err)
+				}
+			}
+
+			if got := queuedEvents(s); !slices.Equal(got, tt.want) {
+				t.Fatalf("queued %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifySaveError(t *testing.T) {
+	dir := t.TempDir()
+	s := newNotifier(&fakeWatcher{}, "http://localhost", filepath.Join(dir, "missing", "queue"), 1)
+	confirmation := []entities.WalletEvent{{Type: entities.WalletEventConfirmation, TxID: "aa", Confirmations: 1}}
+	current := entities.WalletSnapshot{History: []entities.HistoryEntry{{TxID: "aa", State: entities.TxStateConfirmed, Confirmations: 1}}}
+
+	if err := s.notify(confirmation, current); err == nil {
+		t.Fatal("queue is saved to missing directory")
+	}
+	if len(s.state.Queue) != 0 || len(s.state.Confirmed) != 0 || s.state.Snapshot != nil {
+		t.Fatalf("state is changed by failed save: %+v", s.state)
+	}
+
+	// the watcher repeats the events, the confirmation isn't lost
+	s.queuePath = filepath.Join(dir, "queue")
+	if err := s.notify(confirmation, current); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if got := queuedEvents(s); !slices.Equal(got, []entities.WebhookEventType{entities.WebhookEventConfirmed}) {
+		t.Fatalf("queued %v, want confirmed", got)
+	}
+
+	// replaced transaction isn't tracked anymore
+	current.History[0].State = entities.TxStateReplaced
+	if err := s.notify([]entities.WalletEvent{{Type: entities.WalletEventReplaced, TxID: "aa"}}, current); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if len(s.state.Confirmed) != 0 {
+		t.Fatalf("confirmed are %v, want empty", s.state.Confirmed)
+	}
+}
+
+func TestDeliverySigned(t *testing.T) {
+	r := newReceiver(t)
+	watcher := &fakeWatcher{}
+	s := newNotifier(watcher, r.server.URL, filepath.Join(t.TempDir(), "queue"), 1)
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer s.Stop()
+
+	event := entities.WalletEvent{Type: entities.WalletEventNewTransaction, Time: time.Now().Unix(), TxID: "aa", Amount: 1_000}
+	if err := watcher.handle([]entities.WalletEvent{event}, entities.WalletSnapshot{}); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	// the receiver checks the signature
+	payload := r.wait(t)
+	if payload.Event != entities.WebhookEventReceived || payload.TxID != "aa" || payload.Amount != 1_000 {
+		t.Fatalf("payload is %+v", payload)
+	}
+}
+
+func TestDeliveryRetry(t *testing.T) {
+	r := newReceiver(t)
+	r.status.Store(http.StatusServiceUnavailable)
+	s := newNotifier(&fakeWatcher{}, r.server.URL, filepath.Join(t.TempDir(), "queue"), 1)
+
+	if err := s.notify([]entities.WalletEvent{{Type: entities.WalletEventDropped, TxID: "aa"}}, entities.WalletSnapshot{}); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	for attempt := 1; attempt <= constants.WebhookMaxAttempts; attempt++ {
+		s.deliverDue(context.Background())
+
+		if attempt == constants.WebhookMaxAttempts {
+			break
+		}
+		if len(s.state.Queue) != 1 {
+			t.Fatalf("attempt %d: queue has %d deliveries", attempt, len(s.state.Queue))
+		}
+
+		// exponential backoff
+		delivery := &s.state.Queue[0]
+		backoff := min(constants.WebhookRetryBase<<(attempt-1), constants.WebhookRetryMax)
+		if next := time.Until(time.Unix(delivery.NextAttempt, 0)); delivery.Attempts != attempt || next < backoff-2*time.Second || next > backoff+time.Second {
+			t.Fatalf("attempt %d: delivery %+v is retried in %s, want %s", attempt, delivery, next, backoff)
+		}
+		if delivery.LastError == "" {
+			t.Fatalf("attempt %d: error isn't saved", attempt)
+		}
+
+		// the next attempt is due
+		delivery.NextAttempt = 0
+	}
+
+	if len(s.state.Queue) != 0 {
+		t.Fatalf("delivery isn't dropped after %d attempts: %+v", constants.WebhookMaxAttempts, s.state.Queue)
+	}
+	if requests := int(r.requests.Load()); requests != constants.WebhookMaxAttempts {
+		t.Fatalf("receiver got %d requests, want %d", requests, constants.WebhookMaxAttempts)
+	}
+}
+
+func TestQueueReload(t *testing.T) {
+	r := newReceiver(t)
+	queuePath := filepath.Join(t.TempDir(), "queue")
+	watcher := &fakeWatcher{}
+
+	s := newNotifier(watcher, r.server.URL, queuePath, 1)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	s.Stop()
+
+	// events detected after stop are delivered only after restart
+	current := entities.WalletSnapshot{
+		Balance: entities.BalanceResult{Unconfirmed: 1_000},
+		History: []entities.HistoryEntry{{TxID: "aa", Amount: 1_000, State: entities.TxStateMempool}},
+	}
+	event := entities.WalletEvent{Type: entities.WalletEventNewTransaction, Time: time.Now().Unix(), TxID: "aa", Amount: 1_000}
+	if err := watcher.handle([]entities.WalletEvent{event}, current); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	restarted := newNotifier(watcher, r.server.URL, queuePath, 1)
+	if err := restarted.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer restarted.Stop()
+
+	// the watcher compares the saved state with the current one
+	if watcher.baseline == nil || watcher.baseline.Balance != current.Balance || len(watcher.baseline.History) != 1 {
+		t.Fatalf("baseline is %+v, want %+v", watcher.baseline, current)
+	}
+
+	if payload := r.wait(t); payload.Event != entities.WebhookEventReceived || payload.TxID != "aa" {
+		t.Fatalf("payload is %+v", payload)
+	}
+}
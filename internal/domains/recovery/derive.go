@@ -0,0 +1,207 @@
+package recovery
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// scheme is a standard derivation m/purpose'/1'/account'/chain/index with its script type.
+type scheme struct {
+	standard   string
+	purpose    uint32
+	scriptType string
+	address    func(pubKey []byte) (result string, err error)
+}
+
+// schemes are scanned in this order, BIP84 is the scheme of the wallet.
+var schemes = []scheme{
+	{standard: "BIP44", purpose: 44, scriptType: "p2pkh", address: p2pkhAddress},
+	{standard: "BIP49", purpose: 49, scriptType: "p2sh-p2wpkh", address: p2shP2wpkhAddress},
+	{standard: "BIP84", purpose: 84, scriptType: "p2wpkh", address: p2wpkhAddress},
+	{standard: "BIP86", purpose: 86, scriptType: "p2tr", address: p2trAddress},
+}
+
+func accountPath(purpose, account uint32) string {
+	return fmt.Sprintf("m/%d'/1'/%d'", purpose, account)
+}
+
+// accountKey returns key of m/purpose'/1'/account', 1 is testnet.
+func accountKey(masterKey *bip32.Key, purpose, account uint32) (result *bip32.Key, err error) {
+	result = masterKey
+	for _, index := range []uint32{purpose, 1, account} {
+		if result, err = result.NewChildKey(bip32.FirstHardenedChild + index); err != nil {
+			return result, wrap.Wrap(err)
+		}
+	}
+
+	return result, nil
+}
+
+// addressKey returns key of chain/index of the account.
+func addressKey(accountKey *bip32.Key, chain, index uint32) (result *bip32.Key, err error) {
+	chainKey, err := accountKey.NewChildKey(chain)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = chainKey.NewChildKey(index)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+func p2pkhAddress(pubKey []byte) (result string, err error) {
+	address, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), &chaincfg.TestNet3Params)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return address.EncodeAddress(), nil
+}
+
+func p2shP2wpkhAddress(pubKey []byte) (result string, err error) {
+	// redeem script: OP_0 <20-byte public key hash>
+	redeemScript := append([]byte{0x00, 0x14}, btcutil.Hash160(pubKey)...)
+
+	address, err := btcutil.NewAddressScriptHash(redeemScript, &chaincfg.TestNet3Params)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return address.EncodeAddress(), nil
+}
+
+func p2wpkhAddress(pubKey []byte) (result string, err error) {
+	address, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey), &chaincfg.TestNet3Params)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return address.EncodeAddress(), nil
+}
+
+// p2trAddress returns BIP86 key path only taproot address. btcutil of this version doesn't support taproot and bech32m.
+func p2trAddress(pubKey []byte) (result string, err error) {
+	outputKey, err := taprootOutputKey(pubKey)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = encodeSegwitV1(chaincfg.TestNet3Params.Bech32HRPSegwit, outputKey)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// taprootOutputKey returns x-only output key: the internal key tweaked by its tagged hash without script tree.
+func taprootOutputKey(pubKey []byte) (result []byte, err error) {
+	curve := btcec.S256()
+
+	internalKey, err := btcec.ParsePubKey(pubKey, curve)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	// BIP340 x-only key has even Y
+	x, y := internalKey.X, internalKey.Y
+	if y.Bit(0) == 1 {
+		y = new(big.Int).Sub(curve.P, y)
+	}
+
+	xBytes := make([]byte, 32)
+	x.FillBytes(xBytes)
+
+	tweak := taggedHash("TapTweak", xBytes)
+	if new(big.Int).SetBytes(tweak).Cmp(curve.N) >= 0 {
+		return result, wrap.Wrap(errors.New("taproot tweak is out of range"))
+	}
+
+	tweakX, tweakY := curve.ScalarBaseMult(tweak)
+	outputX, _ := curve.Add(x, y, tweakX, tweakY)
+
+	result = make([]byte, 32)
+	outputX.FillBytes(result)
+
+	return result, nil
+}
+
+func taggedHash(tag string, data []byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	hash := sha256.New()
+	hash.Write(tagHash[:])
+	hash.Write(tagHash[:])
+	hash.Write(data)
+
+	return hash.Sum(nil)
+}
+
+const (
+	bech32Charset   = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32mConstant = 0x2bc830a3
+)
+
+// encodeSegwitV1 returns BIP350 bech32m address of witness version 1 program.
+func encodeSegwitV1(hrp string, program []byte) (result string, err error) {
+	converted, err := bech32.ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	data := append([]byte{1}, converted...)
+
+	values := make([]byte, 0, len(hrp)*2+1+len(data)+6)
+	for _, c := range hrp {
+		values = append(values, byte(c>>5))
+	}
+	values = append(values, 0)
+	for _, c := range hrp {
+		values = append(values, byte(c&31))
+	}
+	values = append(values, data...)
+	values = append(values, make([]byte, 6)...)
+
+	checksum := bech32Polymod(values) ^ bech32mConstant
+
+	var builder strings.Builder
+	builder.WriteString(hrp)
+	builder.WriteByte('1')
+	for _, value := range data {
+		builder.WriteByte(bech32Charset[value])
+	}
+	for i := range 6 {
+		builder.WriteByte(bech32Charset[(checksum>>(5*(5-i)))&31])
+	}
+
+	return builder.String(), nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, value := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(value)
+		for i, g := range generator {
+			if (top>>i)&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+
+	return chk
+}
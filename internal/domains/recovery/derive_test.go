@@ -0,0 +1,80 @@
+package recovery
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// TestTaprootVector checks BIP86 test vector: the first receive address of m/86'/0'/0'.
+func TestTaprootVector(t *testing.T) {
+	key, err := bip32.NewMasterKey(bip39.NewSeed(testMnemonic, ""))
+	if err != nil {
+		t.Fatalf("master key: %v", err)
+	}
+	for _, index := range []uint32{bip32.FirstHardenedChild + 86, bip32.FirstHardenedChild, bip32.FirstHardenedChild, 0, 0} {
+		if key, err = key.NewChildKey(index); err != nil {
+			t.Fatalf("derive: %v", err)
+		}
+	}
+
+	pubKey := key.PublicKey().Key
+	if internalKey := hex.EncodeToString(pubKey[1:]); internalKey != "cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115" {
+		t.Fatalf("internal key is %s", internalKey)
+	}
+
+	outputKey, err := taprootOutputKey(pubKey)
+	if err != nil {
+		t.Fatalf("output key: %v", err)
+	}
+	if got := hex.EncodeToString(outputKey); got != "a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c" {
+		t.Fatalf("output key is %s", got)
+	}
+
+	address, err := encodeSegwitV1("bc", outputKey)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if address != "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr" {
+		t.Fatalf("address is %s", address)
+	}
+
+	// the wallet is on testnet
+	if address, err = p2trAddress(pubKey); err != nil || !strings.HasPrefix(address, "tb1p") {
+		t.Fatalf("testnet address is %s, error %v", address, err)
+	}
+}
+
+// TestEncodeSegwitV1 checks BIP350 test vectors of witness version 1.
+func TestEncodeSegwitV1(t *testing.T) {
+	for _, tc := range []struct{ hrp, program, address string }{
+		{
+			hrp:     "bc",
+			program: "751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6",
+			address: "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y",
+		},
+		{
+			hrp:     "tb",
+			program: "000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433",
+			address: "tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c",
+		},
+	} {
+		program, err := hex.DecodeString(tc.program)
+		if err != nil {
+			t.Fatalf("decode program: %v", err)
+		}
+
+		address, err := encodeSegwitV1(tc.hrp, program)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		if address != tc.address {
+			t.Fatalf("address is %s, want %s", address, tc.address)
+		}
+	}
+}
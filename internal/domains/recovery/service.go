@@ -0,0 +1,280 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/address"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/sync/errgroup"
+)
+
+// WalletAddressPath is the only address spent by the wallet, Import doesn't make other found funds spendable.
+const WalletAddressPath = "m/84'/1'/0'/0/0"
+
+var ErrInvalidScanLimits = errors.New("gap limit and number of accounts must be positive")
+
+type (
+	IAddressService interface {
+		RetrieveAddress(ctx context.Context) (result string, err error)
+		Restore(ctx context.Context, mnemonic, address string) (err error)
+	}
+
+	ITransactionService interface {
+		ClearBroadcastedTransactions() (err error)
+	}
+
+	IEsploraClient interface {
+		GetAddressUTXOs(ctx context.Context, address string) (result entities.TxOutputs, err error)
+		GetAddressTransactions(ctx context.Context, address string) (result []entities.Tx, err error)
+	}
+
+	IAuditService interface {
+		Record(event entities.AuditEventType, data map[string]any) (err error)
+	}
+
+	// Service discovers used accounts of a mnemonic on the chain and imports the mnemonic into the wallet.
+	Service struct {
+		addressService     IAddressService
+		transactionService ITransactionService
+		esploraClient      IEsploraClient
+		auditService       IAuditService
+	}
+)
+
+func NewService(addressService IAddressService, transactionService ITransactionService, esploraClient IEsploraClient, auditService IAuditService) *Service {
+	return &Service{
+		addressService:     addressService,
+		transactionService: transactionService,
+		esploraClient:      esploraClient,
+		auditService:       auditService,
+	}
+}
+
+// Scan checks accounts of all standard schemes (BIP44, BIP49, BIP84, BIP86). Receive and change chains
+// of an account are scanned until gapLimit consecutive unused addresses, accounts are scanned until
+// the first unused one (BIP44 account discovery) but at most maxAccounts.
+// passphrase is BIP39 passphrase of the mnemonic, empty passphrase is a valid one.
+func (s *Service) Scan(ctx context.Context, mnemonic, passphrase string, gapLimit, maxAccounts int) (result entities.RecoveryResult, err error) {
+	if gapLimit <= 0 || maxAccounts <= 0 {
+		return result, wrap.Wrap(ErrInvalidScanLimits)
+	}
+
+	masterKey, err := s.masterKey(mnemonic, passphrase)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result.Accounts = []entities.RecoveredAccount{}
+	for _, scheme := range schemes {
+		for account := range uint32(maxAccounts) {
+			found, scanned, err := s.scanAccount(ctx, masterKey, scheme, account, gapLimit)
+			if err != nil {
+				return result, wrap.Wrap(err)
+			}
+			result.Scanned += scanned
+
+			if len(found.Addresses) == 0 {
+				break
+			}
+
+			result.Accounts = append(result.Accounts, found)
+			result.Confirmed += found.Confirmed
+			result.Unconfirmed += found.Unconfirmed
+			for _, used := range found.Addresses {
+				if used.Path != WalletAddressPath {
+					result.Unspendable += used.Confirmed + used.Unconfirmed
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Import replaces the wallet by mnemonic. It isn't a full recovery: the wallet spends only WalletAddressPath
+// and derives it with secretPassphrase from config, so passphrase must be the same.
+func (s *Service) Import(ctx context.Context, mnemonic, passphrase string) (result string, err error) {
+	masterKey, err := s.masterKey(mnemonic, passphrase)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	account, err := accountKey(masterKey, 84, 0)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	key, err := addressKey(account, 0, 0)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	if result, err = p2wpkhAddress(key.PublicKey().Key); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	previousAddress, err := s.addressService.RetrieveAddress(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if err = s.addressService.Restore(ctx, mnemonic, result); err != nil {
+		if errors.Is(err, address.ErrPassphraseMismatch) {
+			return result, wrap.Wrap(fmt.Errorf("%w: set the recovered passphrase as secretPassphrase in config to import the wallet", err))
+		}
+		return result, wrap.Wrap(err)
+	}
+
+	if previousAddress != result {
+		if err = s.transactionService.ClearBroadcastedTransactions(); err != nil {
+			return result, wrap.Wrap(err)
+		}
+	}
+
+	if err = s.auditService.Record(entities.AuditEventWalletRecovered, map[string]any{
+		"address":          result,
+		"previous_address": previousAddress,
+	}); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+func (s *Service) masterKey(mnemonic, passphrase string) (result *bip32.Key, err error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return result, wrap.Wrap(address.ErrInvalidMnemonic)
+	}
+
+	result, err = bip32.NewMasterKey(bip39.NewSeed(mnemonic, passphrase))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// scanAccount checks receive (0) and change (1) chains of the account.
+func (s *Service) scanAccount(ctx context.Context, masterKey *bip32.Key, scheme scheme, account uint32, gapLimit int) (result entities.RecoveredAccount, scanned int, err error) {
+	key, err := accountKey(masterKey, scheme.purpose, account)
+	if err != nil {
+		return result, scanned, wrap.Wrap(err)
+	}
+
+	result = entities.RecoveredAccount{
+		Standard:   scheme.standard,
+		Path:       accountPath(scheme.purpose, account),
+		ScriptType: scheme.scriptType,
+	}
+
+	for chain := range uint32(2) {
+		addresses, chainScanned, err := s.scanChain(ctx, key, scheme, result.Path, chain, gapLimit)
+		if err != nil {
+			return result, scanned, wrap.Wrap(err)
+		}
+		scanned += chainScanned
+
+		result.Addresses = append(result.Addresses, addresses...)
+	}
+
+	result.Confirmed = lo.SumBy(result.Addresses, func(found entities.RecoveredAddress) int64 { return found.Confirmed })
+	result.Unconfirmed = lo.SumBy(result.Addresses, func(found entities.RecoveredAddress) int64 { return found.Unconfirmed })
+
+	return result, scanned, nil
+}
+
+// scanChain checks addresses of the chain in windows up to gapLimit after the last used address,
+// addresses of a window are requested concurrently (at most constants.BackendConcurrency at once).
+func (s *Service) scanChain(ctx context.Context, accountKey *bip32.Key, scheme scheme, path string, chain uint32, gapLimit int) (result []entities.RecoveredAddress, scanned int, err error) {
+	chainKey, err := accountKey.NewChildKey(chain)
+	if err != nil {
+		return result, scanned, wrap.Wrap(err)
+	}
+
+	addresses := make(map[int]entities.RecoveredAddress)
+	lastUsed := -1
+	for next := 0; next <= lastUsed+gapLimit; {
+		end := lastUsed + gapLimit + 1
+
+		var mu sync.Mutex
+		group, groupCtx := errgroup.WithContext(ctx)
+		group.SetLimit(constants.BackendConcurrency)
+		for index := next; index < end; index++ {
+			group.Go(func() error {
+				found, used, err := s.checkAddress(groupCtx, chainKey, scheme, fmt.Sprintf("%s/%d/%d", path, chain, index), uint32(index))
+				if err != nil {
+					return wrap.Wrap(err)
+				}
+				if !used {
+					return nil
+				}
+
+				mu.Lock()
+				addresses[index] = found
+				lastUsed = max(lastUsed, index)
+				mu.Unlock()
+
+				return nil
+			})
+		}
+
+		if err = group.Wait(); err != nil {
+			return result, scanned, wrap.Wrap(err)
+		}
+
+		scanned += end - next
+		next = end
+	}
+
+	indices := lo.Keys(addresses)
+	slices.Sort(indices)
+	for _, index := range indices {
+		result = append(result, addresses[index])
+	}
+
+	return result, scanned, nil
+}
+
+func (s *Service) checkAddress(ctx context.Context, chainKey *bip32.Key, scheme scheme, path string, index uint32) (result entities.RecoveredAddress, used bool, err error) {
+	key, err := chainKey.NewChildKey(index)
+	if err != nil {
+		return result, false, wrap.Wrap(err)
+	}
+
+	result = entities.RecoveredAddress{Path: path}
+	if result.Address, err = scheme.address(key.PublicKey().Key); err != nil {
+		return result, false, wrap.Wrap(err)
+	}
+
+	transactions, err := s.esploraClient.GetAddressTransactions(ctx, result.Address)
+	if err != nil {
+		return result, false, wrap.Wrap(err)
+	}
+	if len(transactions) == 0 {
+		return result, false, nil
+	}
+	result.Transactions = len(transactions)
+
+	utxos, err := s.esploraClient.GetAddressUTXOs(ctx, result.Address)
+	if err != nil {
+		return result, false, wrap.Wrap(err)
+	}
+	for _, utxo := range utxos {
+		if utxo.Status.Confirmed {
+			result.Confirmed += utxo.Value
+		} else {
+			result.Unconfirmed += utxo.Value
+		}
+	}
+
+	return result, true, nil
+}
@@ -0,0 +1,131 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/backend"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora/esploratest"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// derivedAddress returns address of m/purpose'/1'/account'/chain/index of testMnemonic.
+func derivedAddress(t *testing.T, purpose, account, chain, index uint32) string {
+	t.Helper()
+
+	masterKey, err := bip32.NewMasterKey(bip39.NewSeed(testMnemonic, ""))
+	if err != nil {
+		t.Fatalf("master key: %v", err)
+	}
+	key, err := accountKey(masterKey, purpose, account)
+	if err != nil {
+		t.Fatalf("account key: %v", err)
+	}
+	if key, err = addressKey(key, chain, index); err != nil {
+		t.Fatalf("address key: %v", err)
+	}
+
+	idx := slices.IndexFunc(schemes, func(s scheme) bool { return s.purpose == purpose })
+	address, err := schemes[idx].address(key.PublicKey().Key)
+	if err != nil {
+		t.Fatalf("address: %v", err)
+	}
+
+	return address
+}
+
+func TestScan(t *testing.T) {
+	const gapLimit = 3
+
+	server := esploratest.NewServer()
+	t.Cleanup(server.Close)
+
+	funded := []struct {
+		purpose, account, chain, index uint32
+		value                          int64
+	}{
+		{purpose: 84, account: 0, chain: 0, index: 0, value: 10_000}, // the wallet address
+		{purpose: 84, account: 0, chain: 0, index: gapLimit, value: 20_000},
+		{purpose: 84, account: 0, chain: 0, index: 2*gapLimit + 1, value: 1}, // after the gap of the previous one
+		{purpose: 84, account: 0, chain: 1, index: 1, value: 30_000},
+		{purpose: 84, account: 1, chain: 0, index: 0, value: 40_000},
+		{purpose: 84, account: 3, chain: 0, index: 0, value: 1}, // after unused account 2
+		{purpose: 44, account: 0, chain: 0, index: 0, value: 50_000},
+		{purpose: 49, account: 0, chain: 1, index: 0, value: 60_000},
+	}
+	for _, f := range funded {
+		if _, err := server.Fund(derivedAddress(t, f.purpose, f.account, f.chain, f.index), f.value); err != nil {
+			t.Fatalf("fund: %v", err)
+		}
+	}
+	server.Mine()
+	// unconfirmed funds are found too
+	if _, err := server.Fund(derivedAddress(t, 84, 0, 0, 0), 5_000); err != nil {
+		t.Fatalf("fund: %v", err)
+	}
+
+	client := backend.NewClient([]string{server.BaseURL()}, 5*time.Second, filepath.Join(t.TempDir(), "tx_cache"))
+	service := NewService(nil, nil, client, nil)
+
+	result, err := service.Scan(context.Background(), testMnemonic, "", gapLimit, 5)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	type account struct {
+		path      string
+		addresses []string
+	}
+	var got []account
+	for _, found := range result.Accounts {
+		got = append(got, account{path: found.Path})
+		for _, used := range found.Addresses {
+			got[len(got)-1].addresses = append(got[len(got)-1].addresses, used.Path)
+		}
+	}
+	want := []account{
+		{path: "m/44'/1'/0'", addresses: []string{"m/44'/1'/0'/0/0"}},
+		{path: "m/49'/1'/0'", addresses: []string{"m/49'/1'/0'/1/0"}},
+		{path: "m/84'/1'/0'", addresses: []string{"m/84'/1'/0'/0/0", "m/84'/1'/0'/0/3", "m/84'/1'/0'/1/1"}},
+		{path: "m/84'/1'/1'", addresses: []string{"m/84'/1'/1'/0/0"}},
+	}
+	if !slices.EqualFunc(got, want, func(a, b account) bool { return a.path == b.path && slices.Equal(a.addresses, b.addresses) }) {
+		t.Fatalf("found accounts %+v, want %+v", got, want)
+	}
+
+	if result.Confirmed != 210_000 || result.Unconfirmed != 5_000 {
+		t.Fatalf("found %d/%d, want 210000/5000", result.Confirmed, result.Unconfirmed)
+	}
+	if result.Unspendable != 200_000 {
+		t.Fatalf("unspendable is %d, want 200000 outside %s", result.Unspendable, WalletAddressPath)
+	}
+	wallet := result.Accounts[2].Addresses[0]
+	if wallet.Address != derivedAddress(t, 84, 0, 0, 0) || wallet.Transactions != 2 {
+		t.Fatalf("wallet address is %+v", wallet)
+	}
+
+	// another passphrase gives another wallet
+	if result, err = service.Scan(context.Background(), testMnemonic, "passphrase", gapLimit, 5); err != nil || len(result.Accounts) != 0 {
+		t.Fatalf("scan with another passphrase found %+v, error %v", result.Accounts, err)
+	}
+	// every chain of every scheme is scanned up to the gap
+	if want := len(schemes) * 2 * gapLimit; result.Scanned != want {
+		t.Fatalf("scanned %d addresses, want %d", result.Scanned, want)
+	}
+}
+
+func TestScanInvalid(t *testing.T) {
+	service := NewService(nil, nil, nil, nil)
+
+	if _, err := service.Scan(context.Background(), testMnemonic, "", 0, 1); !errors.Is(err, ErrInvalidScanLimits) {
+		t.Fatalf("scan error is %v, want %v", err, ErrInvalidScanLimits)
+	}
+	if _, err := service.Scan(context.Background(), "abandon abandon", "", 1, 1); err == nil {
+		t.Fatal("invalid mnemonic is scanned")
+	}
+}
@@ -0,0 +1,117 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"github.com/tyler-smith/go-bip39"
+)
+
+type (
+	IAddressService interface {
+		Mnemonic(ctx context.Context) (result string, err error)
+		Import(ctx context.Context, mnemonic string) (result string, err error)
+		RetrieveAddress(ctx context.Context) (result string, err error)
+	}
+
+	ITransactionService interface {
+		ClearBroadcastedTransactions() (err error)
+	}
+
+	IAuditService interface {
+		Record(event entities.AuditEventType, data map[string]any) (err error)
+	}
+
+	// Service splits the wallet seed into SLIP-39 shares and restores the wallet from them.
+	// The shared secret is entropy of the BIP39 mnemonic, so combined shares give the same mnemonic,
+	// secretPassphrase from config is still needed.
+	Service struct {
+		addressService     IAddressService
+		transactionService ITransactionService
+		auditService       IAuditService
+	}
+)
+
+func NewService(addressService IAddressService, transactionService ITransactionService, auditService IAuditService) *Service {
+	return &Service{
+		addressService:     addressService,
+		transactionService: transactionService,
+		auditService:       auditService,
+	}
+}
+
+// Create splits the wallet seed into count shares, any threshold of them restore the wallet.
+func (s *Service) Create(ctx context.Context, threshold, count int) (result entities.SharesResult, err error) {
+	mnemonic, err := s.addressService.Mnemonic(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result.Shares, result.Identifier, err = splitMasterSecret(entropy, "", threshold, count)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	result.Threshold = threshold
+
+	// shares are secrets, only their parameters are recorded
+	if err = s.auditService.Record(entities.AuditEventSharesCreated, map[string]any{
+		"identifier": result.Identifier,
+		"threshold":  threshold,
+		"count":      count,
+	}); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// Combine restores the wallet from shares. ErrNotEnoughShares is returned until threshold shares are passed,
+// the wallet isn't changed then.
+func (s *Service) Combine(ctx context.Context, mnemonics []string) (result entities.SharesCombineResult, err error) {
+	if err = ctx.Err(); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	entropy, err := combineMnemonics(mnemonics, "")
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return result, wrap.Wrap(fmt.Errorf("shared secret isn't BIP39 entropy: %w", err))
+	}
+
+	previousAddress, err := s.addressService.RetrieveAddress(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	if result.Address, err = s.addressService.Import(ctx, mnemonic); err != nil {
+		return result, wrap.Wrap(err)
+	}
+	result.Shares = len(mnemonics)
+
+	if previousAddress != result.Address {
+		if err = s.transactionService.ClearBroadcastedTransactions(); err != nil {
+			return result, wrap.Wrap(err)
+		}
+	}
+
+	if err = s.auditService.Record(entities.AuditEventSharesCombined, map[string]any{
+		"address":          result.Address,
+		"previous_address": previousAddress,
+		"shares":           result.Shares,
+	}); err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,460 @@
+package shares
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// SLIP-39 parameters, see https://github.com/satoshilabs/slips/blob/master/slip-0039.md.
+const (
+	radixBits           = 10 // bits of a word
+	headerWords         = 4  // identifier, extendable flag, iteration exponent, group and member parameters
+	checksumWords       = 3
+	minMnemonicWords    = headerWords + checksumWords + 13 // 128-bit secret
+	maxShareCount       = 16
+	digestIndex         = 254
+	secretIndex         = 255
+	digestLength        = 4
+	baseIterationCount  = 10000
+	roundCount          = 4
+	customization       = "shamir"
+	customizationExtend = "shamir_extendable"
+	minSecretLength     = 16
+)
+
+var (
+	ErrInvalidShare     = errors.New("invalid share")
+	ErrNotEnoughShares  = errors.New("not enough shares")
+	ErrSharesMismatch   = errors.New("shares don't belong to the same secret")
+	ErrInvalidDigest    = errors.New("invalid digest of the shared secret")
+	ErrInvalidThreshold = fmt.Errorf("threshold must be from 1 to number of shares, at most %d shares", maxShareCount)
+)
+
+// share is a decoded SLIP-39 mnemonic.
+type share struct {
+	identifier        uint16 // 15 bits
+	extendable        bool
+	iterationExponent byte
+	groupIndex        byte
+	groupThreshold    byte
+	groupCount        byte
+	memberIndex       byte
+	memberThreshold   byte
+	value             []byte
+}
+
+// splitMasterSecret encrypts masterSecret by passphrase and splits it into count mnemonics of a single group,
+// any threshold of them recover the secret.
+func splitMasterSecret(masterSecret []byte, passphrase string, threshold, count int) (result []string, identifier uint16, err error) {
+	if len(masterSecret) < minSecretLength || len(masterSecret)%2 != 0 {
+		return result, identifier, wrap.Wrap(fmt.Errorf("secret must have even length of at least %d bytes", minSecretLength))
+	}
+	if threshold < 1 || threshold > count || count > maxShareCount {
+		return result, identifier, wrap.Wrap(ErrInvalidThreshold)
+	}
+
+	var random [2]byte
+	if _, err = rand.Read(random[:]); err != nil {
+		return result, identifier, wrap.Wrap(err)
+	}
+	identifier = binary.BigEndian.Uint16(random[:]) & 0x7fff
+
+	const (
+		extendable        = true
+		iterationExponent = 1
+	)
+
+	encrypted, err := crypt(masterSecret, passphrase, iterationExponent, identifier, extendable, true)
+	if err != nil {
+		return result, identifier, wrap.Wrap(err)
+	}
+
+	values, err := splitSecret(byte(threshold), byte(count), encrypted)
+	if err != nil {
+		return result, identifier, wrap.Wrap(err)
+	}
+
+	for index, value := range values {
+		result = append(result, encodeShare(share{
+			identifier:        identifier,
+			extendable:        extendable,
+			iterationExponent: iterationExponent,
+			groupThreshold:    1,
+			groupCount:        1,
+			memberIndex:       byte(index),
+			memberThreshold:   byte(threshold),
+			value:             value,
+		}))
+	}
+
+	return result, identifier, nil
+}
+
+// combineMnemonics recovers the master secret from mnemonics. Groups are supported,
+// so shares created by other SLIP-39 implementations are accepted too.
+func combineMnemonics(mnemonics []string, passphrase string) (result []byte, err error) {
+	if len(mnemonics) == 0 {
+		return result, wrap.Wrap(ErrNotEnoughShares)
+	}
+
+	shares := make([]share, 0, len(mnemonics))
+	for i, mnemonic := range mnemonics {
+		decoded, err := decodeShare(mnemonic)
+		if err != nil {
+			return result, wrap.Wrap(fmt.Errorf("share %d: %w", i+1, err))
+		}
+		shares = append(shares, decoded)
+	}
+
+	first := shares[0]
+	groups := make(map[byte]map[byte]share)
+	for _, s := range shares {
+		if s.identifier != first.identifier || s.extendable != first.extendable || s.iterationExponent != first.iterationExponent ||
+			s.groupThreshold != first.groupThreshold || s.groupCount != first.groupCount || len(s.value) != len(first.value) {
+			return result, wrap.Wrap(ErrSharesMismatch)
+		}
+
+		members, ok := groups[s.groupIndex]
+		if !ok {
+			members = make(map[byte]share)
+			groups[s.groupIndex] = members
+		}
+		for _, member := range members {
+			if member.memberThreshold != s.memberThreshold {
+				return result, wrap.Wrap(ErrSharesMismatch)
+			}
+		}
+		if member, ok := members[s.memberIndex]; ok && !bytes.Equal(member.value, s.value) {
+			return result, wrap.Wrap(fmt.Errorf("%w: different shares with index %d", ErrSharesMismatch, s.memberIndex+1))
+		}
+		members[s.memberIndex] = s
+	}
+
+	var groupValues []point
+	for groupIndex, members := range groups {
+		var memberThreshold byte
+		values := make([]point, 0, len(members))
+		for memberIndex, member := range members {
+			memberThreshold = member.memberThreshold
+			values = append(values, point{x: memberIndex, y: member.value})
+		}
+		if len(values) < int(memberThreshold) {
+			continue
+		}
+
+		value, err := recoverSecret(memberThreshold, values)
+		if err != nil {
+			return result, wrap.Wrap(err)
+		}
+		groupValues = append(groupValues, point{x: groupIndex, y: value})
+	}
+
+	if len(groupValues) < int(first.groupThreshold) {
+		if first.groupCount == 1 {
+			return result, wrap.Wrap(fmt.Errorf("%w: %d of %d shares", ErrNotEnoughShares, len(groups[0]), first.memberThreshold))
+		}
+		return result, wrap.Wrap(fmt.Errorf("%w: %d of %d groups are complete", ErrNotEnoughShares, len(groupValues), first.groupThreshold))
+	}
+
+	encrypted, err := recoverSecret(first.groupThreshold, groupValues)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = crypt(encrypted, passphrase, first.iterationExponent, first.identifier, first.extendable, false)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
+
+// crypt encrypts or decrypts the master secret by 4-round Feistel network with PBKDF2 round function.
+func crypt(secret []byte, passphrase string, iterationExponent byte, identifier uint16, extendable, encrypt bool) (result []byte, err error) {
+	half := len(secret) / 2
+	left, right := bytes.Clone(secret[:half]), bytes.Clone(secret[half:])
+
+	var salt []byte
+	if !extendable {
+		salt = binary.BigEndian.AppendUint16([]byte(customization), identifier)
+	}
+
+	for round := range roundCount {
+		if !encrypt {
+			round = roundCount - 1 - round
+		}
+
+		key, err := pbkdf2.Key(sha256.New, string(append([]byte{byte(round)}, passphrase...)), append(bytes.Clone(salt), right...),
+			(baseIterationCount<<iterationExponent)/roundCount, len(right))
+		if err != nil {
+			return result, wrap.Wrap(err)
+		}
+
+		for i := range left {
+			left[i] ^= key[i]
+		}
+		left, right = right, left
+	}
+
+	return append(right, left...), nil
+}
+
+type point struct {
+	x byte
+	y []byte
+}
+
+// splitSecret returns count shares of secret with x = 0..count-1. Shares at digestIndex and secretIndex
+// define the polynomial, the digest checks the recovered secret.
+func splitSecret(threshold, count byte, secret []byte) (result [][]byte, err error) {
+	if threshold == 1 {
+		for range count {
+			result = append(result, bytes.Clone(secret))
+		}
+		return result, nil
+	}
+
+	base := make([]point, 0, threshold)
+	for x := range threshold - 2 {
+		value := make([]byte, len(secret))
+		if _, err = rand.Read(value); err != nil {
+			return result, wrap.Wrap(err)
+		}
+		base = append(base, point{x: x, y: value})
+		result = append(result, value)
+	}
+
+	randomPart := make([]byte, len(secret)-digestLength)
+	if _, err = rand.Read(randomPart); err != nil {
+		return result, wrap.Wrap(err)
+	}
+	digest := append(secretDigest(randomPart, secret), randomPart...)
+	base = append(base, point{x: digestIndex, y: digest}, point{x: secretIndex, y: secret})
+
+	for x := threshold - 2; x < count; x++ {
+		result = append(result, interpolate(base, x))
+	}
+
+	return result, nil
+}
+
+func recoverSecret(threshold byte, points []point) (result []byte, err error) {
+	if threshold == 1 {
+		return points[0].y, nil
+	}
+	points = points[:threshold]
+
+	result = interpolate(points, secretIndex)
+	digest := interpolate(points, digestIndex)
+	if !hmac.Equal(digest[:digestLength], secretDigest(digest[digestLength:], result)) {
+		return result, wrap.Wrap(ErrInvalidDigest)
+	}
+
+	return result, nil
+}
+
+func secretDigest(randomPart, secret []byte) []byte {
+	mac := hmac.New(sha256.New, randomPart)
+	mac.Write(secret)
+
+	return mac.Sum(nil)[:digestLength]
+}
+
+// expTable and logTable are powers and logarithms of generator 3 in GF(256) with Rijndael polynomial.
+var expTable, logTable = func() (exp [255]byte, log [256]byte) {
+	x := 1
+	for i := range exp {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x ^= x << 1
+		if x&0x100 != 0 {
+			x ^= 0x11b
+		}
+	}
+	return exp, log
+}()
+
+// interpolate returns value at x of the polynomial passing through points by Lagrange interpolation in GF(256).
+func interpolate(points []point, x byte) []byte {
+	for _, p := range points {
+		if p.x == x {
+			return bytes.Clone(p.y)
+		}
+	}
+
+	logProduct := 0
+	for _, p := range points {
+		logProduct += int(logTable[p.x^x])
+	}
+
+	result := make([]byte, len(points[0].y))
+	for _, p := range points {
+		logBasis := logProduct - int(logTable[p.x^x])
+		for _, other := range points {
+			if other.x != p.x {
+				logBasis -= int(logTable[p.x^other.x])
+			}
+		}
+		logBasis = (logBasis%255 + 255) % 255
+
+		for i, value := range p.y {
+			if value != 0 {
+				result[i] ^= expTable[(int(logTable[value])+logBasis)%255]
+			}
+		}
+	}
+
+	return result
+}
+
+func encodeShare(s share) string {
+	extendable := 0
+	if s.extendable {
+		extendable = 1
+	}
+
+	header := uint64(s.identifier)<<25 | uint64(extendable)<<24 | uint64(s.iterationExponent)<<20 |
+		uint64(s.groupIndex)<<16 | uint64(s.groupThreshold-1)<<12 | uint64(s.groupCount-1)<<8 |
+		uint64(s.memberIndex)<<4 | uint64(s.memberThreshold-1)
+
+	indices := make([]int, 0, headerWords+valueWords(len(s.value))+checksumWords)
+	for i := headerWords - 1; i >= 0; i-- {
+		indices = append(indices, int(header>>(radixBits*i))&(1<<radixBits-1))
+	}
+
+	value := new(big.Int).SetBytes(s.value)
+	for i := valueWords(len(s.value)) - 1; i >= 0; i-- {
+		word := new(big.Int).Rsh(value, uint(radixBits*i))
+		indices = append(indices, int(word.Uint64())&(1<<radixBits-1))
+	}
+
+	indices = append(indices, checksum(customizationString(s.extendable), indices)...)
+
+	words := make([]string, 0, len(indices))
+	for _, index := range indices {
+		words = append(words, wordlist[index])
+	}
+
+	return strings.Join(words, " ")
+}
+
+func decodeShare(mnemonic string) (result share, err error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) < minMnemonicWords {
+		return result, wrap.Wrap(fmt.Errorf("%w: at least %d words expected", ErrInvalidShare, minMnemonicWords))
+	}
+
+	indices := make([]int, 0, len(words))
+	for _, word := range words {
+		index, ok := wordIndex[word]
+		if !ok {
+			return result, wrap.Wrap(fmt.Errorf("%w: unknown word %q", ErrInvalidShare, word))
+		}
+		indices = append(indices, index)
+	}
+
+	// the extendable flag is a part of the header, the checksum depends on it
+	result.extendable = indices[1]>>4&1 == 1
+	if polymod(customizationString(result.extendable), indices) != 1 {
+		return result, wrap.Wrap(fmt.Errorf("%w: wrong checksum", ErrInvalidShare))
+	}
+
+	var header uint64
+	for _, index := range indices[:headerWords] {
+		header = header<<radixBits | uint64(index)
+	}
+	result.identifier = uint16(header >> 25)
+	result.iterationExponent = byte(header>>20) & 0xf
+	result.groupIndex = byte(header>>16) & 0xf
+	result.groupThreshold = byte(header>>12)&0xf + 1
+	result.groupCount = byte(header>>8)&0xf + 1
+	result.memberIndex = byte(header>>4) & 0xf
+	result.memberThreshold = byte(header)&0xf + 1
+	if result.groupThreshold > result.groupCount {
+		return result, wrap.Wrap(fmt.Errorf("%w: group threshold exceeds number of groups", ErrInvalidShare))
+	}
+
+	valueIndices := indices[headerWords : len(indices)-checksumWords]
+	paddingBits := radixBits * len(valueIndices) % 16
+	if paddingBits > 8 {
+		return result, wrap.Wrap(fmt.Errorf("%w: wrong length", ErrInvalidShare))
+	}
+	length := (radixBits*len(valueIndices) - paddingBits) / 8
+
+	value := new(big.Int)
+	for _, index := range valueIndices {
+		value.Lsh(value, radixBits).Or(value, big.NewInt(int64(index)))
+	}
+	if value.BitLen() > length*8 {
+		return result, wrap.Wrap(fmt.Errorf("%w: wrong padding", ErrInvalidShare))
+	}
+	result.value = value.FillBytes(make([]byte, length))
+
+	return result, nil
+}
+
+var wordIndex = func() map[string]int {
+	result := make(map[string]int, len(wordlist))
+	for i, word := range wordlist {
+		result[word] = i
+	}
+	return result
+}()
+
+func valueWords(length int) int {
+	return (length*8 + radixBits - 1) / radixBits
+}
+
+func customizationString(extendable bool) string {
+	if extendable {
+		return customizationExtend
+	}
+	return customization
+}
+
+// checksum returns RS1024 checksum words of data.
+func checksum(customization string, data []int) []int {
+	values := append(append([]int{}, data...), make([]int, checksumWords)...)
+	chk := polymod(customization, values) ^ 1
+
+	result := make([]int, 0, checksumWords)
+	for i := checksumWords - 1; i >= 0; i-- {
+		result = append(result, (chk>>(radixBits*i))&(1<<radixBits-1))
+	}
+
+	return result
+}
+
+func polymod(customization string, values []int) int {
+	generator := [10]int{0xe0e040, 0x1c1c080, 0x3838100, 0x7070200, 0xe0e0009, 0x1c0c2412, 0x38086c24, 0x3090fc48, 0x21b1f890, 0x3f3f120}
+
+	chk := 1
+	step := func(value int) {
+		top := chk >> 20
+		chk = (chk&0xfffff)<<radixBits ^ value
+		for i, g := range generator {
+			if (top>>i)&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+
+	for _, c := range []byte(customization) {
+		step(int(c))
+	}
+	for _, value := range values {
+		step(value)
+	}
+
+	return chk
+}
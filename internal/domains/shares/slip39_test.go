@@ -0,0 +1,253 @@
+package shares
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// vectors are valid cases of SLIP-39 test vectors (vectors.json), their passphrase is "TREZOR".
+var vectors = []struct {
+	name         string
+	mnemonics    []string
+	masterSecret string
+}{
+	{
+		name: "1. valid mnemonic without sharing (128 bits)",
+		mnemonics: []string{
+			"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard",
+		},
+		masterSecret: "bb54aac4b89dc868ba37d9cc21b2cece",
+	},
+	{
+		name: "4. basic sharing 2-of-3 (128 bits)",
+		mnemonics: []string{
+			"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+			"shadow pistol academic acid actress prayer class unknown daughter sweater depict flip twice unkind craft early superior advocate guest smoking",
+		},
+		masterSecret: "b43ceb7e57a0ea8766221624d01b0864",
+	},
+	{
+		name: "21. valid mnemonic without sharing (256 bits)",
+		mnemonics: []string{
+			"theory painting academic academic armed sweater year military elder discuss acne wildlife boring employer fused large satoshi bundle carbon diagnose anatomy hamster leaves tracks paces beyond phantom capital marvel lips brave detect luck",
+		},
+		masterSecret: "989baf9dcaad5b10ca33dfd8cc75e42477025dce88ae83e75a230086a0e00e92",
+	},
+}
+
+func TestCombineVectors(t *testing.T) {
+	for _, v := range vectors {
+		t.Run(v.name, func(t *testing.T) {
+			result, err := combineMnemonics(v.mnemonics, "TREZOR")
+			if err != nil {
+				t.Fatalf("combine: %v", err)
+			}
+			if got := hex.EncodeToString(result); got != v.masterSecret {
+				t.Fatalf("master secret is %s, want %s", got, v.masterSecret)
+			}
+
+			// the order of shares doesn't matter
+			reversed := make([]string, 0, len(v.mnemonics))
+			for i := len(v.mnemonics) - 1; i >= 0; i-- {
+				reversed = append(reversed, v.mnemonics[i])
+			}
+			if result, err = combineMnemonics(reversed, "TREZOR"); err != nil || hex.EncodeToString(result) != v.masterSecret {
+				t.Fatalf("reversed shares give %x, error %v", result, err)
+			}
+		})
+	}
+}
+
+// rewrite changes word indices of mnemonic without the checksum, then the checksum is computed again,
+// so the mnemonic reaches checks after the checksum one.
+func rewrite(t *testing.T, mnemonic string, change func(indices []int) []int) string {
+	t.Helper()
+
+	words := strings.Fields(mnemonic)
+	indices := make([]int, 0, len(words))
+	for _, word := range words {
+		index, ok := wordIndex[word]
+		if !ok {
+			t.Fatalf("unknown word %q", word)
+		}
+		indices = append(indices, index)
+	}
+
+	indices = change(indices[:len(indices)-checksumWords])
+	extendable := indices[1]>>4&1 == 1
+	indices = append(indices, checksum(customizationString(extendable), indices)...)
+
+	result := make([]string, 0, len(indices))
+	for _, index := range indices {
+		result = append(result, wordlist[index])
+	}
+
+	return strings.Join(result, " ")
+}
+
+func TestCombineInvalid(t *testing.T) {
+	single := vectors[0].mnemonics[0]
+	first, second := vectors[1].mnemonics[0], vectors[1].mnemonics[1]
+	words := strings.Fields(single)
+
+	changedValue, err := decodeShare(second)
+	if err != nil {
+		t.Fatalf("decode share: %v", err)
+	}
+	changedValue.value[0] ^= 1
+
+	tests := []struct {
+		name      string
+		mnemonics []string
+		want      error
+	}{
+		{
+			name:      "no shares",
+			mnemonics: nil,
+			want:      ErrNotEnoughShares,
+		},
+		{
+			name:      "invalid checksum",
+			mnemonics: []string{strings.Join(append(words[:len(words)-1:len(words)-1], "kidney"), " ")},
+			want:      ErrInvalidShare,
+		},
+		{
+			name:      "unknown word",
+			mnemonics: []string{strings.Replace(single, "duckling", "duck", 1)},
+			want:      ErrInvalidShare,
+		},
+		{
+			name:      "too short mnemonic",
+			mnemonics: []string{strings.Join(words[:minMnemonicWords-1], " ")},
+			want:      ErrInvalidShare,
+		},
+		{
+			name: "invalid padding",
+			mnemonics: []string{rewrite(t, single, func(indices []int) []int {
+				// 128-bit value takes 13 words, the first 2 bits are padding
+				indices[headerWords] |= 1 << (radixBits - 1)
+				return indices
+			})},
+			want: ErrInvalidShare,
+		},
+		{
+			name: "invalid value length",
+			mnemonics: []string{rewrite(t, single, func(indices []int) []int {
+				return append(indices[:headerWords:headerWords], indices[headerWords+2:]...)
+			})},
+			want: ErrInvalidShare,
+		},
+		{
+			name: "group threshold exceeds number of groups",
+			mnemonics: []string{rewrite(t, single, func(indices []int) []int {
+				// group threshold - 1 is in bits 2-5 of the third word
+				indices[2] |= 1 << 2
+				return indices
+			})},
+			want: ErrInvalidShare,
+		},
+		{
+			name:      "not enough shares",
+			mnemonics: []string{first},
+			want:      ErrNotEnoughShares,
+		},
+		{
+			name:      "the same share twice",
+			mnemonics: []string{first, first},
+			want:      ErrNotEnoughShares,
+		},
+		{
+			name:      "shares of different secrets",
+			mnemonics: []string{first, single},
+			want:      ErrSharesMismatch,
+		},
+		{
+			name:      "invalid digest",
+			mnemonics: []string{first, encodeShare(changedValue)},
+			want:      ErrInvalidDigest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := combineMnemonics(tt.mnemonics, "TREZOR"); !errors.Is(err, tt.want) {
+				t.Fatalf("combine error is %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret, err := hex.DecodeString("00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff")
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+
+	for _, tc := range []struct{ threshold, count, bytes int }{
+		{threshold: 1, count: 1, bytes: 16},
+		{threshold: 2, count: 3, bytes: 16},
+		{threshold: 2, count: 3, bytes: 32},
+		{threshold: 3, count: 5, bytes: 32},
+		{threshold: 16, count: 16, bytes: 16},
+	} {
+		masterSecret := secret[:tc.bytes]
+
+		mnemonics, identifier, err := splitMasterSecret(masterSecret, "passphrase", tc.threshold, tc.count)
+		if err != nil {
+			t.Fatalf("split %d-of-%d: %v", tc.threshold, tc.count, err)
+		}
+		if len(mnemonics) != tc.count {
+			t.Fatalf("split %d-of-%d gives %d shares", tc.threshold, tc.count, len(mnemonics))
+		}
+
+		for _, mnemonic := range mnemonics {
+			decoded, err := decodeShare(mnemonic)
+			if err != nil {
+				t.Fatalf("decode share: %v", err)
+			}
+			if decoded.identifier != identifier || !decoded.extendable {
+				t.Fatalf("share has identifier %d and extendable %v, want %d and true", decoded.identifier, decoded.extendable, identifier)
+			}
+		}
+
+		// any threshold shares recover the secret
+		for _, subset := range [][]string{mnemonics[:tc.threshold], mnemonics[tc.count-tc.threshold:]} {
+			result, err := combineMnemonics(subset, "passphrase")
+			if err != nil {
+				t.Fatalf("combine %d-of-%d: %v", tc.threshold, tc.count, err)
+			}
+			if !bytes.Equal(result, masterSecret) {
+				t.Fatalf("combine %d-of-%d gives %x, want %x", tc.threshold, tc.count, result, masterSecret)
+			}
+		}
+
+		// another passphrase gives another secret, it can't be detected
+		result, err := combineMnemonics(mnemonics[:tc.threshold], "")
+		if err != nil || bytes.Equal(result, masterSecret) {
+			t.Fatalf("combine %d-of-%d without passphrase gives %x, error %v", tc.threshold, tc.count, result, err)
+		}
+
+		if tc.threshold > 1 {
+			if _, err = combineMnemonics(mnemonics[:tc.threshold-1], "passphrase"); !errors.Is(err, ErrNotEnoughShares) {
+				t.Fatalf("combine %d of %d-of-%d error is %v, want %v", tc.threshold-1, tc.threshold, tc.count, err, ErrNotEnoughShares)
+			}
+		}
+	}
+}
+
+func TestSplitInvalidThreshold(t *testing.T) {
+	secret := make([]byte, 16)
+
+	for _, tc := range []struct{ threshold, count int }{
+		{threshold: 0, count: 3},
+		{threshold: 4, count: 3},
+		{threshold: 2, count: 17},
+	} {
+		if _, _, err := splitMasterSecret(secret, "", tc.threshold, tc.count); !errors.Is(err, ErrInvalidThreshold) {
+			t.Fatalf("split %d-of-%d error is %v, want %v", tc.threshold, tc.count, err, ErrInvalidThreshold)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// GetTransactionDetails requests transaction from the backend and decodes it.
+func (s *Service) GetTransactionDetails(ctx context.Context, txID string) (result entities.DecodedTx, err error) {
+	hexTx, err := s.esploraClient.GetTransactionHex(ctx, txID)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result, err = s.DecodeTransaction(ctx, hexTx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	respTx, err := s.esploraClient.GetTransaction(ctx, txID)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+	result.Status = &respTx.Status
+
+	// backend knows addresses which can't be decoded locally (e.g. taproot)
+	for idx := range result.Outputs {
+		if idx < len(respTx.Vout) && result.Outputs[idx].Address == "" {
+			result.Outputs[idx].Address = respTx.Vout[idx].ScriptPubKeyAddress
+			result.Outputs[idx].Type = respTx.Vout[idx].ScriptPubKeyType
+		}
+	}
+
+	return result, nil
+}
+
+// DecodeTransaction decodes raw transaction in hex.
+// Previous outputs are requested from the backend, unknown ones are skipped.
+func (s *Service) DecodeTransaction(ctx context.Context, hexTx string) (result entities.DecodedTx, err error) {
+	tx, err := ParseTransaction(hexTx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	walletAddress, err := s.addressService.RetrieveAddress(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result = entities.DecodedTx{
+		TxID:     tx.TxHash().String(),
+		WTxID:    tx.WitnessHash().String(),
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Size:     int64(tx.SerializeSize()),
+		Weight:   blockchain.GetTransactionWeight(btcutil.NewTx(tx)),
+		VSize:    mempool.GetTxVirtualSize(btcutil.NewTx(tx)),
+		FeeKnown: true,
+		Inputs:   make([]entities.DecodedTxIn, 0, len(tx.TxIn)),
+		Outputs:  make([]entities.DecodedTxOut, 0, len(tx.TxOut)),
+	}
+
+	// unknown previous transactions are missing
+	prevTXs, err := s.esploraClient.GetTransactions(ctx, lo.Map(tx.TxIn, func(txIn *wire.TxIn, _ int) string {
+		return txIn.PreviousOutPoint.Hash.String()
+	}))
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	var totalInputValue, totalOutputValue int64
+	for _, txIn := range tx.TxIn {
+		input := entities.DecodedTxIn{
+			TxID:         txIn.PreviousOutPoint.Hash.String(),
+			Vout:         txIn.PreviousOutPoint.Index,
+			Sequence:     txIn.Sequence,
+			ScriptSigASM: disasmScript(txIn.SignatureScript),
+			Witness:      make([]string, 0, len(txIn.Witness)),
+		}
+		for _, item := range txIn.Witness {
+			input.Witness = append(input.Witness, hex.EncodeToString(item))
+		}
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			result.RBF = true
+		}
+
+		prevTX := prevTXs[input.TxID]
+		if int(input.Vout) < len(prevTX.Vout) {
+			prevOut := prevTX.Vout[input.Vout]
+			pkScript, err := hex.DecodeString(prevOut.ScriptPubKey)
+			if err != nil {
+				return result, wrap.Wrap(err)
+			}
+			decoded := decodeTxOut(input.Vout, wire.NewTxOut(prevOut.Value, pkScript), walletAddress)
+			if decoded.Address == "" {
+				decoded.Address = prevOut.ScriptPubKeyAddress
+				decoded.Type = prevOut.ScriptPubKeyType
+			}
+			decoded.IsWallet = decoded.Address == walletAddress
+			input.Prevout = &decoded
+			input.IsWallet = decoded.IsWallet
+			totalInputValue += prevOut.Value
+		} else {
+			result.FeeKnown = false
+		}
+
+		result.Inputs = append(result.Inputs, input)
+	}
+
+	for idx, txOut := range tx.TxOut {
+		result.Outputs = append(result.Outputs, decodeTxOut(uint32(idx), txOut, walletAddress))
+		totalOutputValue += txOut.Value
+	}
+
+	if result.FeeKnown {
+		result.Fee = totalInputValue - totalOutputValue
+	}
+
+	return result, nil
+}
+
+// ParseTransaction parses raw transaction in hex.
+func ParseTransaction(hexTx string) (tx *wire.MsgTx, err error) {
+	rawTx, err := hex.DecodeString(hexTx)
+	if err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	tx = wire.NewMsgTx(wire.TxVersion)
+	reader := bytes.NewReader(rawTx)
+	if err = tx.Deserialize(reader); err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	if reader.Len() != 0 {
+		return nil, wrap.Wrap(fmt.Errorf("%d extra bytes after transaction", reader.Len()))
+	}
+
+	return tx, nil
+}
+
+func decodeTxOut(index uint32, txOut *wire.TxOut, walletAddress string) entities.DecodedTxOut {
+	result := entities.DecodedTxOut{
+		Index:           index,
+		Value:           txOut.Value,
+		ScriptPubKey:    hex.EncodeToString(txOut.PkScript),
+		ScriptPubKeyASM: disasmScript(txOut.PkScript),
+	}
+
+	class, addresses, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript, &chaincfg.TestNet3Params)
+	if err == nil && class != txscript.NonStandardTy {
+		result.Type = class.String()
+	}
+	if err == nil && len(addresses) == 1 {
+		result.Address = addresses[0].EncodeAddress()
+		result.IsWallet = result.Address == walletAddress
+	}
+
+	return result
+}
+
+func disasmScript(script []byte) string {
+	asm, err := txscript.DisasmString(script)
+	if err != nil {
+		// script is malformed, show its valid part
+		return fmt.Sprintf("%s [error: %s]", asm, err)
+	}
+
+	return asm
+}
@@ -0,0 +1,186 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// ErrSigHashMismatch is returned when PSBT input requests sighash which the caller doesn't allow.
+var ErrSigHashMismatch = errors.New("PSBT input sighash doesn't match the allowed sighash")
+
+// sigHashNames are bitcoind sighash names, DEFAULT is SIGHASH_ALL for segwit v0 inputs.
+var sigHashNames = map[string]txscript.SigHashType{
+	"DEFAULT":             txscript.SigHashAll,
+	"ALL":                 txscript.SigHashAll,
+	"NONE":                txscript.SigHashNone,
+	"SINGLE":              txscript.SigHashSingle,
+	"ALL|ANYONECANPAY":    txscript.SigHashAll | txscript.SigHashAnyOneCanPay,
+	"NONE|ANYONECANPAY":   txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+	"SINGLE|ANYONECANPAY": txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+}
+
+// ParseSigHashType parses bitcoind sighash name, empty name is SIGHASH_ALL.
+func ParseSigHashType(name string) (result txscript.SigHashType, err error) {
+	if name == "" {
+		return txscript.SigHashAll, nil
+	}
+
+	result, ok := sigHashNames[strings.ToUpper(name)]
+	if !ok {
+		return 0, wrap.Wrap(fmt.Errorf("'%s' is not a valid sighash parameter", name))
+	}
+
+	return result, nil
+}
+
+// sigHashName returns bitcoind name of sighash type.
+func sigHashName(sigHashType txscript.SigHashType) string {
+	for name, value := range sigHashNames {
+		if value == sigHashType && name != "DEFAULT" {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("%#x", uint32(sigHashType))
+}
+
+// SignPSBT signs all PSBT (BIP174) inputs which spend wallet outputs.
+// Missing previous outputs are requested from the backend. If finalize is set,
+// signed inputs are finalized and complete reports whether transaction can be extracted.
+// Inputs are signed with sigHashType (zero is SIGHASH_ALL), an input which requests
+// another sighash is rejected: NONE or ANYONECANPAY signatures let others change the transaction.
+func (s *Service) SignPSBT(ctx context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (result string, signed int, complete bool, err error) {
+	if sigHashType == 0 {
+		sigHashType = txscript.SigHashAll
+	}
+
+	packet, err := psbt.NewFromRawBytes(strings.NewReader(strings.TrimSpace(b64PSBT)), true)
+	if err != nil {
+		return "", 0, false, wrap.Wrap(err)
+	}
+
+	wif, witness, err := s.generateWifAndWitnessAddress(ctx)
+	if err != nil {
+		return "", 0, false, wrap.Wrap(err)
+	}
+
+	walletPkScript, err := txscript.PayToAddrScript(witness)
+	if err != nil {
+		return "", 0, false, wrap.Wrap(err)
+	}
+
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return "", 0, false, wrap.Wrap(err)
+	}
+
+	tx := packet.UnsignedTx
+	sigHashes := txscript.NewTxSigHashes(tx)
+	pubKey := wif.PrivKey.PubKey().SerializeCompressed()
+
+	for idx, txIn := range tx.TxIn {
+		input := &packet.Inputs[idx]
+		if input.FinalScriptWitness != nil || input.FinalScriptSig != nil {
+			continue
+		}
+
+		prevOut, err := s.psbtPrevOut(ctx, updater, idx, txIn)
+		if err != nil {
+			return "", 0, false, wrap.Wrap(err)
+		}
+
+		if !bytes.Equal(prevOut.PkScript, walletPkScript) {
+			continue
+		}
+
+		switch input.SighashType {
+		case sigHashType:
+		case 0:
+			if sigHashType != txscript.SigHashAll {
+				if err = updater.AddInSighashType(sigHashType, idx); err != nil {
+					return "", 0, false, wrap.Wrap(err)
+				}
+			}
+		default:
+			return "", 0, false, wrap.Wrap(fmt.Errorf("input #%d: %w: %s requested, %s allowed", idx, ErrSigHashMismatch, sigHashName(input.SighashType), sigHashName(sigHashType)))
+		}
+
+		sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, idx, prevOut.Value, walletPkScript, sigHashType, wif.PrivKey)
+		if err != nil {
+			return "", 0, false, wrap.Wrap(err)
+		}
+
+		if _, err = updater.Sign(idx, sig, pubKey, nil, nil); err != nil {
+			return "", 0, false, wrap.Wrap(fmt.Errorf("input #%d: %w", idx, err))
+		}
+		signed++
+
+		if finalize {
+			if err = psbt.Finalize(packet, idx); err != nil {
+				return "", 0, false, wrap.Wrap(fmt.Errorf("input #%d: %w", idx, err))
+			}
+		}
+	}
+
+	result, err = packet.B64Encode()
+	if err != nil {
+		return "", 0, false, wrap.Wrap(err)
+	}
+
+	if signed > 0 {
+		if err = s.auditService.Record(entities.AuditEventPSBTSigned, map[string]any{
+			"txid":     packet.UnsignedTx.TxHash().String(),
+			"signed":   signed,
+			"complete": packet.IsComplete(),
+		}); err != nil {
+			return "", 0, false, wrap.Wrap(err)
+		}
+	}
+
+	return result, signed, packet.IsComplete(), nil
+}
+
+// psbtPrevOut returns previous output of PSBT input, it is added to PSBT if it's missing.
+func (s *Service) psbtPrevOut(ctx context.Context, updater *psbt.Updater, idx int, txIn *wire.TxIn) (prevOut *wire.TxOut, err error) {
+	input := updater.Upsbt.Inputs[idx]
+	outIndex := txIn.PreviousOutPoint.Index
+
+	switch {
+	case input.WitnessUtxo != nil:
+		return input.WitnessUtxo, nil
+	case input.NonWitnessUtxo != nil:
+		if input.NonWitnessUtxo.TxHash() != txIn.PreviousOutPoint.Hash || int(outIndex) >= len(input.NonWitnessUtxo.TxOut) {
+			return nil, wrap.Wrap(fmt.Errorf("input #%d: previous transaction doesn't match outpoint", idx))
+		}
+		return input.NonWitnessUtxo.TxOut[outIndex], nil
+	}
+
+	hexTx, err := s.esploraClient.GetTransactionHex(ctx, txIn.PreviousOutPoint.Hash.String())
+	if err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	prevTx, err := ParseTransaction(hexTx)
+	if err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	if int(outIndex) >= len(prevTx.TxOut) {
+		return nil, wrap.Wrap(fmt.Errorf("input #%d: previous output %s not found", idx, txIn.PreviousOutPoint))
+	}
+
+	if err = updater.AddInNonWitnessUtxo(prevTx, idx); err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	return prevTx.TxOut[outIndex], nil
+}
@@ -0,0 +1,28 @@
+package transaction_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
+)
+
+func TestParseSigHashType(t *testing.T) {
+	for name, want := range map[string]txscript.SigHashType{
+		"":                    txscript.SigHashAll,
+		"DEFAULT":             txscript.SigHashAll,
+		"ALL":                 txscript.SigHashAll,
+		"none":                txscript.SigHashNone,
+		"SINGLE|ANYONECANPAY": txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+	} {
+		if got, err := transaction.ParseSigHashType(name); err != nil || got != want {
+			t.Fatalf("%q is parsed as %#x, error %v, want %#x", name, got, err, want)
+		}
+	}
+
+	for _, name := range []string{"ANYONECANPAY", "ALL|NONE", "1"} {
+		if _, err := transaction.ParseSigHashType(name); err == nil {
+			t.Fatalf("invalid sighash %q is parsed", name)
+		}
+	}
+}
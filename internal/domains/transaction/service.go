@@ -2,18 +2,25 @@ package transaction
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
+	"sync"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
-	"github.com/go-resty/resty/v2"
 	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/metrics"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/utils"
 	"github.com/tatun2000/golang-lib/pkg/wrap"
 	"github.com/tyler-smith/go-bip32"
@@ -21,63 +28,75 @@ import (
 
 type (
 	IAddressService interface {
-		GetChildBIP32Key() (result *bip32.Key, err error)
-		RetrieveAddress() (result string, err error)
+		GetChildBIP32Key(ctx context.Context) (result *bip32.Key, err error)
+		RetrieveAddress(ctx context.Context) (result string, err error)
+	}
+
+	IEsploraClient interface {
+		GetTransaction(ctx context.Context, txID string) (result entities.Tx, err error)
+		GetTransactions(ctx context.Context, txIDs []string) (result map[string]entities.Tx, err error)
+		GetTransactionHex(ctx context.Context, txID string) (result string, err error)
+		Broadcast(ctx context.Context, hexTx string) (txID string, err error)
+	}
+
+	IAuditService interface {
+		Record(event entities.AuditEventType, data map[string]any) (err error)
 	}
 
 	Service struct {
-		addressService IAddressService
-		client         *resty.Client
+		addressService   IAddressService
+		esploraClient    IEsploraClient
+		auditService     IAuditService
+		transactionsPath string         // broadcasted transactions, one JSON per line
+		broadcasts       sync.WaitGroup // in-flight broadcasts
 	}
 )
 
-func NewService(addressService IAddressService) *Service {
+func NewService(addressService IAddressService, esploraClient IEsploraClient, auditService IAuditService, transactionsPath string) *Service {
 	return &Service{
-		addressService: addressService,
-		client: resty.New().
-			SetBaseURL("https://blockstream.info/testnet/api/"),
+		addressService:   addressService,
+		esploraClient:    esploraClient,
+		auditService:     auditService,
+		transactionsPath: transactionsPath,
 	}
 }
 
-func (s *Service) CreateNewTransaction(recepientAddress string, amount int64, txIDs ...string) (txID string, err error) {
-	prevTXs := make([]entities.Tx, 0, len(txIDs))
+// CreateNewTransaction builds and signs a transaction spending utxos of the wallet, it isn't broadcasted.
+// If subtractFee is set, the fee is taken from amount, so the recipient receives amount - fee.
+func (s *Service) CreateNewTransaction(ctx context.Context, recepientAddress string, amount int64, subtractFee bool, utxos ...entities.TxOutput) (preview entities.TxPreview, err error) {
+	spent := make([]entities.Vout, 0, len(utxos))
 
-	walletAddress, err := s.addressService.RetrieveAddress()
+	walletAddress, err := s.addressService.RetrieveAddress(ctx)
 	if err != nil {
-		return "", wrap.Wrap(err)
+		return preview, wrap.Wrap(err)
 	}
 
-	for _, txID := range txIDs {
-		var respTx entities.Tx
-		resp, err := s.client.R().
-			SetResult(&respTx).
-			Get(fmt.Sprintf("tx/%s", txID))
-		if err != nil {
-			return "", wrap.Wrap(err)
-		}
+	respTXs, err := s.esploraClient.GetTransactions(ctx, lo.Map(utxos, func(utxo entities.TxOutput, _ int) string { return utxo.TxID }))
+	if err != nil {
+		return preview, wrap.Wrap(err)
+	}
 
-		if resp.Error() != nil {
-			return "", wrap.Wrap(fmt.Errorf("%d %s: api error", resp.StatusCode(), resp.Status()))
+	for _, utxo := range utxos {
+		respTx, ok := respTXs[utxo.TxID]
+		if !ok {
+			return preview, wrap.Wrap(fmt.Errorf("transaction %s not found", utxo.TxID))
 		}
 
-		_, _, ok := lo.FindIndexOf(respTx.Vout, func(vout entities.Vout) bool {
-			return vout.ScriptPubKeyAddress == walletAddress
-		})
-		if !ok {
-			return "", wrap.Wrap(fmt.Errorf("current address %s not found", walletAddress))
+		if utxo.Vout < 0 || utxo.Vout >= len(respTx.Vout) || respTx.Vout[utxo.Vout].ScriptPubKeyAddress != walletAddress {
+			return preview, wrap.Wrap(fmt.Errorf("output %s:%d of current address %s not found", utxo.TxID, utxo.Vout, walletAddress))
 		}
 
-		prevTXs = append(prevTXs, respTx)
+		spent = append(spent, respTx.Vout[utxo.Vout])
 	}
 
-	wif, witness, err := s.generateWifAndWitnessAddress()
+	wif, witness, err := s.generateWifAndWitnessAddress(ctx)
 	if err != nil {
-		return "", wrap.Wrap(err)
+		return preview, wrap.Wrap(err)
 	}
 
 	senderPkScript, err := txscript.PayToAddrScript(witness)
 	if err != nil {
-		return "", wrap.Wrap(err)
+		return preview, wrap.Wrap(err)
 	}
 
 	// create new transaction
@@ -85,62 +104,61 @@ func (s *Service) CreateNewTransaction(recepientAddress string, amount int64, tx
 
 	// add inputs
 	var totalInputValue int64
-	for _, prevTX := range prevTXs {
-		prevOut, index, ok := lo.FindIndexOf(prevTX.Vout, func(vout entities.Vout) bool {
-			return vout.ScriptPubKeyAddress == walletAddress
-		})
-		if !ok {
-			return "", wrap.Wrap(fmt.Errorf("current address %s not found", walletAddress))
-		}
-		utxoHash, err := chainhash.NewHashFromStr(prevTX.TxID)
+	for idx, utxo := range utxos {
+		utxoHash, err := chainhash.NewHashFromStr(utxo.TxID)
 		if err != nil {
-			return "", wrap.Wrap(err)
+			return preview, wrap.Wrap(err)
 		}
-		outPoint := wire.NewOutPoint(utxoHash, uint32(index))
+		outPoint := wire.NewOutPoint(utxoHash, uint32(utxo.Vout))
 		txIn := wire.NewTxIn(outPoint, nil, nil)
 		tx.AddTxIn(txIn)
-		totalInputValue += prevOut.Value
+		totalInputValue += spent[idx].Value
 	}
 
-	fee := utils.CalculateFee(len(prevTXs), 2)
-	changeAmount := totalInputValue - amount - fee
-	if changeAmount < 0 {
-		return "", wrap.Wrap(fmt.Errorf("change amount %d is less than 0", changeAmount))
+	// the fee depends on the number of outputs, at first it's computed with change output
+	fee := utils.CalculateFee(len(spent), 2)
+	recepientAmount, changeAmount := amount, totalInputValue-amount-fee
+	if subtractFee {
+		recepientAmount, changeAmount = amount-fee, totalInputValue-amount
+	}
+	if changeAmount <= 0 || mempool.IsDust(wire.NewTxOut(changeAmount, senderPkScript), mempool.DefaultMinRelayTxFee) {
+		// there is no change output or it's dust which isn't relayed, the rest of inputs goes to the fee
+		fee = utils.CalculateFee(len(spent), 1)
+		if subtractFee {
+			recepientAmount = amount - fee
+		}
+		if rest := totalInputValue - recepientAmount - fee; rest < 0 {
+			return preview, wrap.Wrap(fmt.Errorf("change amount %d is less than 0", rest))
+		}
+		changeAmount = 0
+	}
+	if recepientAmount <= 0 {
+		return preview, wrap.Wrap(fmt.Errorf("amount %d doesn't cover fee %d", amount, fee))
 	}
 
 	recepient, err := btcutil.DecodeAddress(recepientAddress, &chaincfg.TestNet3Params)
 	if err != nil {
-		return "", wrap.Wrap(err)
+		return preview, wrap.Wrap(err)
 	}
 	pkScript, err := txscript.PayToAddrScript(recepient)
 	if err != nil {
-		return "", wrap.Wrap(err)
+		return preview, wrap.Wrap(err)
 	}
-	tx.AddTxOut(wire.NewTxOut(int64(amount), pkScript))
-	switch changeAmount {
-	case 0:
-		// one output
-	default:
-		// two outputs
+	recepientOut := wire.NewTxOut(recepientAmount, pkScript)
+	if mempool.IsDust(recepientOut, mempool.DefaultMinRelayTxFee) {
+		return preview, wrap.Wrap(fmt.Errorf("recepient amount %d is dust", recepientAmount))
+	}
+	tx.AddTxOut(recepientOut)
 
-		// add second output
-		// P2WPKH
-		changePkScript, err := txscript.PayToAddrScript(witness)
-		if err != nil {
-			return "", wrap.Wrap(err)
-		}
-		tx.AddTxOut(wire.NewTxOut(int64(changeAmount), changePkScript))
+	if changeAmount > 0 {
+		// P2WPKH change to the wallet address
+		tx.AddTxOut(wire.NewTxOut(changeAmount, senderPkScript))
 	}
 	// sign (P2WPKH)
 	sigHashes := txscript.NewTxSigHashes(tx)
 
-	for idx, prevTX := range prevTXs {
-		prevOut, _, ok := lo.FindIndexOf(prevTX.Vout, func(vout entities.Vout) bool {
-			return vout.ScriptPubKeyAddress == walletAddress
-		})
-		if !ok {
-			return "", wrap.Wrap(fmt.Errorf("current address %s not found", walletAddress))
-		}
+	prevOuts := make([]*wire.TxOut, 0, len(spent))
+	for idx, prevOut := range spent {
 		witnessScript, err := txscript.WitnessSignature(
 			tx,
 			sigHashes,
@@ -152,36 +170,224 @@ func (s *Service) CreateNewTransaction(recepientAddress string, amount int64, tx
 			true,
 		)
 		if err != nil {
-			return "", wrap.Wrap(err)
+			return preview, wrap.Wrap(err)
 		}
 		tx.TxIn[idx].Witness = witnessScript
+
+		// verify against the script from the chain, not the derived one, to catch wrong key
+		prevPkScript, err := hex.DecodeString(prevOut.ScriptPubKey)
+		if err != nil {
+			return preview, wrap.Wrap(err)
+		}
+		prevOuts = append(prevOuts, wire.NewTxOut(prevOut.Value, prevPkScript))
+	}
+
+	// check signatures locally, the backend only tells that transaction is invalid
+	if err = VerifyTransactionScripts(tx, prevOuts); err != nil {
+		return preview, wrap.Wrap(err)
 	}
 
 	var buf bytes.Buffer
-	tx.Serialize(&buf)
+	if err = tx.Serialize(&buf); err != nil {
+		return preview, wrap.Wrap(err)
+	}
+
+	preview = entities.TxPreview{
+		TxID:    tx.TxHash().String(),
+		VSize:   mempool.GetTxVirtualSize(btcutil.NewTx(tx)),
+		Fee:     totalInputValue - recepientAmount - changeAmount,
+		RawHex:  hex.EncodeToString(buf.Bytes()),
+		Inputs:  make([]entities.TxPreviewInput, 0, len(tx.TxIn)),
+		Outputs: make([]entities.TxPreviewOutput, 0, len(tx.TxOut)),
+	}
+	preview.FeeRate = float64(preview.Fee) / float64(preview.VSize)
+
+	for idx, txIn := range tx.TxIn {
+		preview.Inputs = append(preview.Inputs, entities.TxPreviewInput{
+			TxID:    txIn.PreviousOutPoint.Hash.String(),
+			Vout:    txIn.PreviousOutPoint.Index,
+			Address: walletAddress,
+			Value:   spent[idx].Value,
+		})
+	}
+
+	preview.Outputs = append(preview.Outputs, entities.TxPreviewOutput{
+		Address: recepientAddress,
+		Value:   recepientAmount,
+	})
+	if changeAmount > 0 {
+		preview.Outputs = append(preview.Outputs, entities.TxPreviewOutput{
+			Address:  walletAddress,
+			Value:    changeAmount,
+			IsChange: true,
+		})
+	}
 
-	hexTx := hex.EncodeToString(buf.Bytes())
-	resp, err := s.client.R().
-		SetHeader("Content-Type", "text/plain").
-		SetBody(hexTx).
-		Post("tx")
+	if err = s.auditService.Record(entities.AuditEventTransactionSigned, map[string]any{
+		"txid":      preview.TxID,
+		"recipient": recepientAddress,
+		"amount":    recepientAmount,
+		"change":    changeAmount,
+		"fee":       preview.Fee,
+	}); err != nil {
+		return preview, wrap.Wrap(err)
+	}
+
+	return preview, nil
+}
+
+// BroadcastTransaction validates raw transaction in hex and its input scripts against previous outputs
+// from the backend, sends it into testnet and returns its ID.
+// Sending isn't canceled with ctx, the backend can accept transaction before the answer is received,
+// so the result must be known. It is limited by constants.BroadcastTimeout instead.
+func (s *Service) BroadcastTransaction(ctx context.Context, hexTx string) (txID string, err error) {
+	s.broadcasts.Add(1)
+	defer s.broadcasts.Done()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constants.BroadcastTimeout)
+	defer cancel()
+
+	tx, err := ParseTransaction(hexTx)
 	if err != nil {
 		return "", wrap.Wrap(err)
 	}
 
-	if resp.Error() != nil {
-		return "", wrap.Wrap(fmt.Errorf("%d %s: api error", resp.StatusCode(), resp.Status()))
+	if err = ValidateTransaction(tx); err != nil {
+		return "", wrap.Wrap(err)
 	}
 
-	if resp.StatusCode() != 200 {
-		return "", wrap.Wrap(fmt.Errorf("transaction error: %s", string(resp.Body())))
+	prevOuts, err := s.prevOuts(ctx, tx)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	if err = VerifyTransactionScripts(tx, prevOuts); err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	txID, err = s.esploraClient.Broadcast(ctx, hexTx)
+	s.recordBroadcast(tx.TxHash().String(), err)
+	if err != nil {
+		metrics.Broadcasts.WithLabelValues(metrics.BroadcastFailure).Inc()
+		return "", wrap.Wrap(err)
+	}
+	metrics.Broadcasts.WithLabelValues(metrics.BroadcastSuccess).Inc()
+
+	if expectedTxID := tx.TxHash().String(); txID != expectedTxID {
+		return "", wrap.Wrap(fmt.Errorf("backend returned txid %s, expected %s", txID, expectedTxID))
+	}
+
+	return txID, nil
+}
+
+// prevOuts returns previous outputs of transaction inputs in their order.
+func (s *Service) prevOuts(ctx context.Context, tx *wire.MsgTx) (result []*wire.TxOut, err error) {
+	txIDs := lo.Uniq(lo.Map(tx.TxIn, func(txIn *wire.TxIn, _ int) string { return txIn.PreviousOutPoint.Hash.String() }))
+
+	prevTXs, err := s.esploraClient.GetTransactions(ctx, txIDs)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	result = make([]*wire.TxOut, 0, len(tx.TxIn))
+	for idx, txIn := range tx.TxIn {
+		prevTX, ok := prevTXs[txIn.PreviousOutPoint.Hash.String()]
+		if !ok || int(txIn.PreviousOutPoint.Index) >= len(prevTX.Vout) {
+			return result, wrap.Wrap(fmt.Errorf("input #%d: previous output %s not found", idx, txIn.PreviousOutPoint))
+		}
+
+		prevOut := prevTX.Vout[txIn.PreviousOutPoint.Index]
+		pkScript, err := hex.DecodeString(prevOut.ScriptPubKey)
+		if err != nil {
+			return result, wrap.Wrap(err)
+		}
+		result = append(result, wire.NewTxOut(prevOut.Value, pkScript))
+	}
+
+	return result, nil
+}
+
+// WaitBroadcasts waits until in-flight broadcasts are finished or ctx is done.
+func (s *Service) WaitBroadcasts(ctx context.Context) (err error) {
+	done := make(chan struct{})
+	go func() {
+		s.broadcasts.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return wrap.Wrap(ctx.Err())
+	}
+}
+
+// recordBroadcast writes broadcast result into the audit log, the result is already known
+// to the backend, so audit error is only logged.
+func (s *Service) recordBroadcast(txID string, broadcastErr error) {
+	data := map[string]any{
+		"txid":    txID,
+		"success": broadcastErr == nil,
+	}
+	if broadcastErr != nil {
+		data["error"] = broadcastErr.Error()
+	}
+
+	if err := s.auditService.Record(entities.AuditEventBroadcast, data); err != nil {
+		slog.Error("audit record isn't written", "event", entities.AuditEventBroadcast, "txid", txID, "error", err)
+	}
+}
+
+// SaveBroadcastedTransaction appends the record to the wallet transactions file.
+func (s *Service) SaveBroadcastedTransaction(record entities.BroadcastedTx) (err error) {
+	file, err := os.OpenFile(s.transactionsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+	defer file.Close()
+
+	if err = json.NewEncoder(file).Encode(record); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+// RetrieveBroadcastedTransactions reads all records from the wallet transactions file.
+func (s *Service) RetrieveBroadcastedTransactions() (result []entities.BroadcastedTx, err error) {
+	file, err := os.Open(s.transactionsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, wrap.Wrap(err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var record entities.BroadcastedTx
+		if err = decoder.Decode(&record); err != nil {
+			return result, wrap.Wrap(err)
+		}
+		result = append(result, record)
+	}
+
+	return result, nil
+}
+
+// ClearBroadcastedTransactions removes all records, e.g. when the wallet is replaced by another one.
+func (s *Service) ClearBroadcastedTransactions() (err error) {
+	if err = os.Remove(s.transactionsPath); err != nil && !os.IsNotExist(err) {
+		return wrap.Wrap(err)
 	}
 
-	return string(resp.Body()), nil
+	return nil
 }
 
-func (s *Service) generateWifAndWitnessAddress() (wif *btcutil.WIF, witness *btcutil.AddressWitnessPubKeyHash, err error) {
-	rawKey, err := s.addressService.GetChildBIP32Key()
+func (s *Service) generateWifAndWitnessAddress(ctx context.Context) (wif *btcutil.WIF, witness *btcutil.AddressWitnessPubKeyHash, err error) {
+	rawKey, err := s.addressService.GetChildBIP32Key(ctx)
 	if err != nil {
 		return nil, nil, wrap.Wrap(err)
 	}
@@ -0,0 +1,73 @@
+package transaction
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// ValidateTransaction checks transaction by context-free consensus rules and standardness policy
+// which doesn't need previous outputs. Signatures are checked by VerifyTransactionScripts.
+func ValidateTransaction(tx *wire.MsgTx) (err error) {
+	if err = blockchain.CheckTransactionSanity(btcutil.NewTx(tx)); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	if weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx)); weight > constants.MaxStandardTxWeight {
+		return wrap.Wrap(fmt.Errorf("transaction weight %d is larger than max allowed weight %d", weight, constants.MaxStandardTxWeight))
+	}
+
+	for idx, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) == 0 && len(txIn.Witness) == 0 {
+			return wrap.Wrap(fmt.Errorf("input #%d isn't signed", idx))
+		}
+	}
+
+	for idx, txOut := range tx.TxOut {
+		// OP_RETURN outputs are unspendable, zero value is standard for them
+		if txscript.GetScriptClass(txOut.PkScript) == txscript.NullDataTy {
+			continue
+		}
+		if mempool.IsDust(txOut, mempool.DefaultMinRelayTxFee) {
+			return wrap.Wrap(fmt.Errorf("output #%d with %d satoshi is dust", idx, txOut.Value))
+		}
+	}
+
+	return nil
+}
+
+// VerifyTransactionScripts executes scripts of every input against its previous output.
+// prevOuts must be in the order of transaction inputs.
+func VerifyTransactionScripts(tx *wire.MsgTx, prevOuts []*wire.TxOut) (err error) {
+	if len(prevOuts) != len(tx.TxIn) {
+		return wrap.Wrap(fmt.Errorf("%d previous outputs for %d inputs", len(prevOuts), len(tx.TxIn)))
+	}
+
+	sigHashes := txscript.NewTxSigHashes(tx)
+	for idx, txIn := range tx.TxIn {
+		engine, err := txscript.NewEngine(
+			prevOuts[idx].PkScript,
+			tx,
+			idx,
+			txscript.StandardVerifyFlags,
+			nil,
+			sigHashes,
+			prevOuts[idx].Value,
+		)
+		if err != nil {
+			return wrap.Wrap(fmt.Errorf("input #%d (%s): %w", idx, txIn.PreviousOutPoint, err))
+		}
+
+		if err = engine.Execute(); err != nil {
+			return wrap.Wrap(fmt.Errorf("input #%d (%s) script verification failed: %w", idx, txIn.PreviousOutPoint, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package transaction_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
+)
+
+// signedTx returns transaction which spends P2WPKH output prevOut, it pays value to the same script
+// and has extra outputs.
+func signedTx(t *testing.T, value int64, extra ...*wire.TxOut) (tx *wire.MsgTx, prevOut *wire.TxOut) {
+	t.Helper()
+
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	address, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(privKey.PubKey().SerializeCompressed()), &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("create address: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatalf("create script: %v", err)
+	}
+	prevOut = wire.NewTxOut(100_000, pkScript)
+
+	tx = wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(value, pkScript))
+	for _, txOut := range extra {
+		tx.AddTxOut(txOut)
+	}
+
+	tx.TxIn[0].Witness, err = txscript.WitnessSignature(tx, txscript.NewTxSigHashes(tx), 0, prevOut.Value, pkScript, txscript.SigHashAll, privKey, true)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return tx, prevOut
+}
+
+func TestValidateTransaction(t *testing.T) {
+	t.Run("OP_RETURN output isn't dust", func(t *testing.T) {
+		nullData, err := txscript.NullDataScript([]byte("wallet"))
+		if err != nil {
+			t.Fatalf("create script: %v", err)
+		}
+		tx, _ := signedTx(t, 90_000, wire.NewTxOut(0, nullData))
+
+		if err = transaction.ValidateTransaction(tx); err != nil {
+			t.Fatalf("validate: %v", err)
+		}
+	})
+
+	t.Run("dust output is rejected", func(t *testing.T) {
+		tx, _ := signedTx(t, 100)
+
+		if err := transaction.ValidateTransaction(tx); err == nil {
+			t.Fatal("transaction with dust output is valid")
+		}
+	})
+}
+
+func TestVerifyTransactionScripts(t *testing.T) {
+	tx, prevOut := signedTx(t, 90_000)
+
+	if err := transaction.VerifyTransactionScripts(tx, []*wire.TxOut{prevOut}); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// segwit signature commits to the spent value
+	if err := transaction.VerifyTransactionScripts(tx, []*wire.TxOut{wire.NewTxOut(prevOut.Value+1, prevOut.PkScript)}); err == nil {
+		t.Fatal("signature is valid for another previous output value")
+	}
+
+	// garbage witness passes the context-free check, but not script execution
+	tx.TxIn[0].Witness = wire.TxWitness{{0x01}, {0x02}}
+	if err := transaction.ValidateTransaction(tx); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if err := transaction.VerifyTransactionScripts(tx, []*wire.TxOut{prevOut}); err == nil {
+		t.Fatal("garbage witness is valid")
+	}
+}
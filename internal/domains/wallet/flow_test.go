@@ -0,0 +1,183 @@
+package wallet_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/simulator"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/wallet"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+// TestReceiveSendReplaceReorg runs the wallet against chain simulator without network:
+// receive -> confirm -> send -> RBF -> confirm -> reorg -> confirm again.
+func TestReceiveSendReplaceReorg(t *testing.T) {
+	ctx := context.Background()
+	chain := simulator.NewChain()
+	service := newClientWallet(t, chain, t.TempDir())
+
+	walletAddress, err := service.GetWalletAddress(ctx)
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+
+	// receive
+	fundingTxID, err := chain.Fund(walletAddress, 100_000)
+	if err != nil {
+		t.Fatalf("fund wallet: %v", err)
+	}
+	checkBalance(t, service, 0, 100_000)
+	checkHistory(t, service, fundingTxID, entities.TxStateMempool, 0)
+
+	// confirm
+	chain.Mine()
+	checkBalance(t, service, 100_000, 0)
+	checkHistory(t, service, fundingTxID, entities.TxStateConfirmed, 1)
+
+	// send
+	preview, err := service.PrepareSend(ctx, recipientAddress, 30_000, false)
+	if err != nil {
+		t.Fatalf("prepare send: %v", err)
+	}
+	sentTxID, err := service.Broadcast(ctx, preview)
+	if err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+	change := 100_000 - 30_000 - preview.Fee
+	checkBalance(t, service, 0, change)
+	checkHistory(t, service, sentTxID, entities.TxStateMempool, 0)
+
+	// replacement must pay min relay fee for its own size above the replaced fee
+	if _, err = chain.Broadcast(ctx, replacement(t, service, chain, sentTxID, 10)); !isRejected(err) {
+		t.Fatalf("replacement with small fee bump: error %v, want rejection", err)
+	}
+	replacementTxID, err := chain.Broadcast(ctx, replacement(t, service, chain, sentTxID, 1_000))
+	if err != nil {
+		t.Fatalf("broadcast replacement: %v", err)
+	}
+	if mempool := chain.Mempool(); len(mempool) != 1 || mempool[0] != replacementTxID {
+		t.Fatalf("mempool is %v, want only replacement %s", mempool, replacementTxID)
+	}
+	checkBalance(t, service, 0, change-1_000)
+	replaced := checkHistory(t, service, sentTxID, entities.TxStateReplaced, 0)
+	if replaced.ReplacedBy != replacementTxID {
+		t.Fatalf("transaction is replaced by %s, want %s", replaced.ReplacedBy, replacementTxID)
+	}
+
+	// confirm
+	chain.Mine()
+	chain.Mine()
+	checkBalance(t, service, change-1_000, 0)
+	checkHistory(t, service, replacementTxID, entities.TxStateConfirmed, 2)
+
+	// reorg returns transactions of disconnected blocks into mempool
+	if err = chain.Reorg(2); err != nil {
+		t.Fatalf("reorg: %v", err)
+	}
+	checkBalance(t, service, 0, change-1_000)
+	checkHistory(t, service, replacementTxID, entities.TxStateMempool, 0)
+	checkHistory(t, service, fundingTxID, entities.TxStateConfirmed, 4)
+
+	// confirm in the new branch
+	chain.Mine()
+	checkBalance(t, service, change-1_000, 0)
+	checkHistory(t, service, replacementTxID, entities.TxStateConfirmed, 1)
+}
+
+// replacement re-signs transaction txID by the wallet, its change is reduced by feeBump.
+func replacement(t *testing.T, service *wallet.Service, chain *simulator.Chain, txID string, feeBump int64) (hexTx string) {
+	t.Helper()
+
+	original, ok := chain.Transaction(txID)
+	if !ok {
+		t.Fatalf("transaction %s not found", txID)
+	}
+
+	tx := wire.NewMsgTx(original.Version)
+	for _, txIn := range original.TxIn {
+		replacementIn := wire.NewTxIn(&txIn.PreviousOutPoint, nil, nil)
+		replacementIn.Sequence = wire.MaxTxInSequenceNum - 2 // BIP125 signalling
+		tx.AddTxIn(replacementIn)
+	}
+	for _, txOut := range original.TxOut {
+		tx.AddTxOut(wire.NewTxOut(txOut.Value, txOut.PkScript))
+	}
+	tx.TxOut[len(tx.TxOut)-1].Value -= feeBump // the last output is change
+
+	packet, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		t.Fatalf("create PSBT: %v", err)
+	}
+	unsigned, err := packet.B64Encode()
+	if err != nil {
+		t.Fatalf("encode PSBT: %v", err)
+	}
+
+	signed, _, complete, err := service.SignPSBT(context.Background(), unsigned, txscript.SigHashAll, true)
+	if err != nil || !complete {
+		t.Fatalf("sign PSBT: complete %t, error %v", complete, err)
+	}
+
+	if packet, err = psbt.NewFromRawBytes(strings.NewReader(signed), true); err != nil {
+		t.Fatalf("decode PSBT: %v", err)
+	}
+	if tx, err = psbt.Extract(packet); err != nil {
+		t.Fatalf("extract transaction: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err = tx.Serialize(&buf); err != nil {
+		t.Fatalf("serialize transaction: %v", err)
+	}
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func checkBalance(t *testing.T, service *wallet.Service, wantConfirmed, wantUnconfirmed int64) {
+	t.Helper()
+
+	confirmed, unconfirmed, err := service.GetWalletBalance(context.Background())
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	if confirmed != wantConfirmed || unconfirmed != wantUnconfirmed {
+		t.Fatalf("balance is %d/%d, want %d/%d", confirmed, unconfirmed, wantConfirmed, wantUnconfirmed)
+	}
+}
+
+func checkHistory(t *testing.T, service *wallet.Service, txID string, state entities.TxState, confirmations int) entities.HistoryEntry {
+	t.Helper()
+
+	history, err := service.GetHistory(context.Background())
+	if err != nil {
+		t.Fatalf("get history: %v", err)
+	}
+
+	for _, entry := range history {
+		if entry.TxID != txID {
+			continue
+		}
+		if entry.State != state || entry.Confirmations != confirmations {
+			t.Fatalf("transaction %s is %s with %d confirmations, want %s with %d", txID, entry.State, entry.Confirmations, state, confirmations)
+		}
+		return entry
+	}
+
+	t.Fatalf("transaction %s isn't in history", txID)
+
+	return entities.HistoryEntry{}
+}
+
+func isRejected(err error) bool {
+	var rejectErr *esplora.RejectError
+
+	return errors.As(err, &rejectErr)
+}
@@ -1,43 +1,87 @@
 package wallet
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
 
-	"github.com/go-resty/resty/v2"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
 	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/metrics"
 	"github.com/tatun2000/golang-lib/pkg/wrap"
 )
 
+var (
+	// ErrInsufficientFunds is returned when confirmed balance is less than sending amount.
+	ErrInsufficientFunds = errors.New("insufficient available balance")
+	// ErrTransactionNotFound is returned when the backend doesn't know transaction, e.g. it isn't propagated yet.
+	ErrTransactionNotFound = errors.New("transaction isn't found: it is neither in mempool nor in the chain")
+	// ErrTransactionDropped is returned when transaction disappeared or isn't found for a long time.
+	ErrTransactionDropped = errors.New("transaction is dropped: it is neither in mempool nor in the chain")
+)
+
 type (
 	IAddressService interface {
-		RetrieveAddress() (result string, err error)
+		RetrieveAddress(ctx context.Context) (result string, err error)
 	}
 
 	ITransactionService interface {
-		CreateNewTransaction(recepientAddress string, amount int64, txIDs ...string) (txID string, err error)
+		CreateNewTransaction(ctx context.Context, recepientAddress string, amount int64, subtractFee bool, utxos ...entities.TxOutput) (preview entities.TxPreview, err error)
+		BroadcastTransaction(ctx context.Context, hexTx string) (txID string, err error)
+		SaveBroadcastedTransaction(record entities.BroadcastedTx) (err error)
+		RetrieveBroadcastedTransactions() (result []entities.BroadcastedTx, err error)
+		SignPSBT(ctx context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (result string, signed int, complete bool, err error)
+	}
+
+	IEsploraClient interface {
+		GetTransactionHex(ctx context.Context, txID string) (result string, err error)
+		GetTransactionStatus(ctx context.Context, txID string) (result entities.TxStatus, err error)
+		GetOutspend(ctx context.Context, txID string, vout uint32) (result entities.Outspend, err error)
+		GetAddressUTXOs(ctx context.Context, address string) (result entities.TxOutputs, err error)
+		GetAddressTransactions(ctx context.Context, address string) (result []entities.Tx, err error)
+		GetTipHeight(ctx context.Context) (result int, err error)
+	}
+
+	IAuditService interface {
+		Record(event entities.AuditEventType, data map[string]any) (err error)
 	}
 
 	Service struct {
 		addressService     IAddressService
 		transactionService ITransactionService
-		client             *resty.Client
+		esploraClient      IEsploraClient
+		auditService       IAuditService
+		broadcasts         sync.WaitGroup // in-flight broadcasts with saving
+
+		sendMu   sync.Mutex           // concurrent sends don't select the same UTXOs
+		reserved map[string]time.Time // "txid:vout" of spent UTXOs -> reservation expiry
 	}
 )
 
-func NewService(addressService IAddressService, transactionService ITransactionService) *Service {
+func NewService(addressService IAddressService, transactionService ITransactionService, esploraClient IEsploraClient, auditService IAuditService) *Service {
 	s := &Service{
 		addressService:     addressService,
 		transactionService: transactionService,
-		client: resty.New().
-			SetBaseURL("https://blockstream.info/testnet/api/"),
+		esploraClient:      esploraClient,
+		auditService:       auditService,
+		reserved:           make(map[string]time.Time),
 	}
 
 	return s
 }
 
-func (s *Service) GetWalletAddress() (result string, err error) {
-	result, err = s.addressService.RetrieveAddress()
+func (s *Service) GetWalletAddress(ctx context.Context) (result string, err error) {
+	result, err = s.addressService.RetrieveAddress(ctx)
 	if err != nil {
 		return result, wrap.Wrap(err)
 	}
@@ -45,97 +89,424 @@ func (s *Service) GetWalletAddress() (result string, err error) {
 	return result, nil
 }
 
-func (s *Service) GetWalletBalance() (confirmed, unconfirmed int64, err error) {
-	address, err := s.GetWalletAddress()
+func (s *Service) GetWalletBalance(ctx context.Context) (confirmed, unconfirmed int64, err error) {
+	utxos, err := s.GetWalletUTXOs(ctx)
 	if err != nil {
 		return confirmed, unconfirmed, wrap.Wrap(err)
 	}
 
-	txs, err := s.getConfirmedUTXOTransactions(address)
+	confirmed = lo.SumBy(confirmedUTXOs(utxos), func(vout entities.TxOutput) int64 { return vout.Value })
+	unconfirmed = lo.SumBy(utxos, func(vout entities.TxOutput) int64 { return vout.Value }) - confirmed
+
+	return confirmed, unconfirmed, nil
+}
+
+// GetWalletUTXOs returns confirmed and unconfirmed wallet UTXOs.
+func (s *Service) GetWalletUTXOs(ctx context.Context) (result entities.TxOutputs, err error) {
+	address, err := s.GetWalletAddress(ctx)
 	if err != nil {
-		return confirmed, unconfirmed, wrap.Wrap(err)
+		return result, wrap.Wrap(err)
 	}
 
-	confirmed = lo.SumBy(txs, func(vout entities.TxOutput) int64 { return vout.Value })
+	result, err = s.esploraClient.GetAddressUTXOs(ctx, address)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
+}
 
-	txs, err = s.getUnconfirmedUTXOTransactions(address)
+// GetTipHeight returns height of the best block.
+func (s *Service) GetTipHeight(ctx context.Context) (result int, err error) {
+	result, err = s.esploraClient.GetTipHeight(ctx)
 	if err != nil {
-		return confirmed, unconfirmed, wrap.Wrap(err)
+		return result, wrap.Wrap(err)
 	}
 
-	unconfirmed = lo.SumBy(txs, func(vout entities.TxOutput) int64 { return vout.Value })
+	return result, nil
+}
 
-	return confirmed, unconfirmed, nil
+// GetTransactionHex returns raw transaction in hex.
+func (s *Service) GetTransactionHex(ctx context.Context, txID string) (result string, err error) {
+	result, err = s.esploraClient.GetTransactionHex(ctx, txID)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return result, nil
 }
 
-func (s *Service) SendTo(address string, amount int64) (txid string, err error) {
-	balance, _, err := s.GetWalletBalance()
+// GetConfirmations returns number of transaction confirmations, 0 if transaction is in mempool.
+func (s *Service) GetConfirmations(ctx context.Context, txID string) (result int, err error) {
+	status, err := s.esploraClient.GetTransactionStatus(ctx, txID)
 	if err != nil {
-		return "", wrap.Wrap(err)
+		if errors.Is(err, esplora.ErrNotFound) {
+			return result, wrap.Wrap(ErrTransactionNotFound)
+		}
+		return result, wrap.Wrap(err)
 	}
 
-	if balance < amount {
-		return "", wrap.Wrap(fmt.Errorf("insufficient available balance"))
+	if !status.Confirmed {
+		return 0, nil
 	}
 
-	walletAddress, err := s.GetWalletAddress()
+	tipHeight, err := s.esploraClient.GetTipHeight(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	return tipHeight - status.BlockHeight + 1, nil
+}
+
+// WaitConfirmations polls the backend every interval until transaction has required confirmations or ctx is done.
+// progress is called when the number of confirmations is changed, backend errors are logged and polling goes on.
+// Just broadcasted transaction can be unknown to the backend, so it is dropped if it disappeared after it was seen
+// or it isn't found constants.WaitNotFoundPolls times in a row.
+func (s *Service) WaitConfirmations(ctx context.Context, txID string, confirmations int, interval time.Duration, progress func(current int)) (err error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, current, notFound := -1, 0, 0
+	for {
+		current, err = s.GetConfirmations(ctx, txID)
+		if !errors.Is(err, ErrTransactionNotFound) {
+			notFound = 0
+		}
+
+		switch {
+		case errors.Is(err, ErrTransactionNotFound):
+			notFound++
+			if last >= 0 || notFound >= constants.WaitNotFoundPolls {
+				return wrap.Wrap(ErrTransactionDropped)
+			}
+			slog.Warn("transaction isn't found yet", "txid", txID, "attempt", notFound)
+		case err != nil:
+			slog.Warn("transaction status isn't received", "txid", txID, "error", err)
+		default:
+			if current != last {
+				last = current
+				progress(current)
+			}
+			if current >= confirmations {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return wrap.Wrap(fmt.Errorf("transaction has %d of %d confirmations: %w", max(last, 0), confirmations, ctx.Err()))
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendTo sends amount satoshi to address from confirmed UTXOs.
+// If subtractFee is set, the fee is paid by the recipient.
+func (s *Service) SendTo(ctx context.Context, address string, amount int64, subtractFee bool) (txid string, err error) {
+	preview, err := s.PrepareSend(ctx, address, amount, subtractFee)
 	if err != nil {
 		return "", wrap.Wrap(err)
 	}
 
-	txs, err := s.getConfirmedUTXOTransactions(walletAddress)
+	txid, err = s.Broadcast(ctx, preview)
 	if err != nil {
 		return "", wrap.Wrap(err)
 	}
 
+	return txid, nil
+}
+
+// PrepareSend selects confirmed UTXOs and builds signed transaction without broadcasting it.
+// Selected UTXOs are reserved: concurrent sends don't spend them until Broadcast fails, Release is called
+// or constants.SendReservationTTL is passed.
+func (s *Service) PrepareSend(ctx context.Context, address string, amount int64, subtractFee bool) (preview entities.TxPreview, err error) {
+	if err = s.auditService.Record(entities.AuditEventSendAttempt, map[string]any{
+		"address":      address,
+		"amount":       amount,
+		"subtract_fee": subtractFee,
+	}); err != nil {
+		return preview, wrap.Wrap(err)
+	}
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	utxos, err := s.GetWalletUTXOs(ctx)
+	if err != nil {
+		return preview, wrap.Wrap(err)
+	}
+
+	now := time.Now()
+	for key, expiry := range s.reserved {
+		if now.After(expiry) {
+			delete(s.reserved, key)
+		}
+	}
+
+	available := lo.Filter(confirmedUTXOs(utxos), func(utxo entities.TxOutput, _ int) bool {
+		_, ok := s.reserved[outPoint(utxo.TxID, utxo.Vout)]
+		return !ok
+	})
+	if balance := lo.SumBy(available, func(utxo entities.TxOutput) int64 { return utxo.Value }); balance < amount {
+		return preview, wrap.Wrap(ErrInsufficientFunds)
+	}
+
 	var (
 		necessarySum int64
-		txIDs        []string
+		selected     []entities.TxOutput
 	)
-	for _, tx := range txs {
-		txIDs = append(txIDs, tx.TxID)
-		necessarySum += tx.Value
+	for _, utxo := range available {
+		selected = append(selected, utxo)
+		necessarySum += utxo.Value
 		if necessarySum >= amount {
 			break
 		}
 	}
 
-	txid, err = s.transactionService.CreateNewTransaction(address, amount, txIDs...)
+	preview, err = s.transactionService.CreateNewTransaction(ctx, address, amount, subtractFee, selected...)
 	if err != nil {
+		return preview, wrap.Wrap(err)
+	}
+
+	for _, input := range preview.Inputs {
+		s.reserved[outPoint(input.TxID, int(input.Vout))] = now.Add(constants.SendReservationTTL)
+	}
+
+	return preview, nil
+}
+
+// Release returns UTXOs of transaction which won't be broadcasted, e.g. dry run, to the next sends.
+func (s *Service) Release(preview entities.TxPreview) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	for _, input := range preview.Inputs {
+		delete(s.reserved, outPoint(input.TxID, int(input.Vout)))
+	}
+}
+
+// outPoint returns reservation key of UTXO.
+func outPoint(txID string, vout int) string {
+	return fmt.Sprintf("%s:%d", txID, vout)
+}
+
+// Broadcast sends transaction prepared by PrepareSend and saves it into wallet transactions.
+// Like sending, saving isn't canceled with ctx: broadcasted transaction must be saved.
+func (s *Service) Broadcast(ctx context.Context, preview entities.TxPreview) (txid string, err error) {
+	s.broadcasts.Add(1)
+	defer s.broadcasts.Done()
+
+	ctx = context.WithoutCancel(ctx)
+
+	// on success UTXOs stay reserved: the backend can return them until it sees the transaction
+	txid, err = s.transactionService.BroadcastTransaction(ctx, preview.RawHex)
+	if err != nil {
+		s.Release(preview)
 		return "", wrap.Wrap(err)
 	}
+	metrics.FeesPaid.Add(float64(preview.Fee))
+
+	walletAddress, err := s.GetWalletAddress(ctx)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	amount := -lo.SumBy(preview.Inputs, func(input entities.TxPreviewInput) int64 { return input.Value })
+	for _, output := range preview.Outputs {
+		if output.Address == walletAddress {
+			amount += output.Value
+		}
+	}
+
+	// transaction is already in mempool, so saving error mustn't fail sending
+	if err := s.transactionService.SaveBroadcastedTransaction(entities.BroadcastedTx{
+		TxID:   txid,
+		RawHex: preview.RawHex,
+		Amount: amount,
+		Fee:    preview.Fee,
+		Time:   time.Now().Unix(),
+	}); err != nil {
+		slog.Error("broadcasted transaction isn't saved", "txid", txid, "error", err)
+	}
+
+	slog.Info("transaction is broadcasted", "txid", txid, "fee", preview.Fee, "amount", amount)
 
 	return txid, nil
 }
 
-func (s *Service) getConfirmedUTXOTransactions(address string) (confirmedUTXOs []entities.TxOutput, err error) {
-	var respUTXOs entities.TxOutputs
-	resp, err := s.client.R().
-		SetResult(&respUTXOs).
-		Get(fmt.Sprintf("address/%s/utxo", address))
+// WaitBroadcasts waits until in-flight broadcasts are finished and saved or ctx is done.
+func (s *Service) WaitBroadcasts(ctx context.Context) (err error) {
+	done := make(chan struct{})
+	go func() {
+		s.broadcasts.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return wrap.Wrap(ctx.Err())
+	}
+}
+
+// SignPSBT signs PSBT inputs which spend wallet outputs.
+func (s *Service) SignPSBT(ctx context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (result string, signed int, complete bool, err error) {
+	result, signed, complete, err = s.transactionService.SignPSBT(ctx, b64PSBT, sigHashType, finalize)
 	if err != nil {
-		return confirmedUTXOs, wrap.Wrap(err)
+		return "", 0, false, wrap.Wrap(err)
+	}
+
+	return result, signed, complete, nil
+}
+
+// GetHistory returns all wallet transactions, newest first.
+// Own transactions which disappeared from the backend are marked as replaced or dropped.
+func (s *Service) GetHistory(ctx context.Context) (result []entities.HistoryEntry, err error) {
+	walletAddress, err := s.GetWalletAddress(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	tipHeight, err := s.esploraClient.GetTipHeight(ctx)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	txs, err := s.esploraClient.GetAddressTransactions(ctx, walletAddress)
+	if err != nil {
+		return result, wrap.Wrap(err)
+	}
+
+	broadcasted, err := s.transactionService.RetrieveBroadcastedTransactions()
+	if err != nil {
+		return result, wrap.Wrap(err)
 	}
+	result = make([]entities.HistoryEntry, 0, len(txs))
+	broadcastTimes := lo.SliceToMap(broadcasted, func(record entities.BroadcastedTx) (string, int64) {
+		return record.TxID, record.Time
+	})
 
-	if resp.Error() != nil {
-		return confirmedUTXOs, wrap.Wrap(fmt.Errorf("%d %s: api error", resp.StatusCode(), resp.Status()))
+	for _, tx := range txs {
+		entry := newHistoryEntry(tx, walletAddress, tipHeight)
+		if entry.Time == 0 {
+			entry.Time = broadcastTimes[tx.TxID]
+		}
+		result = append(result, entry)
+	}
+
+	known := lo.SliceToMap(txs, func(tx entities.Tx) (string, struct{}) { return tx.TxID, struct{}{} })
+	for _, record := range broadcasted {
+		if _, ok := known[record.TxID]; ok {
+			continue
+		}
+
+		entry, err := s.newMissingHistoryEntry(ctx, record)
+		if err != nil {
+			return result, wrap.Wrap(err)
+		}
+		if entry == nil {
+			continue
+		}
+		result = append(result, *entry)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		// mempool transactions have no block time, they are the newest
+		if (result[i].State == entities.TxStateMempool) != (result[j].State == entities.TxStateMempool) {
+			return result[i].State == entities.TxStateMempool
+		}
+		return result[i].Time > result[j].Time
+	})
+
+	return result, nil
+}
+
+func newHistoryEntry(tx entities.Tx, walletAddress string, tipHeight int) entities.HistoryEntry {
+	var received, spent int64
+	for _, vout := range tx.Vout {
+		if vout.ScriptPubKeyAddress == walletAddress {
+			received += vout.Value
+		}
+	}
+	for _, vin := range tx.Vin {
+		if vin.Prevout != nil && vin.Prevout.ScriptPubKeyAddress == walletAddress {
+			spent += vin.Prevout.Value
+		}
 	}
 
-	return lo.Filter(respUTXOs, func(vout entities.TxOutput, _ int) bool { return vout.Status.Confirmed }), nil
+	entry := entities.HistoryEntry{
+		TxID:   tx.TxID,
+		Amount: received - spent,
+		Fee:    tx.Fee,
+		State:  entities.TxStateMempool,
+	}
+
+	switch {
+	case spent == 0:
+		entry.Direction = entities.TxDirectionIncoming
+	case lo.EveryBy(tx.Vout, func(vout entities.Vout) bool { return vout.ScriptPubKeyAddress == walletAddress }):
+		entry.Direction = entities.TxDirectionSelfTransfer
+	default:
+		entry.Direction = entities.TxDirectionOutgoing
+	}
+
+	if tx.Status.Confirmed {
+		entry.State = entities.TxStateConfirmed
+		entry.Time = tx.Status.BlockTime
+		entry.Confirmations = tipHeight - tx.Status.BlockHeight + 1
+	}
+
+	return entry
 }
 
-func (s *Service) getUnconfirmedUTXOTransactions(address string) (confirmedUTXOs []entities.TxOutput, err error) {
-	var respUTXOs entities.TxOutputs
-	resp, err := s.client.R().
-		SetResult(&respUTXOs).
-		Get(fmt.Sprintf("address/%s/utxo", address))
+// newMissingHistoryEntry checks own transaction which isn't returned in address transactions.
+// It returns nil if the backend knows transaction (address index can be behind).
+func (s *Service) newMissingHistoryEntry(ctx context.Context, record entities.BroadcastedTx) (entry *entities.HistoryEntry, err error) {
+	if _, err = s.esploraClient.GetTransactionStatus(ctx, record.TxID); err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, esplora.ErrNotFound) {
+		return nil, wrap.Wrap(err)
+	}
+
+	entry = &entities.HistoryEntry{
+		TxID:      record.TxID,
+		Time:      record.Time,
+		Amount:    record.Amount,
+		Direction: entities.TxDirectionOutgoing,
+		Fee:       record.Fee,
+		State:     entities.TxStateDropped,
+	}
+	if record.Amount == -record.Fee {
+		entry.Direction = entities.TxDirectionSelfTransfer
+	}
+
+	rawTx, err := hex.DecodeString(record.RawHex)
 	if err != nil {
-		return confirmedUTXOs, wrap.Wrap(err)
+		return nil, wrap.Wrap(err)
+	}
+	var tx wire.MsgTx
+	if err = tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, wrap.Wrap(err)
 	}
 
-	if resp.Error() != nil {
-		return confirmedUTXOs, wrap.Wrap(fmt.Errorf("%d %s: api error", resp.StatusCode(), resp.Status()))
+	// transaction is replaced if any of its inputs is spent by another transaction
+	for _, txIn := range tx.TxIn {
+		outspend, err := s.esploraClient.GetOutspend(ctx, txIn.PreviousOutPoint.Hash.String(), txIn.PreviousOutPoint.Index)
+		if err != nil {
+			return nil, wrap.Wrap(err)
+		}
+		if outspend.Spent && outspend.TxID != record.TxID {
+			entry.State = entities.TxStateReplaced
+			entry.ReplacedBy = outspend.TxID
+			break
+		}
 	}
 
-	return lo.Filter(respUTXOs, func(vout entities.TxOutput, _ int) bool { return !vout.Status.Confirmed }), nil
+	return entry, nil
+}
+
+func confirmedUTXOs(utxos entities.TxOutputs) []entities.TxOutput {
+	return lo.Filter(utxos, func(vout entities.TxOutput, _ int) bool { return vout.Status.Confirmed })
 }
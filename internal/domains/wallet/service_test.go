@@ -0,0 +1,583 @@
+package wallet_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/backend"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/clients/esplora/esploratest"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/address"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/audit"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/wallet"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/utils"
+)
+
+// recipientAddress is a testnet P2WPKH address from BIP173 test vectors.
+const recipientAddress = "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+
+// chainClient is implemented by backend client and chain simulator.
+type chainClient interface {
+	wallet.IEsploraClient
+	transaction.IEsploraClient
+}
+
+// newWallet creates wallet which uses fake Esplora API through backend client, its files are in dir.
+// Backend client caches responses, so a new wallet must be created to see blocks mined by the server.
+func newWallet(t *testing.T, server *esploratest.Server, dir string) *wallet.Service {
+	t.Helper()
+
+	client := backend.NewClient([]string{server.BaseURL()}, 5*time.Second, filepath.Join(dir, "tx_cache"))
+
+	return newClientWallet(t, client, dir)
+}
+
+// newClientWallet creates wallet with default mnemonic, its files are in dir.
+func newClientWallet(t *testing.T, client chainClient, dir string) *wallet.Service {
+	t.Helper()
+
+	auditService := audit.NewService(filepath.Join(dir, "audit_log"))
+	addressService := address.NewService("", false, filepath.Join(dir, "wallet_mnemonic"), filepath.Join(dir, "wallet_address"), auditService)
+	transactionService := transaction.NewService(addressService, client, auditService, filepath.Join(dir, "wallet_transactions"))
+
+	return wallet.NewService(addressService, transactionService, client, auditService)
+}
+
+// fundedWallet returns wallet which received confirmed outputs with values.
+func fundedWallet(t *testing.T, values ...int64) (*wallet.Service, *esploratest.Server) {
+	t.Helper()
+
+	server := esploratest.NewServer()
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	walletAddress, err := newWallet(t, server, dir).GetWalletAddress(context.Background())
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+
+	for _, value := range values {
+		if _, err = server.Fund(walletAddress, value); err != nil {
+			t.Fatalf("fund wallet: %v", err)
+		}
+	}
+	server.Mine()
+
+	return newWallet(t, server, dir), server
+}
+
+func TestWalletAddress(t *testing.T) {
+	server := esploratest.NewServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	first, err := newWallet(t, server, dir).GetWalletAddress(ctx)
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+
+	decoded, err := btcutil.DecodeAddress(first, &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("decode address %s: %v", first, err)
+	}
+	if _, ok := decoded.(*btcutil.AddressWitnessPubKeyHash); !ok || !decoded.IsForNet(&chaincfg.TestNet3Params) {
+		t.Fatalf("address %s isn't testnet P2WPKH", first)
+	}
+
+	second, err := newWallet(t, server, dir).GetWalletAddress(ctx)
+	if err != nil {
+		t.Fatalf("get address again: %v", err)
+	}
+	if first != second {
+		t.Fatalf("address is changed after restart: %s != %s", first, second)
+	}
+}
+
+func TestWalletBalance(t *testing.T) {
+	service, server := fundedWallet(t, 10_000, 20_000)
+	ctx := context.Background()
+
+	walletAddress, err := service.GetWalletAddress(ctx)
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+	if _, err = server.Fund(walletAddress, 5_000); err != nil {
+		t.Fatalf("fund wallet: %v", err)
+	}
+
+	confirmed, unconfirmed, err := service.GetWalletBalance(ctx)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	if confirmed != 30_000 || unconfirmed != 5_000 {
+		t.Fatalf("balance is %d/%d, want 30000/5000", confirmed, unconfirmed)
+	}
+}
+
+func TestSendWithChange(t *testing.T) {
+	service, server := fundedWallet(t, 100_000)
+	ctx := context.Background()
+
+	preview, err := service.PrepareSend(ctx, recipientAddress, 30_000, false)
+	if err != nil {
+		t.Fatalf("prepare send: %v", err)
+	}
+	if len(preview.Outputs) != 2 {
+		t.Fatalf("transaction has %d outputs, want recipient and change", len(preview.Outputs))
+	}
+	if preview.Outputs[0].Address != recipientAddress || preview.Outputs[0].Value != 30_000 {
+		t.Fatalf("recipient output is %+v", preview.Outputs[0])
+	}
+	change := 100_000 - 30_000 - preview.Fee
+	if preview.Outputs[1].Value != change {
+		t.Fatalf("change is %d, want %d", preview.Outputs[1].Value, change)
+	}
+
+	// the server accepts only valid signed transactions
+	txID, err := service.Broadcast(ctx, preview)
+	if err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+	if mempool := server.Mempool(); len(mempool) != 1 || mempool[0] != txID {
+		t.Fatalf("mempool is %v, want %s", mempool, txID)
+	}
+
+	confirmed, unconfirmed, err := service.GetWalletBalance(ctx)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	if confirmed != 0 || unconfirmed != change {
+		t.Fatalf("balance is %d/%d, want 0/%d", confirmed, unconfirmed, change)
+	}
+
+	history, err := service.GetHistory(ctx)
+	if err != nil {
+		t.Fatalf("get history: %v", err)
+	}
+	if len(history) == 0 || history[0].TxID != txID || history[0].Amount != -30_000-preview.Fee {
+		t.Fatalf("history doesn't start with sent transaction %s: %+v", txID, history)
+	}
+}
+
+func TestSendWithoutChange(t *testing.T) {
+	service, server := fundedWallet(t, 50_000)
+	ctx := context.Background()
+
+	txID, err := service.SendTo(ctx, recipientAddress, 50_000, true)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	tx, ok := server.Transaction(txID)
+	if !ok {
+		t.Fatalf("transaction %s isn't broadcasted", txID)
+	}
+	if len(tx.TxOut) != 1 {
+		t.Fatalf("transaction has %d outputs, want only recipient", len(tx.TxOut))
+	}
+	if value := tx.TxOut[0].Value; value >= 50_000 || value < 50_000-1_000 {
+		t.Fatalf("recipient gets %d, want 50000 without fee", value)
+	}
+
+	confirmed, unconfirmed, err := service.GetWalletBalance(ctx)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	if confirmed != 0 || unconfirmed != 0 {
+		t.Fatalf("balance is %d/%d, want empty wallet", confirmed, unconfirmed)
+	}
+}
+
+func TestSendDustOutputs(t *testing.T) {
+	service, server := fundedWallet(t, 100_000)
+	ctx := context.Background()
+
+	if _, err := service.SendTo(ctx, recipientAddress, 100, false); err == nil {
+		t.Fatal("dust amount is sent")
+	}
+
+	// fee of a transaction with change
+	preview, err := service.PrepareSend(ctx, recipientAddress, 30_000, false)
+	if err != nil {
+		t.Fatalf("prepare send: %v", err)
+	}
+	service.Release(preview)
+
+	// dust change goes to the fee
+	amount := 100_000 - preview.Fee - 100
+	if preview, err = service.PrepareSend(ctx, recipientAddress, amount, false); err != nil {
+		t.Fatalf("prepare send: %v", err)
+	}
+	if len(preview.Outputs) != 1 || preview.Outputs[0].Value != amount {
+		t.Fatalf("outputs are %+v, want only recipient with %d", preview.Outputs, amount)
+	}
+	if preview.Fee != 100_000-amount {
+		t.Fatalf("fee is %d, want %d", preview.Fee, 100_000-amount)
+	}
+
+	if _, err = service.Broadcast(ctx, preview); err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+	if requests := server.Requests(http.MethodPost, "/tx"); requests != 1 {
+		t.Fatalf("%d transactions are broadcasted, want 1", requests)
+	}
+}
+
+func TestSendFeeForOutputs(t *testing.T) {
+	withChange, withoutChange := utils.CalculateFee(1, 2), utils.CalculateFee(1, 1)
+
+	for _, tc := range []struct {
+		name          string
+		amount        int64
+		subtractFee   bool
+		wantRecipient int64
+		wantFee       int64
+		wantChange    bool
+	}{
+		{name: "change", amount: 30_000, wantRecipient: 30_000, wantFee: withChange, wantChange: true},
+		{name: "no room for change", amount: 100_000 - withoutChange, wantRecipient: 100_000 - withoutChange, wantFee: withoutChange},
+		{name: "dust change", amount: 100_000 - withChange - 100, wantRecipient: 100_000 - withChange - 100, wantFee: withChange + 100},
+		{name: "subtract fee with change", amount: 30_000, subtractFee: true, wantRecipient: 30_000 - withChange, wantFee: withChange, wantChange: true},
+		{name: "subtract fee without change", amount: 100_000, subtractFee: true, wantRecipient: 100_000 - withoutChange, wantFee: withoutChange},
+		{name: "subtract fee with dust change", amount: 100_000 - 100, subtractFee: true, wantRecipient: 100_000 - 100 - withoutChange, wantFee: withoutChange + 100},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := fundedWallet(t, 100_000)
+
+			preview, err := service.PrepareSend(context.Background(), recipientAddress, tc.amount, tc.subtractFee)
+			if err != nil {
+				t.Fatalf("prepare send: %v", err)
+			}
+			if preview.Outputs[0].Value != tc.wantRecipient || preview.Fee != tc.wantFee {
+				t.Fatalf("recipient gets %d with fee %d, want %d with fee %d", preview.Outputs[0].Value, preview.Fee, tc.wantRecipient, tc.wantFee)
+			}
+			if wantOutputs := lo.Ternary(tc.wantChange, 2, 1); len(preview.Outputs) != wantOutputs {
+				t.Fatalf("outputs are %+v, want %d", preview.Outputs, wantOutputs)
+			}
+			if change := lo.SumBy(preview.Outputs, func(output entities.TxPreviewOutput) int64 { return output.Value }) - tc.wantRecipient; change != 100_000-tc.wantRecipient-tc.wantFee {
+				t.Fatalf("change is %d, want %d", change, 100_000-tc.wantRecipient-tc.wantFee)
+			}
+		})
+	}
+}
+
+func TestSendInsufficientFunds(t *testing.T) {
+	service, server := fundedWallet(t, 1_000)
+	ctx := context.Background()
+
+	walletAddress, err := service.GetWalletAddress(ctx)
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+	// unconfirmed outputs aren't spent
+	if _, err = server.Fund(walletAddress, 100_000); err != nil {
+		t.Fatalf("fund wallet: %v", err)
+	}
+
+	_, err = service.SendTo(ctx, recipientAddress, 5_000, false)
+	if !errors.Is(err, wallet.ErrInsufficientFunds) {
+		t.Fatalf("send error is %v, want %v", err, wallet.ErrInsufficientFunds)
+	}
+	if requests := server.Requests(http.MethodPost, "/tx"); requests != 0 {
+		t.Fatalf("%d transactions are broadcasted", requests)
+	}
+}
+
+func TestConcurrentSendsSpendDifferentUTXOs(t *testing.T) {
+	service, server := fundedWallet(t, 50_000, 50_000)
+	ctx := context.Background()
+
+	var (
+		wg       sync.WaitGroup
+		previews [2]entities.TxPreview
+		errs     [2]error
+	)
+	for i := range previews {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			previews[i], errs[i] = service.PrepareSend(ctx, recipientAddress, 30_000, false)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("prepare send %d: %v", i, err)
+		}
+	}
+	if previews[0].Inputs[0].TxID == previews[1].Inputs[0].TxID {
+		t.Fatalf("both sends spend %s", previews[0].Inputs[0].TxID)
+	}
+
+	// reserved UTXOs aren't available until the preview is released
+	if _, err := service.PrepareSend(ctx, recipientAddress, 30_000, false); !errors.Is(err, wallet.ErrInsufficientFunds) {
+		t.Fatalf("third send error is %v, want %v", err, wallet.ErrInsufficientFunds)
+	}
+	service.Release(previews[1])
+
+	if _, err := service.Broadcast(ctx, previews[0]); err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+	if _, err := service.SendTo(ctx, recipientAddress, 30_000, false); err != nil {
+		t.Fatalf("send released UTXO: %v", err)
+	}
+	if mempool := server.Mempool(); len(mempool) != 2 {
+		t.Fatalf("mempool is %v, want 2 transactions", mempool)
+	}
+}
+
+func TestReservationByOutPoint(t *testing.T) {
+	server := esploratest.NewServer()
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	walletAddress, err := newWallet(t, server, dir).GetWalletAddress(ctx)
+	if err != nil {
+		t.Fatalf("get address: %v", err)
+	}
+	if _, err = server.Fund(walletAddress, 100_000); err != nil {
+		t.Fatalf("fund wallet: %v", err)
+	}
+	server.Mine()
+
+	// sending to itself gives two wallet outputs of one transaction
+	txID, err := newWallet(t, server, dir).SendTo(ctx, walletAddress, 40_000, false)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	server.Mine()
+	service := newWallet(t, server, dir)
+
+	first, err := service.PrepareSend(ctx, recipientAddress, 10_000, false)
+	if err != nil {
+		t.Fatalf("prepare send: %v", err)
+	}
+	second, err := service.PrepareSend(ctx, recipientAddress, 10_000, false)
+	if err != nil {
+		t.Fatalf("prepare send with another output of the transaction: %v", err)
+	}
+	if first.Inputs[0].TxID != txID || second.Inputs[0].TxID != txID || first.Inputs[0].Vout == second.Inputs[0].Vout {
+		t.Fatalf("sends spend %+v and %+v, want different outputs of %s", first.Inputs, second.Inputs, txID)
+	}
+
+	// release frees only the outputs of the preview
+	service.Release(first)
+	third, err := service.PrepareSend(ctx, recipientAddress, 10_000, false)
+	if err != nil {
+		t.Fatalf("prepare send: %v", err)
+	}
+	if third.Inputs[0] != first.Inputs[0] {
+		t.Fatalf("send spends %+v, want released %+v", third.Inputs[0], first.Inputs[0])
+	}
+}
+
+// unsignedPSBT returns PSBT which spends wallet UTXO to recipientAddress, its input requests sigHashType.
+func unsignedPSBT(t *testing.T, service *wallet.Service, sigHashType txscript.SigHashType) string {
+	t.Helper()
+
+	utxos, err := service.GetWalletUTXOs(context.Background())
+	if err != nil || len(utxos) == 0 {
+		t.Fatalf("get UTXOs: %v, error %v", utxos, err)
+	}
+	prevHash, err := chainhash.NewHashFromStr(utxos[0].TxID)
+	if err != nil {
+		t.Fatalf("parse txid: %v", err)
+	}
+	recipient, err := btcutil.DecodeAddress(recipientAddress, &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("decode address: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(recipient)
+	if err != nil {
+		t.Fatalf("create script: %v", err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prevHash, uint32(utxos[0].Vout)), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(utxos[0].Value-1_000, pkScript))
+
+	packet, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		t.Fatalf("create PSBT: %v", err)
+	}
+	packet.Inputs[0].SighashType = sigHashType
+
+	result, err := packet.B64Encode()
+	if err != nil {
+		t.Fatalf("encode PSBT: %v", err)
+	}
+
+	return result
+}
+
+func TestSignPSBTSigHash(t *testing.T) {
+	service, _ := fundedWallet(t, 100_000)
+	noneAnyoneCanPay := txscript.SigHashNone | txscript.SigHashAnyOneCanPay
+
+	for _, tc := range []struct {
+		name      string
+		requested txscript.SigHashType // sighash of PSBT input
+		allowed   txscript.SigHashType
+		want      txscript.SigHashType
+		wantErr   error
+	}{
+		{name: "default", want: txscript.SigHashAll},
+		{name: "all requested", requested: txscript.SigHashAll, allowed: txscript.SigHashAll, want: txscript.SigHashAll},
+		{name: "none isn't signed by default", requested: txscript.SigHashNone, wantErr: transaction.ErrSigHashMismatch},
+		{name: "anyonecanpay isn't signed by default", requested: noneAnyoneCanPay, allowed: txscript.SigHashAll, wantErr: transaction.ErrSigHashMismatch},
+		{name: "another allowed", requested: txscript.SigHashNone, allowed: noneAnyoneCanPay, wantErr: transaction.ErrSigHashMismatch},
+		{name: "explicitly allowed", requested: noneAnyoneCanPay, allowed: noneAnyoneCanPay, want: noneAnyoneCanPay},
+		{name: "allowed is recorded", allowed: txscript.SigHashSingle, want: txscript.SigHashSingle},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signedPSBT, signed, _, err := service.SignPSBT(context.Background(), unsignedPSBT(t, service, tc.requested), tc.allowed, false)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("sign error is %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if signed != 1 {
+				t.Fatalf("%d inputs signed, want 1", signed)
+			}
+
+			packet, err := psbt.NewFromRawBytes(strings.NewReader(signedPSBT), true)
+			if err != nil {
+				t.Fatalf("decode PSBT: %v", err)
+			}
+			input := packet.Inputs[0]
+			if len(input.PartialSigs) != 1 {
+				t.Fatalf("input has %d signatures, want 1", len(input.PartialSigs))
+			}
+			sig := input.PartialSigs[0].Signature
+			if got := txscript.SigHashType(sig[len(sig)-1]); got != tc.want {
+				t.Fatalf("signature sighash is %#x, want %#x", got, tc.want)
+			}
+			if tc.want != txscript.SigHashAll && input.SighashType != tc.want {
+				t.Fatalf("input sighash is %#x, want %#x", input.SighashType, tc.want)
+			}
+		})
+	}
+}
+
+func TestWaitConfirmations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not propagated transaction is waited", func(t *testing.T) {
+		service, server := fundedWallet(t, 50_000)
+
+		txID, err := service.SendTo(ctx, recipientAddress, 30_000, false)
+		if err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		server.Fail(http.MethodGet, "/tx/"+txID+"/status", http.StatusNotFound, 2)
+
+		if err = service.WaitConfirmations(ctx, txID, 0, time.Millisecond, func(int) {}); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	})
+
+	t.Run("unknown transaction is dropped", func(t *testing.T) {
+		service, server := fundedWallet(t)
+		txID := strings.Repeat("ab", 32)
+
+		err := service.WaitConfirmations(ctx, txID, 1, time.Millisecond, func(int) {})
+		if !errors.Is(err, wallet.ErrTransactionDropped) {
+			t.Fatalf("wait error is %v, want %v", err, wallet.ErrTransactionDropped)
+		}
+		if requests := server.Requests(http.MethodGet, "/tx/"+txID+"/status"); requests != constants.WaitNotFoundPolls {
+			t.Fatalf("status is requested %d times, want %d", requests, constants.WaitNotFoundPolls)
+		}
+	})
+}
+
+func TestAPIErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("server error", func(t *testing.T) {
+		service, server := fundedWallet(t, 10_000)
+		server.Fail(http.MethodGet, "/address/", http.StatusInternalServerError, 0)
+
+		if _, _, err := service.GetWalletBalance(ctx); err == nil {
+			t.Fatal("balance is returned on server error")
+		}
+
+		server.ClearFailures()
+		confirmed, _, err := service.GetWalletBalance(ctx)
+		if err != nil || confirmed != 10_000 {
+			t.Fatalf("balance after recovery is %d, error %v", confirmed, err)
+		}
+	})
+
+	t.Run("temporary error is retried", func(t *testing.T) {
+		service, server := fundedWallet(t, 10_000)
+		server.Fail(http.MethodGet, "/address/", http.StatusServiceUnavailable, 1)
+
+		confirmed, _, err := service.GetWalletBalance(ctx)
+		if err != nil || confirmed != 10_000 {
+			t.Fatalf("balance is %d, error %v", confirmed, err)
+		}
+	})
+
+	t.Run("broadcast is rejected", func(t *testing.T) {
+		service, server := fundedWallet(t, 10_000)
+		server.Fail(http.MethodPost, "/tx", http.StatusBadRequest, 1)
+
+		_, err := service.SendTo(ctx, recipientAddress, 5_000, false)
+		var rejectErr *esplora.RejectError
+		if !errors.As(err, &rejectErr) {
+			t.Fatalf("send error is %v, want reject error", err)
+		}
+		if mempool := server.Mempool(); len(mempool) != 0 {
+			t.Fatalf("rejected transaction is in mempool: %v", mempool)
+		}
+	})
+
+	t.Run("double spend is rejected", func(t *testing.T) {
+		service, _ := fundedWallet(t, 10_000)
+
+		first, err := service.PrepareSend(ctx, recipientAddress, 5_000, false)
+		if err != nil {
+			t.Fatalf("prepare send: %v", err)
+		}
+		// UTXO of the first transaction is reserved
+		if _, err = service.PrepareSend(ctx, recipientAddress, 6_000, false); !errors.Is(err, wallet.ErrInsufficientFunds) {
+			t.Fatalf("prepare send error is %v, want %v", err, wallet.ErrInsufficientFunds)
+		}
+		// the backend still rejects conflicting transaction, e.g. after the reservation is expired
+		service.Release(first)
+		second, err := service.PrepareSend(ctx, recipientAddress, 6_000, false)
+		if err != nil {
+			t.Fatalf("prepare send: %v", err)
+		}
+
+		if _, err = service.Broadcast(ctx, first); err != nil {
+			t.Fatalf("broadcast: %v", err)
+		}
+		_, err = service.Broadcast(ctx, second)
+		var rejectErr *esplora.RejectError
+		if !errors.As(err, &rejectErr) {
+			t.Fatalf("double spend error is %v, want reject error", err)
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/metrics"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+type (
+	IWalletService interface {
+		GetWalletUTXOs(ctx context.Context) (result entities.TxOutputs, err error)
+		GetHistory(ctx context.Context) (result []entities.HistoryEntry, err error)
+	}
+
+	Service struct {
+		walletService IWalletService
+
+		pollMu      sync.Mutex // polls are serialized, so handlers get events in order
+		mu          sync.Mutex
+		cancel      context.CancelFunc // nil if watcher isn't running
+		done        chan struct{}
+		subscribers map[chan entities.WalletEvent]struct{}
+		handlers    map[*handler]struct{}
+		snapshot    *snapshot // nil before the first poll
+	}
+
+	// handler is a lossless subscriber, it has its own previous state.
+	handler struct {
+		handle   func(events []entities.WalletEvent, current entities.WalletSnapshot) error
+		previous *snapshot // state accepted by handle last time
+	}
+
+	snapshot struct {
+		balance entities.BalanceResult
+		entries []entities.HistoryEntry // oldest first
+		history map[string]entities.HistoryEntry
+	}
+)
+
+func NewService(walletService IWalletService) *Service {
+	return &Service{
+		walletService: walletService,
+		subscribers:   make(map[chan entities.WalletEvent]struct{}),
+		handlers:      make(map[*handler]struct{}),
+	}
+}
+
+func newSnapshot(state entities.WalletSnapshot) *snapshot {
+	return &snapshot{
+		balance: state.Balance,
+		entries: state.History,
+		history: lo.SliceToMap(state.History, func(entry entities.HistoryEntry) (string, entities.HistoryEntry) {
+			return entry.TxID, entry
+		}),
+	}
+}
+
+func (s *snapshot) state() entities.WalletSnapshot {
+	return entities.WalletSnapshot{Balance: s.balance, History: s.entries}
+}
+
+// Start polls the wallet state every interval in background until Stop is called or ctx is done.
+func (s *Service) Start(ctx context.Context, interval time.Duration) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return wrap.Wrap(errors.New("watcher is already running"))
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go s.run(ctx, interval, s.done)
+
+	return nil
+}
+
+// Stop stops background polling and waits for it.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+func (s *Service) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cancel != nil
+}
+
+// Subscribe returns channel with events detected by polling. Slow subscribers lose events,
+// so polling is never blocked, see SubscribeFunc for lossless one. unsubscribe must be called to release the channel.
+func (s *Service) Subscribe() (events <-chan entities.WalletEvent, unsubscribe func()) {
+	ch := make(chan entities.WalletEvent, 64)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subscribers, ch)
+			s.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// SubscribeFunc adds lossless subscriber: handle is called by polls with events detected since the state
+// accepted by handle last time, at first since baseline. nil baseline means the first poll only passes the state.
+// If handle fails, the state isn't accepted and the events are detected again by the next poll.
+func (s *Service) SubscribeFunc(baseline *entities.WalletSnapshot, handle func(events []entities.WalletEvent, current entities.WalletSnapshot) error) (unsubscribe func()) {
+	h := &handler{handle: handle}
+	if baseline != nil {
+		h.previous = newSnapshot(*baseline)
+	}
+
+	s.mu.Lock()
+	s.handlers[h] = struct{}{}
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		delete(s.handlers, h)
+		s.mu.Unlock()
+	}
+}
+
+// Poll compares the current wallet state with the previous one and publishes events.
+// The first poll only remembers the state.
+func (s *Service) Poll(ctx context.Context) (events []entities.WalletEvent, err error) {
+	s.pollMu.Lock()
+	defer s.pollMu.Unlock()
+
+	history, err := s.walletService.GetHistory(ctx)
+	if err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	utxos, err := s.walletService.GetWalletUTXOs(ctx)
+	if err != nil {
+		return nil, wrap.Wrap(err)
+	}
+
+	current := newSnapshot(entities.WalletSnapshot{
+		Balance: entities.BalanceResult{
+			Confirmed:   lo.SumBy(utxos, func(utxo entities.TxOutput) int64 { return lo.Ternary(utxo.Status.Confirmed, utxo.Value, 0) }),
+			Unconfirmed: lo.SumBy(utxos, func(utxo entities.TxOutput) int64 { return lo.Ternary(utxo.Status.Confirmed, 0, utxo.Value) }),
+		},
+		History: lo.Reverse(history),
+	})
+
+	metrics.ObserveSync(current.balance, len(utxos))
+
+	now := time.Now().Unix()
+
+	s.mu.Lock()
+	if s.snapshot != nil {
+		events = diff(s.snapshot, current, now)
+	}
+	s.snapshot = current
+
+	for _, event := range events {
+		for ch := range s.subscribers {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+	handlers := lo.Keys(s.handlers)
+	s.mu.Unlock()
+
+	// handlers are called without the lock, they can subscribe and unsubscribe
+	var errs []error
+	for _, h := range handlers {
+		var handlerEvents []entities.WalletEvent
+		if h.previous != nil {
+			handlerEvents = diff(h.previous, current, now)
+			if len(handlerEvents) == 0 {
+				continue
+			}
+		}
+
+		if err = h.handle(handlerEvents, current.state()); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		h.previous = current
+	}
+	if err = errors.Join(errs...); err != nil {
+		return events, wrap.Wrap(err)
+	}
+
+	return events, nil
+}
+
+func (s *Service) run(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Poll(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("wallet poll failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func diff(previous, current *snapshot, now int64) (events []entities.WalletEvent) {
+	for _, entry := range current.entries {
+		prevEntry, ok := previous.history[entry.TxID]
+		switch {
+		case !ok:
+			events = append(events, entities.WalletEvent{
+				Type:          entities.WalletEventNewTransaction,
+				Time:          now,
+				TxID:          entry.TxID,
+				Amount:        entry.Amount,
+				Confirmations: entry.Confirmations,
+			})
+		case entry.State != prevEntry.State && entry.State == entities.TxStateReplaced:
+			events = append(events, entities.WalletEvent{
+				Type:       entities.WalletEventReplaced,
+				Time:       now,
+				TxID:       entry.TxID,
+				Amount:     entry.Amount,
+				ReplacedBy: entry.ReplacedBy,
+			})
+		case entry.State != prevEntry.State && entry.State == entities.TxStateDropped:
+			events = append(events, entities.WalletEvent{
+				Type:   entities.WalletEventDropped,
+				Time:   now,
+				TxID:   entry.TxID,
+				Amount: entry.Amount,
+			})
+		// deep confirmations aren't interesting, otherwise every block produces event for every transaction
+		case entry.Confirmations != prevEntry.Confirmations && prevEntry.Confirmations < constants.WatcherMaxConfirmations:
+			events = append(events, entities.WalletEvent{
+				Type:          entities.WalletEventConfirmation,
+				Time:          now,
+				TxID:          entry.TxID,
+				Amount:        entry.Amount,
+				Confirmations: entry.Confirmations,
+			})
+		}
+	}
+
+	if current.balance != previous.balance {
+		balance := current.balance
+		events = append(events, entities.WalletEvent{
+			Type:    entities.WalletEventBalanceChange,
+			Time:    now,
+			Balance: &balance,
+		})
+	}
+
+	return events
+}
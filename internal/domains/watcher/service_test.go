@@ -0,0 +1,285 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+var (
+	errWallet  = errors.New("wallet error")
+	errHandler = errors.New("handler error")
+)
+
+// fakeWallet returns the set state, history is oldest first like in snapshots.
+type fakeWallet struct {
+	mu      sync.Mutex
+	history []entities.HistoryEntry
+	utxos   entities.TxOutputs
+	err     error
+}
+
+func (f *fakeWallet) set(utxos entities.TxOutputs, history ...entities.HistoryEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.utxos, f.history = utxos, history
+}
+
+func (f *fakeWallet) GetWalletUTXOs(context.Context) (entities.TxOutputs, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.utxos), f.err
+}
+
+func (f *fakeWallet) GetHistory(context.Context) ([]entities.HistoryEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	history := slices.Clone(f.history)
+	slices.Reverse(history) // the wallet returns newest first
+
+	return history, f.err
+}
+
+func mempoolEntry(txID string, amount int64) entities.HistoryEntry {
+	return entities.HistoryEntry{TxID: txID, Amount: amount, State: entities.TxStateMempool}
+}
+
+func confirmedEntry(txID string, amount int64, confirmations int) entities.HistoryEntry {
+	return entities.HistoryEntry{TxID: txID, Amount: amount, Confirmations: confirmations, State: entities.TxStateConfirmed}
+}
+
+// eventTypes returns "type:txid" of events, balance change events are "balance_change:confirmed/unconfirmed".
+func eventTypes(events []entities.WalletEvent) (result []string) {
+	for _, event := range events {
+		switch {
+		case event.Balance != nil:
+			result = append(result, string(event.Type)+":"+strconv.FormatInt(event.Balance.Confirmed, 10)+"/"+strconv.FormatInt(event.Balance.Unconfirmed, 10))
+		case event.ReplacedBy != "":
+			result = append(result, string(event.Type)+":"+event.TxID+">"+event.ReplacedBy)
+		case event.Type == entities.WalletEventConfirmation:
+			result = append(result, string(event.Type)+":"+event.TxID+"@"+strconv.Itoa(event.Confirmations))
+		default:
+			result = append(result, string(event.Type)+":"+event.TxID)
+		}
+	}
+
+	return result
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		previous, current entities.WalletSnapshot
+		want              []string
+	}{
+		{
+			name:    "new transaction",
+			current: entities.WalletSnapshot{History: []entities.HistoryEntry{mempoolEntry("aa", 1_000)}},
+			want:    []string{"new_transaction:aa"},
+		},
+		{
+			name:     "confirmation",
+			previous: entities.WalletSnapshot{History: []entities.HistoryEntry{mempoolEntry("aa", 1_000)}},
+			current:  entities.WalletSnapshot{History: []entities.HistoryEntry{confirmedEntry("aa", 1_000, 1)}},
+			want:     []string{"confirmation:aa@1"},
+		},
+		{
+			name:     "the last interesting confirmation",
+			previous: entities.WalletSnapshot{History: []entities.HistoryEntry{confirmedEntry("aa", 1_000, constants.WatcherMaxConfirmations-1)}},
+			current:  entities.WalletSnapshot{History: []entities.HistoryEntry{confirmedEntry("aa", 1_000, constants.WatcherMaxConfirmations)}},
+			want:     []string{"confirmation:aa@" + strconv.Itoa(constants.WatcherMaxConfirmations)},
+		},
+		{
+			name:     "deep confirmation",
+			previous: entities.WalletSnapshot{History: []entities.HistoryEntry{confirmedEntry("aa", 1_000, constants.WatcherMaxConfirmations)}},
+			current:  entities.WalletSnapshot{History: []entities.HistoryEntry{confirmedEntry("aa", 1_000, constants.WatcherMaxConfirmations+1)}},
+		},
+		{
+			name:     "replaced",
+			previous: entities.WalletSnapshot{History: []entities.HistoryEntry{mempoolEntry("aa", -1_000)}},
+			current: entities.WalletSnapshot{History: []entities.HistoryEntry{
+				{TxID: "aa", Amount: -1_000, State: entities.TxStateReplaced, ReplacedBy: "bb"},
+				mempoolEntry("bb", -1_100),
+			}},
+			want: []string{"replaced:aa>bb", "new_transaction:bb"},
+		},
+		{
+			name:     "still replaced",
+			previous: entities.WalletSnapshot{History: []entities.HistoryEntry{{TxID: "aa", State: entities.TxStateReplaced, ReplacedBy: "bb"}}},
+			current:  entities.WalletSnapshot{History: []entities.HistoryEntry{{TxID: "aa", State: entities.TxStateReplaced, ReplacedBy: "bb"}}},
+		},
+		{
+			name:     "dropped",
+			previous: entities.WalletSnapshot{History: []entities.HistoryEntry{mempoolEntry("aa", -1_000)}},
+			current:  entities.WalletSnapshot{History: []entities.HistoryEntry{{TxID: "aa", Amount: -1_000, State: entities.TxStateDropped}}},
+			want:     []string{"dropped:aa"},
+		},
+		{
+			name:     "balance change",
+			previous: entities.WalletSnapshot{Balance: entities.BalanceResult{Confirmed: 1_000}},
+			current:  entities.WalletSnapshot{Balance: entities.BalanceResult{Confirmed: 1_000, Unconfirmed: 500}},
+			want:     []string{"balance_change:1000/500"},
+		},
+		{
+			name:     "no changes",
+			previous: entities.WalletSnapshot{Balance: entities.BalanceResult{Confirmed: 1_000}, History: []entities.HistoryEntry{confirmedEntry("aa", 1_000, 1)}},
+			current:  entities.WalletSnapshot{Balance: entities.BalanceResult{Confirmed: 1_000}, History: []entities.HistoryEntry{confirmedEntry("aa", 1_000, 1)}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			events := diff(newSnapshot(tc.previous), newSnapshot(tc.current), 100)
+			if got := eventTypes(events); !slices.Equal(got, tc.want) {
+				t.Fatalf("events are %v, want %v", got, tc.want)
+			}
+			for _, event := range events {
+				if event.Time != 100 {
+					t.Fatalf("event time is %d, want 100", event.Time)
+				}
+			}
+		})
+	}
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+	wallet := &fakeWallet{}
+	service := NewService(wallet)
+	events, unsubscribe := service.Subscribe()
+	defer unsubscribe()
+
+	poll := func(want ...string) {
+		t.Helper()
+
+		polled, err := service.Poll(ctx)
+		if err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+		if got := eventTypes(polled); !slices.Equal(got, want) {
+			t.Fatalf("polled events are %v, want %v", got, want)
+		}
+
+		// subscribers get the same events
+		var received []entities.WalletEvent
+		for range polled {
+			received = append(received, <-events)
+		}
+		if got := eventTypes(received); !slices.Equal(got, want) {
+			t.Fatalf("received events are %v, want %v", got, want)
+		}
+	}
+
+	// the first poll only remembers the state
+	wallet.set(entities.TxOutputs{{TxID: "aa", Value: 1_000, Status: entities.TxStatus{Confirmed: true}}}, confirmedEntry("aa", 1_000, 1))
+	poll()
+
+	wallet.set(
+		entities.TxOutputs{{TxID: "aa", Value: 1_000, Status: entities.TxStatus{Confirmed: true}}, {TxID: "bb", Value: 500}},
+		confirmedEntry("aa", 1_000, 2), mempoolEntry("bb", 500),
+	)
+	poll("confirmation:aa@2", "new_transaction:bb", "balance_change:1000/500")
+
+	// wallet errors don't change the state
+	wallet.err = errWallet
+	if _, err := service.Poll(ctx); !errors.Is(err, errWallet) {
+		t.Fatalf("poll error is %v, want %v", err, errWallet)
+	}
+	wallet.err = nil
+	poll()
+
+	wallet.set(
+		entities.TxOutputs{{TxID: "aa", Value: 1_000, Status: entities.TxStatus{Confirmed: true}}, {TxID: "bb", Value: 500, Status: entities.TxStatus{Confirmed: true}}},
+		confirmedEntry("aa", 1_000, 3), confirmedEntry("bb", 500, 1),
+	)
+	poll("confirmation:aa@3", "confirmation:bb@1", "balance_change:1500/0")
+}
+
+func TestHandlerReplay(t *testing.T) {
+	ctx := context.Background()
+	wallet := &fakeWallet{}
+	service := NewService(wallet)
+
+	var (
+		calls   []string
+		failing = true
+	)
+	service.SubscribeFunc(nil, func(events []entities.WalletEvent, current entities.WalletSnapshot) error {
+		calls = append(calls, eventTypes(events)...)
+		calls = append(calls, "state:"+strconv.Itoa(len(current.History)))
+		if len(events) > 0 && failing {
+			return errHandler
+		}
+		return nil
+	})
+
+	// the first poll passes the state without events
+	wallet.set(nil, confirmedEntry("aa", 1_000, 1))
+	if _, err := service.Poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+
+	wallet.set(entities.TxOutputs{{TxID: "bb", Value: 500}}, confirmedEntry("aa", 1_000, 2), mempoolEntry("bb", 500))
+	if _, err := service.Poll(ctx); !errors.Is(err, errHandler) {
+		t.Fatalf("poll error is %v, want %v", err, errHandler)
+	}
+
+	// the failed events are passed again though the wallet doesn't change, even if the poll has no events
+	failing = false
+	events, err := service.Poll(ctx)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("poll events %v, error %v", events, err)
+	}
+
+	// the accepted state isn't passed again
+	if _, err = service.Poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+
+	want := []string{
+		"state:1",
+		"confirmation:aa@2", "new_transaction:bb", "balance_change:0/500", "state:2",
+		"confirmation:aa@2", "new_transaction:bb", "balance_change:0/500", "state:2",
+	}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("handler calls are %v, want %v", calls, want)
+	}
+}
+
+func TestHandlerBaseline(t *testing.T) {
+	wallet := &fakeWallet{}
+	service := NewService(wallet)
+
+	// the handler gets events which happened since the baseline, e.g. while the wallet wasn't running
+	baseline := entities.WalletSnapshot{History: []entities.HistoryEntry{mempoolEntry("aa", -1_000)}}
+	var got []string
+	unsubscribe := service.SubscribeFunc(&baseline, func(events []entities.WalletEvent, _ entities.WalletSnapshot) error {
+		got = append(got, eventTypes(events)...)
+		return nil
+	})
+
+	wallet.set(nil, entities.HistoryEntry{TxID: "aa", Amount: -1_000, State: entities.TxStateDropped})
+	if _, err := service.Poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if want := []string{"dropped:aa"}; !slices.Equal(got, want) {
+		t.Fatalf("handler events are %v, want %v", got, want)
+	}
+
+	// unsubscribed handler isn't called
+	unsubscribe()
+	wallet.set(nil, entities.HistoryEntry{TxID: "aa", Amount: -1_000, State: entities.TxStateDropped}, mempoolEntry("bb", 1))
+	if _, err := service.Poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("unsubscribed handler got %v", got)
+	}
+}
@@ -0,0 +1,43 @@
+package entities
+
+import "encoding/json"
+
+type AuditEventType string
+
+const (
+	AuditEventAddressDerived    AuditEventType = "address_derived"
+	AuditEventSendAttempt       AuditEventType = "send_attempt"
+	AuditEventTransactionSigned AuditEventType = "transaction_signed"
+	AuditEventPSBTSigned        AuditEventType = "psbt_signed"
+	AuditEventBroadcast         AuditEventType = "broadcast"
+	AuditEventBackupCreated     AuditEventType = "backup_created"
+	AuditEventWalletRestored    AuditEventType = "wallet_restored"
+	AuditEventWalletRecovered   AuditEventType = "wallet_recovered"
+	AuditEventSharesCreated     AuditEventType = "shares_created"
+	AuditEventSharesCombined    AuditEventType = "shares_combined"
+)
+
+// AuditRecord is a line of the audit log. Hash is SHA-256 of the record JSON without hash,
+// so every record confirms all previous ones.
+type AuditRecord struct {
+	Seq      uint64          `json:"seq" yaml:"seq"`
+	Time     int64           `json:"time" yaml:"time"` // Unix
+	Event    AuditEventType  `json:"event" yaml:"event"`
+	Data     json.RawMessage `json:"data" yaml:"-"` // see MarshalYAML
+	PrevHash string          `json:"prev_hash" yaml:"prev_hash"`
+	Hash     string          `json:"hash,omitempty" yaml:"hash"`
+}
+
+// MarshalYAML outputs data as YAML mapping instead of JSON bytes.
+func (r AuditRecord) MarshalYAML() (any, error) {
+	var data any
+	if err := json.Unmarshal(r.Data, &data); err != nil {
+		return nil, err
+	}
+
+	type plain AuditRecord // without MarshalYAML
+	return struct {
+		plain `yaml:",inline"`
+		Data  any `yaml:"data"`
+	}{plain: plain(r), Data: data}, nil
+}
@@ -0,0 +1,37 @@
+package entities
+
+// WalletBackup is decrypted content of a backup file.
+// The BIP39 passphrase (secretPassphrase) isn't saved, only its optional hint.
+// The wallet has no labels and frozen UTXOs yet, their fields are always empty,
+// so the format version isn't changed when they are supported.
+type WalletBackup struct {
+	Version        int               `json:"version"`
+	CreatedAt      int64             `json:"created_at"` // Unix
+	Network        string            `json:"network"`
+	Mnemonic       string            `json:"mnemonic"`
+	PassphraseHint string            `json:"passphrase_hint,omitempty"`
+	Accounts       []BackupAccount   `json:"accounts"`
+	Labels         map[string]string `json:"labels"`       // by address or txid
+	FrozenUTXOs    []string          `json:"frozen_utxos"` // "txid:vout"
+}
+
+type BackupAccount struct {
+	ReceiveDescriptor string `json:"receive_descriptor"`
+	ChangeDescriptor  string `json:"change_descriptor"`
+	ReceiveIndex      uint32 `json:"receive_index"` // last used index
+	ChangeIndex       uint32 `json:"change_index"`
+	Address           string `json:"address"` // the first receive address, it checks the passphrase on restore
+}
+
+type BackupResult struct {
+	Path    string `json:"path" yaml:"path"`
+	Version int    `json:"version" yaml:"version"`
+	Address string `json:"address" yaml:"address"`
+}
+
+type RestoreResult struct {
+	Address      string `json:"address" yaml:"address"`
+	Confirmed    int64  `json:"confirmed" yaml:"confirmed"`
+	Unconfirmed  int64  `json:"unconfirmed" yaml:"unconfirmed"`
+	Transactions int    `json:"transactions" yaml:"transactions"` // found by rescan
+}
@@ -5,3 +5,49 @@ type HelpArg struct {
 	SeqNumber   int // argument's sequence number in output message
 	Required    bool
 }
+
+type AddressResult struct {
+	Address string `json:"address" yaml:"address"`
+}
+
+type BalanceResult struct {
+	Confirmed   int64 `json:"confirmed" yaml:"confirmed"`
+	Unconfirmed int64 `json:"unconfirmed" yaml:"unconfirmed"`
+}
+
+type SendResult struct {
+	Transaction TxPreview `json:"transaction" yaml:"transaction"`
+	Broadcasted bool      `json:"broadcasted" yaml:"broadcasted"`
+}
+
+type BroadcastResult struct {
+	TxID string `json:"txid" yaml:"txid"`
+}
+
+type SignPSBTResult struct {
+	PSBT     string `json:"psbt" yaml:"psbt"`
+	Signed   int    `json:"signed" yaml:"signed"`     // number of inputs signed by the wallet
+	Complete bool   `json:"complete" yaml:"complete"` // all inputs are finalized
+}
+
+type WaitResult struct {
+	TxID          string `json:"txid" yaml:"txid"`
+	Confirmations int    `json:"confirmations" yaml:"confirmations"`
+}
+
+type ErrorResult struct {
+	Error string `json:"error" yaml:"error"`
+}
+
+type SendRequest struct {
+	Address     string `json:"address" validate:"required"`
+	Amount      int64  `json:"amount" validate:"gt=0"`
+	SubtractFee bool   `json:"subtract_fee"`
+	DryRun      bool   `json:"dry_run"`
+}
+
+type SignPSBTRequest struct {
+	PSBT        string `json:"psbt" validate:"required,base64"`
+	Finalize    bool   `json:"finalize"`
+	SighashType string `json:"sighash_type"` // bitcoind sighash name, inputs which request another one are rejected
+}
@@ -0,0 +1,28 @@
+package entities
+
+type WalletEventType string
+
+const (
+	WalletEventNewTransaction WalletEventType = "new_transaction"
+	WalletEventConfirmation   WalletEventType = "confirmation"
+	WalletEventBalanceChange  WalletEventType = "balance_change"
+	WalletEventReplaced       WalletEventType = "replaced" // sent transaction is replaced by another one (RBF)
+	WalletEventDropped        WalletEventType = "dropped"  // sent transaction is evicted from mempool
+)
+
+// WalletEvent is a change of the wallet state detected by the watcher.
+type WalletEvent struct {
+	Type          WalletEventType `json:"type" yaml:"type"`
+	Time          int64           `json:"time" yaml:"time"`                                       // detection time
+	TxID          string          `json:"txid,omitempty" yaml:"txid,omitempty"`                   // transaction events only
+	Amount        int64           `json:"amount,omitempty" yaml:"amount,omitempty"`               // wallet balance change by transaction
+	Confirmations int             `json:"confirmations,omitempty" yaml:"confirmations,omitempty"` // transaction events only
+	ReplacedBy    string          `json:"replaced_by,omitempty" yaml:"replaced_by,omitempty"`     // replaced event only
+	Balance       *BalanceResult  `json:"balance,omitempty" yaml:"balance,omitempty"`             // balance change event only
+}
+
+// WalletSnapshot is the wallet state which the watcher compares with the previous one to detect events.
+type WalletSnapshot struct {
+	Balance BalanceResult  `json:"balance" yaml:"balance"`
+	History []HistoryEntry `json:"history" yaml:"history"` // oldest first
+}
@@ -0,0 +1,30 @@
+package entities
+
+type TxDirection string
+
+const (
+	TxDirectionIncoming     TxDirection = "incoming"
+	TxDirectionOutgoing     TxDirection = "outgoing"
+	TxDirectionSelfTransfer TxDirection = "self-transfer"
+)
+
+type TxState string
+
+const (
+	TxStateMempool   TxState = "mempool"
+	TxStateConfirmed TxState = "confirmed"
+	TxStateReplaced  TxState = "replaced"
+	TxStateDropped   TxState = "dropped"
+)
+
+// HistoryEntry is a wallet transaction from the wallet point of view.
+type HistoryEntry struct {
+	TxID          string      `json:"txid" yaml:"txid"`
+	Time          int64       `json:"time" yaml:"time"`     // block time for confirmed, broadcast time for own transactions, otherwise 0
+	Amount        int64       `json:"amount" yaml:"amount"` // wallet balance change, negative for outgoing
+	Direction     TxDirection `json:"direction" yaml:"direction"`
+	Fee           int64       `json:"fee" yaml:"fee"`
+	Confirmations int         `json:"confirmations" yaml:"confirmations"`
+	State         TxState     `json:"state" yaml:"state"`
+	ReplacedBy    string      `json:"replaced_by,omitempty" yaml:"replaced_by,omitempty"` // txid of transaction which spent the same inputs
+}
@@ -0,0 +1,28 @@
+package entities
+
+type RecoveryResult struct {
+	Accounts    []RecoveredAccount `json:"accounts" yaml:"accounts"` // accounts with transactions
+	Scanned     int                `json:"scanned" yaml:"scanned"`   // number of checked addresses
+	Confirmed   int64              `json:"confirmed" yaml:"confirmed"`
+	Unconfirmed int64              `json:"unconfirmed" yaml:"unconfirmed"`
+	Unspendable int64              `json:"unspendable" yaml:"unspendable"` // found funds outside the wallet address
+	Imported    bool               `json:"imported" yaml:"imported"`
+	Address     string             `json:"address,omitempty" yaml:"address,omitempty"` // wallet address after import
+}
+
+type RecoveredAccount struct {
+	Standard    string             `json:"standard" yaml:"standard"` // BIP44, BIP49, BIP84 or BIP86
+	Path        string             `json:"path" yaml:"path"`
+	ScriptType  string             `json:"script_type" yaml:"script_type"`
+	Addresses   []RecoveredAddress `json:"addresses" yaml:"addresses"` // used addresses only
+	Confirmed   int64              `json:"confirmed" yaml:"confirmed"`
+	Unconfirmed int64              `json:"unconfirmed" yaml:"unconfirmed"`
+}
+
+type RecoveredAddress struct {
+	Path         string `json:"path" yaml:"path"`
+	Address      string `json:"address" yaml:"address"`
+	Transactions int    `json:"transactions" yaml:"transactions"`
+	Confirmed    int64  `json:"confirmed" yaml:"confirmed"`
+	Unconfirmed  int64  `json:"unconfirmed" yaml:"unconfirmed"`
+}
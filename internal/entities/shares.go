@@ -0,0 +1,12 @@
+package entities
+
+type SharesResult struct {
+	Identifier uint16   `json:"identifier" yaml:"identifier"` // common for shares of the secret
+	Threshold  int      `json:"threshold" yaml:"threshold"`
+	Shares     []string `json:"shares" yaml:"shares"` // SLIP-39 mnemonics
+}
+
+type SharesCombineResult struct {
+	Address string `json:"address" yaml:"address"`
+	Shares  int    `json:"shares" yaml:"shares"` // number of used shares
+}
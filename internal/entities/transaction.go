@@ -29,8 +29,85 @@ type Vout struct {
 }
 
 type TxStatus struct {
-	Confirmed   bool   `json:"confirmed"`
-	BlockHeight int    `json:"block_height,omitempty"`
-	BlockHash   string `json:"block_hash,omitempty"`
-	BlockTime   int64  `json:"block_time,omitempty"`
+	Confirmed   bool   `json:"confirmed" yaml:"confirmed"`
+	BlockHeight int    `json:"block_height,omitempty" yaml:"block_height,omitempty"`
+	BlockHash   string `json:"block_hash,omitempty" yaml:"block_hash,omitempty"`
+	BlockTime   int64  `json:"block_time,omitempty" yaml:"block_time,omitempty"`
+}
+
+type Outspend struct {
+	Spent  bool     `json:"spent"`
+	TxID   string   `json:"txid,omitempty"`
+	Vin    int      `json:"vin,omitempty"`
+	Status TxStatus `json:"status,omitempty"`
+}
+
+// TxPreview describes signed but not broadcasted transaction.
+type TxPreview struct {
+	TxID    string            `json:"txid" yaml:"txid"`
+	Inputs  []TxPreviewInput  `json:"inputs" yaml:"inputs"`
+	Outputs []TxPreviewOutput `json:"outputs" yaml:"outputs"`
+	VSize   int64             `json:"vsize" yaml:"vsize"`
+	Fee     int64             `json:"fee" yaml:"fee"`
+	FeeRate float64           `json:"fee_rate" yaml:"fee_rate"` // sat/vbyte
+	RawHex  string            `json:"hex" yaml:"hex"`
+}
+
+type TxPreviewInput struct {
+	TxID    string `json:"txid" yaml:"txid"`
+	Vout    uint32 `json:"vout" yaml:"vout"`
+	Address string `json:"address" yaml:"address"`
+	Value   int64  `json:"value" yaml:"value"`
+}
+
+type TxPreviewOutput struct {
+	Address  string `json:"address" yaml:"address"`
+	Value    int64  `json:"value" yaml:"value"`
+	IsChange bool   `json:"is_change" yaml:"is_change"`
+}
+
+// BroadcastedTx is a record about transaction sent by the wallet.
+type BroadcastedTx struct {
+	TxID   string `json:"txid"`
+	RawHex string `json:"hex"`
+	Amount int64  `json:"amount"` // wallet balance change, negative for outgoing
+	Fee    int64  `json:"fee"`
+	Time   int64  `json:"time"`
+}
+
+// DecodedTx is a detailed view of raw transaction.
+type DecodedTx struct {
+	TxID     string         `json:"txid" yaml:"txid"`
+	WTxID    string         `json:"wtxid" yaml:"wtxid"`
+	Version  int32          `json:"version" yaml:"version"`
+	LockTime uint32         `json:"locktime" yaml:"locktime"`
+	Size     int64          `json:"size" yaml:"size"`
+	Weight   int64          `json:"weight" yaml:"weight"`
+	VSize    int64          `json:"vsize" yaml:"vsize"`
+	RBF      bool           `json:"rbf" yaml:"rbf"` // BIP125 replaceability signalling
+	Fee      int64          `json:"fee" yaml:"fee"`
+	FeeKnown bool           `json:"fee_known" yaml:"fee_known"`               // fee is known only if all previous outputs are known
+	Status   *TxStatus      `json:"status,omitempty" yaml:"status,omitempty"` // nil if transaction isn't requested from the backend
+	Inputs   []DecodedTxIn  `json:"inputs" yaml:"inputs"`
+	Outputs  []DecodedTxOut `json:"outputs" yaml:"outputs"`
+}
+
+type DecodedTxIn struct {
+	TxID         string        `json:"txid" yaml:"txid"`
+	Vout         uint32        `json:"vout" yaml:"vout"`
+	Sequence     uint32        `json:"sequence" yaml:"sequence"`
+	ScriptSigASM string        `json:"scriptsig_asm,omitempty" yaml:"scriptsig_asm,omitempty"`
+	Witness      []string      `json:"witness,omitempty" yaml:"witness,omitempty"`
+	Prevout      *DecodedTxOut `json:"prevout,omitempty" yaml:"prevout,omitempty"` // nil if previous output is unknown
+	IsWallet     bool          `json:"is_wallet" yaml:"is_wallet"`
+}
+
+type DecodedTxOut struct {
+	Index           uint32 `json:"index" yaml:"index"`
+	Value           int64  `json:"value" yaml:"value"`
+	ScriptPubKey    string `json:"scriptpubkey" yaml:"scriptpubkey"`
+	ScriptPubKeyASM string `json:"scriptpubkey_asm" yaml:"scriptpubkey_asm"`
+	Type            string `json:"type" yaml:"type"`
+	Address         string `json:"address" yaml:"address"`
+	IsWallet        bool   `json:"is_wallet" yaml:"is_wallet"`
 }
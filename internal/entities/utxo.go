@@ -1,10 +1,10 @@
 package entities
 
 type TxOutput struct {
-	TxID   string   `json:"txid"`
-	Vout   int      `json:"vout"`
-	Status TxStatus `json:"status"`
-	Value  int64    `json:"value"`
+	TxID   string   `json:"txid" yaml:"txid"`
+	Vout   int      `json:"vout" yaml:"vout"`
+	Status TxStatus `json:"status" yaml:"status"`
+	Value  int64    `json:"value" yaml:"value"`
 }
 
 type TxOutputs []TxOutput
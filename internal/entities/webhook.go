@@ -0,0 +1,30 @@
+package entities
+
+type WebhookEventType string
+
+const (
+	WebhookEventReceived  WebhookEventType = "funds_received"
+	WebhookEventConfirmed WebhookEventType = "transaction_confirmed"
+	WebhookEventReplaced  WebhookEventType = "transaction_replaced"
+	WebhookEventDropped   WebhookEventType = "transaction_dropped"
+)
+
+// WebhookPayload is a JSON body which is posted to webhook URLs.
+type WebhookPayload struct {
+	ID            string           `json:"id"` // the same for every delivery attempt
+	Event         WebhookEventType `json:"event"`
+	Time          int64            `json:"time"`
+	TxID          string           `json:"txid"`
+	Amount        int64            `json:"amount"` // wallet balance change by transaction
+	Confirmations int              `json:"confirmations"`
+	ReplacedBy    string           `json:"replaced_by,omitempty"`
+}
+
+// WebhookDelivery is an item of the persistent delivery queue.
+type WebhookDelivery struct {
+	URL         string         `json:"url"`
+	Payload     WebhookPayload `json:"payload"`
+	Attempts    int            `json:"attempts"`
+	NextAttempt int64          `json:"next_attempt"` // Unix time
+	LastError   string         `json:"last_error,omitempty"`
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/constants"
+)
+
+// New creates structured logger which writes records of level and higher in format (text or json) to w.
+func New(w io.Writer, level, format string) (logger *slog.Logger, err error) {
+	var logLevel slog.Level
+	if level == "" {
+		level = constants.DefaultLogLevel
+	}
+	if err = logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+
+	switch format {
+	case constants.LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case constants.LogFormatText, "":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
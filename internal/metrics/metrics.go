@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+const namespace = "wallet"
+
+const (
+	BroadcastSuccess = "success"
+	BroadcastFailure = "failure"
+)
+
+var (
+	BackendRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "backend",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of chain backend requests by endpoint.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"endpoint"})
+
+	BackendRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "backend",
+		Name:      "request_errors_total",
+		Help:      "Chain backend requests failed by network error, rate limit or server error, by endpoint.",
+	}, []string{"endpoint"})
+
+	Broadcasts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broadcasts_total",
+		Help:      "Broadcasted transactions by result.",
+	}, []string{"result"})
+
+	FeesPaid = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fees_paid_satoshi_total",
+		Help:      "Fees of transactions sent by the wallet.",
+	})
+
+	balance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "balance_satoshi",
+		Help:      "Wallet balance at the last sync by state: confirmed (available) or unconfirmed (on hold).",
+	}, []string{"state"})
+
+	utxos = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "utxos",
+		Help:      "Number of wallet unspent outputs at the last sync.",
+	})
+
+	lastSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_sync_timestamp_seconds",
+		Help:      "Unix time of the last successful sync with the chain backend.",
+	})
+
+	lastSyncUnix atomic.Int64
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	syncLag := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_lag_seconds",
+		Help:      "Seconds since the last successful sync with the chain backend, -1 before the first sync.",
+	}, func() float64 {
+		last := lastSyncUnix.Load()
+		if last == 0 {
+			return -1
+		}
+		return time.Since(time.Unix(last, 0)).Seconds()
+	})
+
+	// series exist before the first broadcast
+	Broadcasts.WithLabelValues(BroadcastSuccess)
+	Broadcasts.WithLabelValues(BroadcastFailure)
+
+	registry.MustRegister(
+		BackendRequestDuration,
+		BackendRequestErrors,
+		Broadcasts,
+		FeesPaid,
+		balance,
+		utxos,
+		lastSync,
+		syncLag,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves metrics in Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveSync updates wallet state metrics after successful sync.
+func ObserveSync(walletBalance entities.BalanceResult, utxoCount int) {
+	balance.WithLabelValues("confirmed").Set(float64(walletBalance.Confirmed))
+	balance.WithLabelValues("unconfirmed").Set(float64(walletBalance.Unconfirmed))
+	utxos.Set(float64(utxoCount))
+
+	now := time.Now().Unix()
+	lastSync.Set(float64(now))
+	lastSyncUnix.Store(now)
+}
+
+var txIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// Endpoint converts request path to endpoint label without transaction IDs, addresses and numbers,
+// e.g. "tx/<txid>/outspend/0" to "tx/:txid/outspend/:n".
+func Endpoint(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		switch {
+		case txIDPattern.MatchString(segment):
+			segments[i] = ":txid"
+		case i > 0 && segments[i-1] == "address":
+			segments[i] = ":address"
+		case strings.Trim(segment, "0123456789") == "" && segment != "":
+			segments[i] = ":n"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
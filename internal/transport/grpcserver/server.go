@@ -0,0 +1,343 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	walletv1 "github.com/tatun2000/bitcoin-testnet-wallet/pkg/api/wallet/v1"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	IWalletService interface {
+		GetWalletAddress(ctx context.Context) (result string, err error)
+		GetWalletBalance(ctx context.Context) (confirmed, unconfirmed int64, err error)
+		GetWalletUTXOs(ctx context.Context) (result entities.TxOutputs, err error)
+		GetHistory(ctx context.Context) (result []entities.HistoryEntry, err error)
+		PrepareSend(ctx context.Context, address string, amount int64, subtractFee bool) (preview entities.TxPreview, err error)
+		Broadcast(ctx context.Context, preview entities.TxPreview) (txid string, err error)
+		Release(preview entities.TxPreview)
+		SignPSBT(ctx context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (result string, signed int, complete bool, err error)
+	}
+
+	IWatcherService interface {
+		Subscribe() (events <-chan entities.WalletEvent, unsubscribe func())
+	}
+
+	Server struct {
+		walletv1.UnimplementedWalletServiceServer
+
+		walletService  IWalletService
+		watcherService IWatcherService
+		token          string
+		server         *grpc.Server
+		stopping       chan struct{} // closed when the server is stopping
+	}
+)
+
+// NewServer creates gRPC server, every call must have metadata "authorization: Bearer <token>".
+func NewServer(walletService IWalletService, watcherService IWatcherService, token string) *Server {
+	s := &Server{
+		walletService:  walletService,
+		watcherService: watcherService,
+		token:          token,
+		stopping:       make(chan struct{}),
+	}
+
+	s.server = grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+
+			resp, err := handler(ctx, req)
+			if code := status.Code(err); code == codes.Internal || code == codes.Unavailable {
+				slog.Error("gRPC call failed", "method", info.FullMethod, "error", err)
+			}
+
+			return resp, err
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authorize(stream.Context()); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	)
+	walletv1.RegisterWalletServiceServer(s.server, s)
+
+	return s
+}
+
+// Run serves calls on listen address until ctx is done, then waits for active calls.
+// Event streams are closed by ctx, so they don't block stopping.
+func (s *Server) Run(ctx context.Context, listen string) (err error) {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return wrap.Wrap(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.server.Serve(listener)
+	}()
+
+	select {
+	case err = <-errCh:
+		return wrap.Wrap(err)
+	case <-ctx.Done():
+	}
+
+	close(s.stopping)
+	s.server.GracefulStop()
+
+	return nil
+}
+
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1 {
+			return nil
+		}
+	}
+
+	return status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+}
+
+func (s *Server) GetAddress(ctx context.Context, _ *walletv1.GetAddressRequest) (*walletv1.GetAddressResponse, error) {
+	address, err := s.walletService.GetWalletAddress(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &walletv1.GetAddressResponse{Address: address}, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, _ *walletv1.GetBalanceRequest) (*walletv1.GetBalanceResponse, error) {
+	confirmed, unconfirmed, err := s.walletService.GetWalletBalance(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &walletv1.GetBalanceResponse{
+		Confirmed:   confirmed,
+		Unconfirmed: unconfirmed,
+	}, nil
+}
+
+func (s *Server) ListUTXOs(ctx context.Context, _ *walletv1.ListUTXOsRequest) (*walletv1.ListUTXOsResponse, error) {
+	utxos, err := s.walletService.GetWalletUTXOs(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &walletv1.ListUTXOsResponse{
+		Utxos: lo.Map(utxos, func(utxo entities.TxOutput, _ int) *walletv1.UTXO {
+			return &walletv1.UTXO{
+				Txid:   utxo.TxID,
+				Vout:   uint32(utxo.Vout),
+				Value:  utxo.Value,
+				Status: toTxStatus(utxo.Status),
+			}
+		}),
+	}, nil
+}
+
+func (s *Server) GetHistory(ctx context.Context, _ *walletv1.GetHistoryRequest) (*walletv1.GetHistoryResponse, error) {
+	history, err := s.walletService.GetHistory(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &walletv1.GetHistoryResponse{
+		Entries: lo.Map(history, func(entry entities.HistoryEntry, _ int) *walletv1.HistoryEntry {
+			return &walletv1.HistoryEntry{
+				Txid:          entry.TxID,
+				Time:          entry.Time,
+				Amount:        entry.Amount,
+				Direction:     toTxDirection(entry.Direction),
+				Fee:           entry.Fee,
+				Confirmations: int64(entry.Confirmations),
+				State:         toTxState(entry.State),
+				ReplacedBy:    entry.ReplacedBy,
+			}
+		}),
+	}, nil
+}
+
+func (s *Server) Send(ctx context.Context, req *walletv1.SendRequest) (*walletv1.SendResponse, error) {
+	if req.GetAmount() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+
+	address, err := btcutil.DecodeAddress(req.GetAddress(), &chaincfg.TestNet3Params)
+	if err != nil || !address.IsForNet(&chaincfg.TestNet3Params) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid testnet address %q", req.GetAddress())
+	}
+
+	preview, err := s.walletService.PrepareSend(ctx, req.GetAddress(), req.GetAmount(), req.GetSubtractFee())
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	resp := &walletv1.SendResponse{
+		Txid: preview.TxID,
+		Inputs: lo.Map(preview.Inputs, func(input entities.TxPreviewInput, _ int) *walletv1.TxInput {
+			return &walletv1.TxInput{
+				Txid:    input.TxID,
+				Vout:    input.Vout,
+				Address: input.Address,
+				Value:   input.Value,
+			}
+		}),
+		Outputs: lo.Map(preview.Outputs, func(output entities.TxPreviewOutput, _ int) *walletv1.TxOutput {
+			return &walletv1.TxOutput{
+				Address:  output.Address,
+				Value:    output.Value,
+				IsChange: output.IsChange,
+			}
+		}),
+		Vsize:   preview.VSize,
+		Fee:     preview.Fee,
+		FeeRate: preview.FeeRate,
+		Hex:     preview.RawHex,
+	}
+
+	if req.GetDryRun() {
+		s.walletService.Release(preview)
+		return resp, nil
+	}
+
+	if _, err = s.walletService.Broadcast(ctx, preview); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	resp.Broadcasted = true
+
+	return resp, nil
+}
+
+func (s *Server) SignPSBT(ctx context.Context, req *walletv1.SignPSBTRequest) (*walletv1.SignPSBTResponse, error) {
+	if req.GetPsbt() == "" {
+		return nil, status.Error(codes.InvalidArgument, "psbt is required")
+	}
+
+	signedPSBT, signed, complete, err := s.walletService.SignPSBT(ctx, req.GetPsbt(), txscript.SigHashAll, req.GetFinalize())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &walletv1.SignPSBTResponse{
+		Psbt:     signedPSBT,
+		Signed:   int32(signed),
+		Complete: complete,
+	}, nil
+}
+
+func (s *Server) SubscribeEvents(req *walletv1.SubscribeEventsRequest, stream grpc.ServerStreamingServer[walletv1.WalletEvent]) error {
+	events, unsubscribe := s.watcherService.Subscribe()
+	defer unsubscribe()
+
+	types := lo.SliceToMap(req.GetTypes(), func(eventType walletv1.EventType) (walletv1.EventType, struct{}) {
+		return eventType, struct{}{}
+	})
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "server is stopping")
+		case event := <-events:
+			resp := toWalletEvent(event)
+			if _, ok := types[resp.GetType()]; len(types) > 0 && !ok {
+				continue
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toTxStatus(txStatus entities.TxStatus) *walletv1.TxStatus {
+	return &walletv1.TxStatus{
+		Confirmed:   txStatus.Confirmed,
+		BlockHeight: int64(txStatus.BlockHeight),
+		BlockHash:   txStatus.BlockHash,
+		BlockTime:   txStatus.BlockTime,
+	}
+}
+
+func toTxDirection(direction entities.TxDirection) walletv1.TxDirection {
+	switch direction {
+	case entities.TxDirectionIncoming:
+		return walletv1.TxDirection_TX_DIRECTION_INCOMING
+	case entities.TxDirectionOutgoing:
+		return walletv1.TxDirection_TX_DIRECTION_OUTGOING
+	case entities.TxDirectionSelfTransfer:
+		return walletv1.TxDirection_TX_DIRECTION_SELF_TRANSFER
+	default:
+		return walletv1.TxDirection_TX_DIRECTION_UNSPECIFIED
+	}
+}
+
+func toTxState(state entities.TxState) walletv1.TxState {
+	switch state {
+	case entities.TxStateMempool:
+		return walletv1.TxState_TX_STATE_MEMPOOL
+	case entities.TxStateConfirmed:
+		return walletv1.TxState_TX_STATE_CONFIRMED
+	case entities.TxStateReplaced:
+		return walletv1.TxState_TX_STATE_REPLACED
+	case entities.TxStateDropped:
+		return walletv1.TxState_TX_STATE_DROPPED
+	default:
+		return walletv1.TxState_TX_STATE_UNSPECIFIED
+	}
+}
+
+func toWalletEvent(event entities.WalletEvent) *walletv1.WalletEvent {
+	resp := &walletv1.WalletEvent{
+		Time:          event.Time,
+		Txid:          event.TxID,
+		Amount:        event.Amount,
+		Confirmations: int64(event.Confirmations),
+		ReplacedBy:    event.ReplacedBy,
+	}
+
+	switch event.Type {
+	case entities.WalletEventNewTransaction:
+		resp.Type = walletv1.EventType_EVENT_TYPE_NEW_TRANSACTION
+	case entities.WalletEventConfirmation:
+		resp.Type = walletv1.EventType_EVENT_TYPE_CONFIRMATION
+	case entities.WalletEventBalanceChange:
+		resp.Type = walletv1.EventType_EVENT_TYPE_BALANCE_CHANGE
+	case entities.WalletEventReplaced:
+		resp.Type = walletv1.EventType_EVENT_TYPE_REPLACED
+	case entities.WalletEventDropped:
+		resp.Type = walletv1.EventType_EVENT_TYPE_DROPPED
+	}
+
+	if event.Balance != nil {
+		resp.Balance = &walletv1.GetBalanceResponse{
+			Confirmed:   event.Balance.Confirmed,
+			Unconfirmed: event.Balance.Unconfirmed,
+		}
+	}
+
+	return resp
+}
@@ -0,0 +1,334 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	walletv1 "github.com/tatun2000/bitcoin-testnet-wallet/pkg/api/wallet/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	testToken        = "secret"
+	recipientAddress = "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+)
+
+var errWallet = errors.New("wallet error")
+
+// fakeWallet returns err from every call and records sends and signed PSBTs.
+type fakeWallet struct {
+	err          error
+	broadcastErr error
+
+	prepared    int
+	broadcasted int
+	released    int
+	sigHashType txscript.SigHashType
+}
+
+func (f *fakeWallet) GetWalletAddress(context.Context) (string, error) {
+	return recipientAddress, f.err
+}
+
+func (f *fakeWallet) GetWalletBalance(context.Context) (confirmed, unconfirmed int64, err error) {
+	return 1_000, 200, f.err
+}
+
+func (f *fakeWallet) GetWalletUTXOs(context.Context) (entities.TxOutputs, error) {
+	return entities.TxOutputs{{TxID: "aa", Vout: 1, Value: 1_000}}, f.err
+}
+
+func (f *fakeWallet) GetHistory(context.Context) ([]entities.HistoryEntry, error) {
+	return []entities.HistoryEntry{{TxID: "aa", State: entities.TxStateReplaced, ReplacedBy: "bb"}}, f.err
+}
+
+func (f *fakeWallet) PrepareSend(context.Context, string, int64, bool) (entities.TxPreview, error) {
+	f.prepared++
+	return entities.TxPreview{TxID: "cc", Fee: 141}, f.err
+}
+
+func (f *fakeWallet) Broadcast(_ context.Context, preview entities.TxPreview) (string, error) {
+	f.broadcasted++
+	return preview.TxID, f.broadcastErr
+}
+
+func (f *fakeWallet) Release(entities.TxPreview) {
+	f.released++
+}
+
+func (f *fakeWallet) SignPSBT(_ context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (string, int, bool, error) {
+	f.sigHashType = sigHashType
+	return b64PSBT, 1, finalize, f.err
+}
+
+// fakeWatcher passes events to the subscriber, subscribed is signaled when a stream subscribes.
+type fakeWatcher struct {
+	events     chan entities.WalletEvent
+	subscribed chan struct{}
+}
+
+func (f *fakeWatcher) Subscribe() (<-chan entities.WalletEvent, func()) {
+	f.subscribed <- struct{}{}
+	return f.events, func() {}
+}
+
+// newTestClient serves s in memory and returns client of it.
+func newTestClient(t *testing.T, s *Server) walletv1.WalletServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	go s.server.Serve(listener) //nolint:errcheck // stopped by cleanup
+	t.Cleanup(s.server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return walletv1.NewWalletServiceClient(conn)
+}
+
+// withToken returns context with bearer token metadata.
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthorization(t *testing.T) {
+	watcher := &fakeWatcher{events: make(chan entities.WalletEvent), subscribed: make(chan struct{}, 1)}
+	client := newTestClient(t, NewServer(&fakeWallet{}, watcher, testToken))
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		withToken("wrong"),
+		withToken(testToken + "x"),
+		metadata.AppendToOutgoingContext(context.Background(), "authorization", testToken),
+	} {
+		if _, err := client.GetBalance(ctx, &walletv1.GetBalanceRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("unary call error is %v, want %s", err, codes.Unauthenticated)
+		}
+
+		stream, err := client.SubscribeEvents(ctx, &walletv1.SubscribeEventsRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("stream error is %v, want %s", err, codes.Unauthenticated)
+		}
+	}
+	if len(watcher.subscribed) != 0 {
+		t.Fatal("unauthenticated stream is subscribed")
+	}
+
+	resp, err := client.GetBalance(withToken(testToken), &walletv1.GetBalanceRequest{})
+	if err != nil || resp.GetConfirmed() != 1_000 || resp.GetUnconfirmed() != 200 {
+		t.Fatalf("balance is %v, error %v", resp, err)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	ctx := withToken(testToken)
+
+	for _, tc := range []struct {
+		name     string
+		wallet   *fakeWallet
+		call     func(client walletv1.WalletServiceClient) error
+		wantCode codes.Code
+	}{
+		{
+			name:   "address",
+			wallet: &fakeWallet{err: errWallet},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.GetAddress(ctx, &walletv1.GetAddressRequest{})
+				return err
+			},
+			wantCode: codes.Internal,
+		},
+		{
+			name:   "history",
+			wallet: &fakeWallet{err: errWallet},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.GetHistory(ctx, &walletv1.GetHistoryRequest{})
+				return err
+			},
+			wantCode: codes.Internal,
+		},
+		{
+			name:   "zero amount",
+			wallet: &fakeWallet{},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.Send(ctx, &walletv1.SendRequest{Address: recipientAddress})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "mainnet address",
+			wallet: &fakeWallet{},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.Send(ctx, &walletv1.SendRequest{Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", Amount: 1_000})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "prepare send",
+			wallet: &fakeWallet{err: errWallet},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.Send(ctx, &walletv1.SendRequest{Address: recipientAddress, Amount: 1_000})
+				return err
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:   "broadcast",
+			wallet: &fakeWallet{broadcastErr: errWallet},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.Send(ctx, &walletv1.SendRequest{Address: recipientAddress, Amount: 1_000})
+				return err
+			},
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:   "missing PSBT",
+			wallet: &fakeWallet{},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.SignPSBT(ctx, &walletv1.SignPSBTRequest{})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "invalid PSBT",
+			wallet: &fakeWallet{err: errWallet},
+			call: func(client walletv1.WalletServiceClient) error {
+				_, err := client.SignPSBT(ctx, &walletv1.SignPSBTRequest{Psbt: "cHNidP8="})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, NewServer(tc.wallet, nil, testToken))
+
+			if err := tc.call(client); status.Code(err) != tc.wantCode {
+				t.Fatalf("error is %v, want %s", err, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestCalls(t *testing.T) {
+	ctx := withToken(testToken)
+	wallet := &fakeWallet{}
+	client := newTestClient(t, NewServer(wallet, nil, testToken))
+
+	history, err := client.GetHistory(ctx, &walletv1.GetHistoryRequest{})
+	if err != nil || len(history.GetEntries()) != 1 || history.GetEntries()[0].GetState() != walletv1.TxState_TX_STATE_REPLACED ||
+		history.GetEntries()[0].GetReplacedBy() != "bb" {
+		t.Fatalf("history is %v, error %v", history, err)
+	}
+
+	// dry run releases UTXOs and doesn't broadcast
+	send, err := client.Send(ctx, &walletv1.SendRequest{Address: recipientAddress, Amount: 1_000, DryRun: true})
+	if err != nil || send.GetBroadcasted() || send.GetTxid() != "cc" || wallet.released != 1 || wallet.broadcasted != 0 {
+		t.Fatalf("dry run is %v, error %v, released %d, broadcasted %d", send, err, wallet.released, wallet.broadcasted)
+	}
+	if send, err = client.Send(ctx, &walletv1.SendRequest{Address: recipientAddress, Amount: 1_000}); err != nil || !send.GetBroadcasted() || wallet.broadcasted != 1 {
+		t.Fatalf("send is %v, error %v, broadcasted %d", send, err, wallet.broadcasted)
+	}
+
+	// only SIGHASH_ALL is signed
+	signed, err := client.SignPSBT(ctx, &walletv1.SignPSBTRequest{Psbt: "cHNidP8=", Finalize: true})
+	if err != nil || signed.GetSigned() != 1 || !signed.GetComplete() || wallet.sigHashType != txscript.SigHashAll {
+		t.Fatalf("signed PSBT is %v, error %v, sighash %#x", signed, err, wallet.sigHashType)
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	events := []entities.WalletEvent{
+		{Type: entities.WalletEventNewTransaction, TxID: "aa", Amount: 1_000},
+		{Type: entities.WalletEventBalanceChange, Balance: &entities.BalanceResult{Unconfirmed: 1_000}},
+		{Type: entities.WalletEventConfirmation, TxID: "aa", Confirmations: 1},
+		{Type: entities.WalletEventReplaced, TxID: "bb", ReplacedBy: "cc"},
+		{Type: entities.WalletEventDropped, TxID: "dd"},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		types []walletv1.EventType
+		want  []walletv1.EventType
+	}{
+		{
+			name: "all events",
+			want: []walletv1.EventType{
+				walletv1.EventType_EVENT_TYPE_NEW_TRANSACTION,
+				walletv1.EventType_EVENT_TYPE_BALANCE_CHANGE,
+				walletv1.EventType_EVENT_TYPE_CONFIRMATION,
+				walletv1.EventType_EVENT_TYPE_REPLACED,
+				walletv1.EventType_EVENT_TYPE_DROPPED,
+			},
+		},
+		{
+			name:  "filtered",
+			types: []walletv1.EventType{walletv1.EventType_EVENT_TYPE_REPLACED, walletv1.EventType_EVENT_TYPE_BALANCE_CHANGE},
+			want:  []walletv1.EventType{walletv1.EventType_EVENT_TYPE_BALANCE_CHANGE, walletv1.EventType_EVENT_TYPE_REPLACED},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			watcher := &fakeWatcher{events: make(chan entities.WalletEvent), subscribed: make(chan struct{}, 1)}
+			s := NewServer(&fakeWallet{}, watcher, testToken)
+			client := newTestClient(t, s)
+
+			ctx, cancel := context.WithTimeout(withToken(testToken), 5*time.Second)
+			defer cancel()
+			stream, err := client.SubscribeEvents(ctx, &walletv1.SubscribeEventsRequest{Types: tc.types})
+			if err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+			<-watcher.subscribed
+
+			// the events channel is unbuffered, so every event is handled before the next one
+			for _, event := range events {
+				watcher.events <- event
+			}
+
+			for _, want := range tc.want {
+				event, err := stream.Recv()
+				if err != nil {
+					t.Fatalf("receive: %v", err)
+				}
+				if event.GetType() != want {
+					t.Fatalf("event is %v, want %s", event, want)
+				}
+				if want == walletv1.EventType_EVENT_TYPE_REPLACED && event.GetReplacedBy() != "cc" {
+					t.Fatalf("replaced event is %v", event)
+				}
+				if want == walletv1.EventType_EVENT_TYPE_BALANCE_CHANGE && event.GetBalance().GetUnconfirmed() != 1_000 {
+					t.Fatalf("balance event is %v", event)
+				}
+			}
+
+			// the stream is closed when the server is stopping
+			close(s.stopping)
+			if _, err = stream.Recv(); status.Code(err) != codes.Unavailable {
+				t.Fatalf("stream error is %v after the last event, want %s", err, codes.Unavailable)
+			}
+		})
+	}
+}
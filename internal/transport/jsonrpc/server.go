@@ -0,0 +1,515 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/samber/lo"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/wallet"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+// Bitcoin Core RPC error codes (src/rpc/protocol.h).
+const (
+	codeMiscError           = -1
+	codeTypeError           = -3
+	codeWalletError         = -4
+	codeInvalidAddressOrKey = -5
+	codeInsufficientFunds   = -6
+	codeInvalidParameter    = -8
+	codeDeserialization     = -22
+	codeInvalidRequest      = -32600
+	codeMethodNotFound      = -32601
+	codeParseError          = -32700
+)
+
+type (
+	IWalletService interface {
+		GetWalletAddress(ctx context.Context) (result string, err error)
+		GetWalletBalance(ctx context.Context) (confirmed, unconfirmed int64, err error)
+		GetWalletUTXOs(ctx context.Context) (result entities.TxOutputs, err error)
+		GetHistory(ctx context.Context) (result []entities.HistoryEntry, err error)
+		GetTipHeight(ctx context.Context) (result int, err error)
+		GetTransactionHex(ctx context.Context, txID string) (result string, err error)
+		SendTo(ctx context.Context, address string, amount int64, subtractFee bool) (txid string, err error)
+		SignPSBT(ctx context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (result string, signed int, complete bool, err error)
+	}
+
+	Server struct {
+		walletService IWalletService
+		user          string
+		password      string
+		server        *http.Server
+		methods       map[string]method
+	}
+
+	method struct {
+		params  []string // parameter names, used to convert named parameters to positional ones
+		handler func(ctx context.Context, params []json.RawMessage) (result any, err error)
+	}
+
+	request struct {
+		JSONRPC string          `json:"jsonrpc,omitempty"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+
+	response struct {
+		Result any             `json:"result"`
+		Error  *Error          `json:"error"`
+		ID     json.RawMessage `json:"id"`
+	}
+)
+
+// Error is bitcoind JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code int, err error) *Error {
+	return &Error{Code: code, Message: err.Error()}
+}
+
+// NewServer creates JSON-RPC server with a subset of Bitcoin Core wallet RPCs,
+// requests must use HTTP basic authentication like bitcoind rpcuser/rpcpassword.
+func NewServer(walletService IWalletService, listen, user, password string) *Server {
+	s := &Server{
+		walletService: walletService,
+		user:          user,
+		password:      password,
+	}
+
+	s.methods = map[string]method{
+		"getnewaddress":     {params: []string{"label", "address_type"}, handler: s.getNewAddress},
+		"getbalance":        {params: []string{"dummy", "minconf", "include_watchonly", "avoid_reuse"}, handler: s.getBalance},
+		"listunspent":       {params: []string{"minconf", "maxconf", "addresses", "include_unsafe", "query_options"}, handler: s.listUnspent},
+		"sendtoaddress":     {params: []string{"address", "amount", "comment", "comment_to", "subtractfeefromamount", "replaceable", "conf_target", "estimate_mode", "avoid_reuse", "fee_rate", "verbose"}, handler: s.sendToAddress},
+		"listtransactions":  {params: []string{"label", "count", "skip", "include_watchonly"}, handler: s.listTransactions},
+		"gettransaction":    {params: []string{"txid", "include_watchonly", "verbose"}, handler: s.getTransaction},
+		"walletprocesspsbt": {params: []string{"psbt", "sign", "sighashtype", "bip32derivs", "finalize"}, handler: s.walletProcessPSBT},
+	}
+
+	s.server = &http.Server{
+		Addr:              listen,
+		Handler:           http.HandlerFunc(s.handle),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// Run serves requests until ctx is done, then waits for active requests.
+func (s *Server) Run(ctx context.Context) (err error) {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errCh:
+		return wrap.Wrap(err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err = s.server.Shutdown(shutdownCtx); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok ||
+		subtle.ConstantTimeCompare([]byte(user), []byte(s.user)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="jsonrpc"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusInternalServerError, response{Error: newError(codeParseError, err)})
+		return
+	}
+
+	// batch request
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []request
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			writeJSON(w, http.StatusInternalServerError, response{Error: newError(codeParseError, err)})
+			return
+		}
+
+		resps := make([]response, 0, len(reqs))
+		for _, req := range reqs {
+			resps = append(resps, s.call(r.Context(), req))
+		}
+		writeJSON(w, http.StatusOK, resps)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusInternalServerError, response{Error: newError(codeParseError, err)})
+		return
+	}
+
+	resp := s.call(r.Context(), req)
+	switch {
+	case resp.Error == nil:
+		writeJSON(w, http.StatusOK, resp)
+	case resp.Error.Code == codeMethodNotFound:
+		writeJSON(w, http.StatusNotFound, resp)
+	default:
+		writeJSON(w, http.StatusInternalServerError, resp)
+	}
+}
+
+func (s *Server) call(ctx context.Context, req request) (resp response) {
+	resp.ID = req.ID
+
+	m, ok := s.methods[req.Method]
+	if !ok {
+		resp.Error = &Error{Code: codeMethodNotFound, Message: "Method not found"}
+		return resp
+	}
+
+	params, err := positionalParams(req.Params, m.params)
+	if err != nil {
+		resp.Error = newError(codeInvalidRequest, err)
+		return resp
+	}
+
+	result, err := m.handler(ctx, params)
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			resp.Error = rpcErr
+		} else {
+			resp.Error = newError(codeMiscError, err)
+		}
+		if resp.Error.Code == codeMiscError || resp.Error.Code == codeWalletError {
+			slog.Error("JSON-RPC call failed", "method", req.Method, "error", err)
+		}
+		return resp
+	}
+
+	resp.Result = result
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, status int, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result) //nolint:errcheck // client can be disconnected
+}
+
+func (s *Server) getNewAddress(ctx context.Context, _ []json.RawMessage) (result any, err error) {
+	// the wallet has the only address
+	address, err := s.walletService.GetWalletAddress(ctx)
+	if err != nil {
+		return nil, newError(codeWalletError, err)
+	}
+
+	return address, nil
+}
+
+func (s *Server) getBalance(ctx context.Context, params []json.RawMessage) (result any, err error) {
+	minConf := 0
+	if err = param(params, 1, &minConf); err != nil {
+		return nil, err
+	}
+
+	confirmed, unconfirmed, err := s.walletService.GetWalletBalance(ctx)
+	if err != nil {
+		return nil, newError(codeWalletError, err)
+	}
+
+	if minConf == 0 {
+		return Amount(confirmed + unconfirmed), nil
+	}
+
+	return Amount(confirmed), nil
+}
+
+func (s *Server) listUnspent(ctx context.Context, params []json.RawMessage) (result any, err error) {
+	minConf, maxConf := 1, 9999999
+	if err = param(params, 0, &minConf); err != nil {
+		return nil, err
+	}
+	if err = param(params, 1, &maxConf); err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	if err = param(params, 2, &addresses); err != nil {
+		return nil, err
+	}
+
+	walletAddress, err := s.walletService.GetWalletAddress(ctx)
+	if err != nil {
+		return nil, newError(codeWalletError, err)
+	}
+
+	if len(addresses) > 0 && !lo.Contains(addresses, walletAddress) {
+		return []UnspentResult{}, nil
+	}
+
+	utxos, err := s.walletService.GetWalletUTXOs(ctx)
+	if err != nil {
+		return nil, newError(codeWalletError, err)
+	}
+
+	tipHeight, err := s.walletService.GetTipHeight(ctx)
+	if err != nil {
+		return nil, newError(codeWalletError, err)
+	}
+
+	scriptPubKey, err := addressScript(walletAddress)
+	if err != nil {
+		return nil, newError(codeWalletError, err)
+	}
+
+	unspent := make([]UnspentResult, 0, len(utxos))
+	for _, utxo := range utxos {
+		confirmations := 0
+		if utxo.Status.Confirmed {
+			confirmations = tipHeight - utxo.Status.BlockHeight + 1
+		}
+		if confirmations < minConf || confirmations > maxConf {
+			continue
+		}
+
+		unspent = append(unspent, UnspentResult{
+			TxID:          utxo.TxID,
+			Vout:          utxo.Vout,
+			Address:       walletAddress,
+			ScriptPubKey:  scriptPubKey,
+			Amount:        Amount(utxo.Value),
+			Confirmations: confirmations,
+			Spendable:     true,
+			Solvable:      true,
+			Safe:          utxo.Status.Confirmed,
+		})
+	}
+
+	return unspent, nil
+}
+
+func (s *Server) sendToAddress(ctx context.Context, params []json.RawMessage) (result any, err error) {
+	var (
+		address     string
+		btcAmount   float64
+		subtractFee bool
+	)
+	if err = requiredParam(params, 0, "address", &address); err != nil {
+		return nil, err
+	}
+	if err = requiredParam(params, 1, "amount", &btcAmount); err != nil {
+		return nil, err
+	}
+	if err = param(params, 4, &subtractFee); err != nil {
+		return nil, err
+	}
+
+	decoded, err := btcutil.DecodeAddress(address, &chaincfg.TestNet3Params)
+	if err != nil || !decoded.IsForNet(&chaincfg.TestNet3Params) {
+		return nil, &Error{Code: codeInvalidAddressOrKey, Message: "Invalid address"}
+	}
+
+	amount, err := btcutil.NewAmount(btcAmount)
+	if err != nil || amount <= 0 {
+		return nil, &Error{Code: codeTypeError, Message: "Invalid amount for send"}
+	}
+
+	txid, err := s.walletService.SendTo(ctx, address, int64(amount), subtractFee)
+	if err != nil {
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			return nil, &Error{Code: codeInsufficientFunds, Message: "Insufficient funds"}
+		}
+		return nil, newError(codeWalletError, err)
+	}
+
+	return txid, nil
+}
+
+func (s *Server) listTransactions(ctx context.Context, params []json.RawMessage) (result any, err error) {
+	count, skip := 10, 0
+	if err = param(params, 1, &count); err != nil {
+		return nil, err
+	}
+	if err = param(params, 2, &skip); err != nil {
+		return nil, err
+	}
+	if count < 0 || skip < 0 {
+		return nil, &Error{Code: codeInvalidParameter, Message: "Negative count or skip"}
+	}
+
+	walletAddress, history, err := s.walletHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// history is newest first, bitcoind returns the most recent transactions oldest first
+	if skip > len(history) {
+		skip = len(history)
+	}
+	history = history[skip:min(skip+count, len(history))]
+
+	transactions := make([]TransactionResult, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		transactions = append(transactions, newTransactionResult(history[i], walletAddress))
+	}
+
+	return transactions, nil
+}
+
+func (s *Server) getTransaction(ctx context.Context, params []json.RawMessage) (result any, err error) {
+	var txID string
+	if err = requiredParam(params, 0, "txid", &txID); err != nil {
+		return nil, err
+	}
+
+	walletAddress, history, err := s.walletHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range history {
+		if entry.TxID != txID {
+			continue
+		}
+
+		transaction := newTransactionResult(entry, walletAddress)
+		if entry.State == entities.TxStateMempool || entry.State == entities.TxStateConfirmed {
+			transaction.Hex, err = s.walletService.GetTransactionHex(ctx, txID)
+			if err != nil {
+				return nil, newError(codeWalletError, err)
+			}
+		}
+		transaction.Details = []TransactionDetail{{
+			Address:  transaction.Address,
+			Category: transaction.Category,
+			Amount:   transaction.Amount,
+			Fee:      transaction.Fee,
+		}}
+
+		return transaction, nil
+	}
+
+	return nil, &Error{Code: codeInvalidAddressOrKey, Message: "Invalid or non-wallet transaction id"}
+}
+
+func (s *Server) walletProcessPSBT(ctx context.Context, params []json.RawMessage) (result any, err error) {
+	var b64PSBT, sigHashName string
+	sign, finalize := true, true
+	if err = requiredParam(params, 0, "psbt", &b64PSBT); err != nil {
+		return nil, err
+	}
+	if err = param(params, 1, &sign); err != nil {
+		return nil, err
+	}
+	if err = param(params, 2, &sigHashName); err != nil {
+		return nil, err
+	}
+	if err = param(params, 4, &finalize); err != nil {
+		return nil, err
+	}
+
+	// like bitcoind, inputs are signed with sighashtype and an input which requests another one is an error
+	sigHashType, err := transaction.ParseSigHashType(sigHashName)
+	if err != nil {
+		return nil, &Error{Code: codeInvalidParameter, Message: fmt.Sprintf("'%s' is not a valid sighash parameter.", sigHashName)}
+	}
+
+	processed := ProcessPSBTResult{PSBT: b64PSBT}
+	if sign {
+		processed.PSBT, _, _, err = s.walletService.SignPSBT(ctx, b64PSBT, sigHashType, finalize)
+		if errors.Is(err, transaction.ErrSigHashMismatch) {
+			return nil, &Error{Code: codeDeserialization, Message: "Specified sighash value does not match value stored in PSBT"}
+		}
+		if err != nil {
+			return nil, newError(codeDeserialization, err)
+		}
+	}
+
+	packet, err := psbt.NewFromRawBytes(strings.NewReader(processed.PSBT), true)
+	if err != nil {
+		return nil, newError(codeDeserialization, err)
+	}
+	processed.Complete = packet.IsComplete()
+
+	if processed.Complete && finalize {
+		tx, err := psbt.Extract(packet)
+		if err != nil {
+			return nil, newError(codeMiscError, err)
+		}
+
+		var buf bytes.Buffer
+		if err = tx.Serialize(&buf); err != nil {
+			return nil, newError(codeMiscError, err)
+		}
+		processed.Hex = hex.EncodeToString(buf.Bytes())
+	}
+
+	return processed, nil
+}
+
+func (s *Server) walletHistory(ctx context.Context) (walletAddress string, history []entities.HistoryEntry, err error) {
+	walletAddress, err = s.walletService.GetWalletAddress(ctx)
+	if err != nil {
+		return "", nil, newError(codeWalletError, err)
+	}
+
+	history, err = s.walletService.GetHistory(ctx)
+	if err != nil {
+		return "", nil, newError(codeWalletError, err)
+	}
+
+	return walletAddress, history, nil
+}
+
+func addressScript(address string) (result string, err error) {
+	decoded, err := btcutil.DecodeAddress(address, &chaincfg.TestNet3Params)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	script, err := txscript.PayToAddrScript(decoded)
+	if err != nil {
+		return "", wrap.Wrap(err)
+	}
+
+	return hex.EncodeToString(script), nil
+}
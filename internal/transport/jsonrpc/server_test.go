@@ -0,0 +1,371 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/wallet"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+const (
+	testUser         = "user"
+	testPassword     = "password"
+	walletAddress    = "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+	recipientAddress = "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"
+)
+
+var errWallet = errors.New("wallet error")
+
+// fakeWallet returns err from every call, sign errors are set separately.
+type fakeWallet struct {
+	err     error
+	signErr error
+
+	sends     []string
+	signCalls []txscript.SigHashType
+}
+
+func (f *fakeWallet) GetWalletAddress(context.Context) (string, error) {
+	return walletAddress, f.err
+}
+
+func (f *fakeWallet) GetWalletBalance(context.Context) (confirmed, unconfirmed int64, err error) {
+	return 100_000_000, 50_000, f.err
+}
+
+func (f *fakeWallet) GetWalletUTXOs(context.Context) (entities.TxOutputs, error) {
+	return entities.TxOutputs{
+		{TxID: "aa", Vout: 0, Value: 100_000_000, Status: entities.TxStatus{Confirmed: true, BlockHeight: 91}},
+		{TxID: "bb", Vout: 1, Value: 50_000},
+	}, f.err
+}
+
+func (f *fakeWallet) GetHistory(context.Context) ([]entities.HistoryEntry, error) {
+	return []entities.HistoryEntry{
+		{TxID: "cc", Amount: -10_141, Fee: 141, Direction: entities.TxDirectionOutgoing, State: entities.TxStateMempool},
+		{TxID: "aa", Amount: 100_000_000, Direction: entities.TxDirectionIncoming, Confirmations: 10, State: entities.TxStateConfirmed},
+	}, f.err
+}
+
+func (f *fakeWallet) GetTipHeight(context.Context) (int, error) {
+	return 100, f.err
+}
+
+func (f *fakeWallet) GetTransactionHex(_ context.Context, txID string) (string, error) {
+	return "hex-" + txID, f.err
+}
+
+func (f *fakeWallet) SendTo(_ context.Context, address string, amount int64, subtractFee bool) (string, error) {
+	f.sends = append(f.sends, fmt.Sprintf("%s %d %t", address, amount, subtractFee))
+	return "dd", f.err
+}
+
+func (f *fakeWallet) SignPSBT(_ context.Context, b64PSBT string, sigHashType txscript.SigHashType, _ bool) (string, int, bool, error) {
+	f.signCalls = append(f.signCalls, sigHashType)
+	return b64PSBT, 0, false, f.signErr
+}
+
+type testResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// rpc sends body to the server handler with basic authentication and returns HTTP status and response.
+func rpc(t *testing.T, s *Server, body string) (status int, resp testResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.SetBasicAuth(testUser, testPassword)
+	recorder := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(recorder, req)
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %q: %v", recorder.Body.String(), err)
+	}
+
+	return recorder.Code, resp
+}
+
+// testPSBT returns unsigned PSBT with one input.
+func testPSBT(t *testing.T) string {
+	t.Helper()
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1_000, []byte{txscript.OP_TRUE}))
+
+	packet, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		t.Fatalf("create PSBT: %v", err)
+	}
+	result, err := packet.B64Encode()
+	if err != nil {
+		t.Fatalf("encode PSBT: %v", err)
+	}
+
+	return result
+}
+
+func TestWalletProcessPSBT(t *testing.T) {
+	unsigned := testPSBT(t)
+
+	for _, tc := range []struct {
+		name      string
+		params    string
+		signErr   error
+		wantCode  int
+		wantCalls []txscript.SigHashType
+	}{
+		{name: "default sighash", params: `["` + unsigned + `"]`, wantCalls: []txscript.SigHashType{txscript.SigHashAll}},
+		{name: "positional sighash", params: `["` + unsigned + `", true, "NONE"]`, wantCalls: []txscript.SigHashType{txscript.SigHashNone}},
+		{
+			name:      "named sighash",
+			params:    `{"psbt":"` + unsigned + `","sighashtype":"ALL|ANYONECANPAY"}`,
+			wantCalls: []txscript.SigHashType{txscript.SigHashAll | txscript.SigHashAnyOneCanPay},
+		},
+		{name: "invalid sighash", params: `["` + unsigned + `", true, "ANYONECANPAY"]`, wantCode: codeInvalidParameter},
+		{
+			name:      "sighash mismatch",
+			params:    `["` + unsigned + `", true, "ALL"]`,
+			signErr:   fmt.Errorf("input #0: %w", transaction.ErrSigHashMismatch),
+			wantCode:  codeDeserialization,
+			wantCalls: []txscript.SigHashType{txscript.SigHashAll},
+		},
+		{name: "not signed", params: `["` + unsigned + `", false, "NONE"]`},
+		{name: "invalid PSBT", params: `["cHNidP8=", false]`, wantCode: codeDeserialization},
+		{name: "missing PSBT", params: `{"sighashtype":"ALL"}`, wantCode: codeInvalidParameter},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeWallet{signErr: tc.signErr}
+			s := NewServer(fake, "", testUser, testPassword)
+
+			_, resp := rpc(t, s, `{"id":1,"method":"walletprocesspsbt","params":`+tc.params+`}`)
+			if tc.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tc.wantCode {
+					t.Fatalf("error is %+v, want code %d", resp.Error, tc.wantCode)
+				}
+			} else if resp.Error != nil {
+				t.Fatalf("error: %+v", resp.Error)
+			}
+			if fmt.Sprint(fake.signCalls) != fmt.Sprint(tc.wantCalls) {
+				t.Fatalf("signed with %v, want %v", fake.signCalls, tc.wantCalls)
+			}
+			if tc.wantCode != 0 {
+				return
+			}
+
+			var result ProcessPSBTResult
+			if err := json.Unmarshal(resp.Result, &result); err != nil || result.PSBT != unsigned || result.Complete {
+				t.Fatalf("result is %s, error %v", resp.Result, err)
+			}
+		})
+	}
+
+	// bitcoind message is kept
+	s := NewServer(&fakeWallet{signErr: transaction.ErrSigHashMismatch}, "", testUser, testPassword)
+	if _, resp := rpc(t, s, `{"id":1,"method":"walletprocesspsbt","params":["`+unsigned+`"]}`); resp.Error == nil ||
+		resp.Error.Message != "Specified sighash value does not match value stored in PSBT" {
+		t.Fatalf("error is %+v", resp.Error)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := NewServer(&fakeWallet{}, "", testUser, testPassword)
+
+	for _, tc := range []struct{ user, password string }{
+		{user: "", password: ""},
+		{user: testUser, password: "wrong"},
+		{user: "wrong", password: testPassword},
+		{user: testUser, password: testPassword + "x"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1,"method":"getbalance"}`))
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.password)
+		}
+		recorder := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("credentials %q/%q: status %d, want 401", tc.user, tc.password, recorder.Code)
+		}
+	}
+
+	// bitcoin-cli uses POST only
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth(testUser, testPassword)
+	recorder := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET status %d, want 405", recorder.Code)
+	}
+}
+
+func TestCall(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		wantCode   int
+		wantResult string
+		wantSends  []string
+	}{
+		{name: "parse error", body: `{"id":1,`, wantStatus: http.StatusInternalServerError, wantCode: codeParseError},
+		{name: "unknown method", body: `{"id":1,"method":"dumpprivkey"}`, wantStatus: http.StatusNotFound, wantCode: codeMethodNotFound},
+		{name: "invalid params", body: `{"id":1,"method":"getbalance","params":1}`, wantStatus: http.StatusInternalServerError, wantCode: codeInvalidRequest},
+		{
+			name:       "unknown named parameter",
+			body:       `{"id":1,"method":"getbalance","params":{"minconf":1,"unknown":1}}`,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInvalidRequest,
+		},
+		{name: "balance", body: `{"id":1,"method":"getbalance"}`, wantStatus: http.StatusOK, wantResult: `1.00050000`},
+		{name: "confirmed balance", body: `{"id":1,"method":"getbalance","params":["*",1]}`, wantStatus: http.StatusOK, wantResult: `1.00000000`},
+		{name: "named minconf", body: `{"id":1,"method":"getbalance","params":{"minconf":1}}`, wantStatus: http.StatusOK, wantResult: `1.00000000`},
+		{name: "type error", body: `{"id":1,"method":"getbalance","params":{"minconf":"one"}}`, wantStatus: http.StatusInternalServerError, wantCode: codeTypeError},
+		{name: "wallet error", body: `{"id":1,"method":"getbalance"}`, err: errWallet, wantStatus: http.StatusInternalServerError, wantCode: codeWalletError},
+		{
+			name:       "send",
+			body:       `{"id":1,"method":"sendtoaddress","params":["` + recipientAddress + `",0.0001]}`,
+			wantStatus: http.StatusOK,
+			wantResult: `"dd"`,
+			wantSends:  []string{recipientAddress + " 10000 false"},
+		},
+		{
+			name:       "named send",
+			body:       `{"id":1,"method":"sendtoaddress","params":{"amount":0.0001,"subtractfeefromamount":true,"address":"` + recipientAddress + `"}}`,
+			wantStatus: http.StatusOK,
+			wantResult: `"dd"`,
+			wantSends:  []string{recipientAddress + " 10000 true"},
+		},
+		{
+			name:       "missing address",
+			body:       `{"id":1,"method":"sendtoaddress","params":{"amount":0.0001}}`,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInvalidParameter,
+		},
+		{
+			name:       "mainnet address",
+			body:       `{"id":1,"method":"sendtoaddress","params":["bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",0.0001]}`,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInvalidAddressOrKey,
+		},
+		{
+			name:       "invalid amount",
+			body:       `{"id":1,"method":"sendtoaddress","params":["` + recipientAddress + `",0]}`,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeTypeError,
+		},
+		{
+			name:       "insufficient funds",
+			body:       `{"id":1,"method":"sendtoaddress","params":["` + recipientAddress + `",0.0001]}`,
+			err:        fmt.Errorf("send: %w", wallet.ErrInsufficientFunds),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInsufficientFunds,
+			wantSends:  []string{recipientAddress + " 10000 false"},
+		},
+		{
+			name:       "unknown transaction",
+			body:       `{"id":1,"method":"gettransaction","params":["ee"]}`,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInvalidAddressOrKey,
+		},
+		{
+			name:       "negative count",
+			body:       `{"id":1,"method":"listtransactions","params":["*",-1]}`,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codeInvalidParameter,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeWallet{err: tc.err}
+			s := NewServer(fake, "", testUser, testPassword)
+
+			status, resp := rpc(t, s, tc.body)
+			if status != tc.wantStatus {
+				t.Fatalf("status %d, want %d", status, tc.wantStatus)
+			}
+			if tc.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tc.wantCode {
+					t.Fatalf("error is %+v, want code %d", resp.Error, tc.wantCode)
+				}
+			} else if resp.Error != nil || string(resp.Result) != tc.wantResult {
+				t.Fatalf("result is %s, error %+v, want %s", resp.Result, resp.Error, tc.wantResult)
+			}
+			if fmt.Sprint(fake.sends) != fmt.Sprint(tc.wantSends) {
+				t.Fatalf("sends are %v, want %v", fake.sends, tc.wantSends)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s := NewServer(&fakeWallet{}, "", testUser, testPassword)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"id":1,"method":"getnewaddress"},{"id":"2","method":"unknown"}]`))
+	req.SetBasicAuth(testUser, testPassword)
+	recorder := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(recorder, req)
+
+	var resps []testResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resps); err != nil {
+		t.Fatalf("decode %q: %v", recorder.Body.String(), err)
+	}
+	if recorder.Code != http.StatusOK || len(resps) != 2 {
+		t.Fatalf("status %d, responses %s", recorder.Code, recorder.Body.String())
+	}
+	if string(resps[0].ID) != `1` || string(resps[0].Result) != `"`+walletAddress+`"` || resps[0].Error != nil {
+		t.Fatalf("the first response is %+v", resps[0])
+	}
+	if string(resps[1].ID) != `"2"` || resps[1].Error == nil || resps[1].Error.Code != codeMethodNotFound {
+		t.Fatalf("the second response is %+v", resps[1])
+	}
+}
+
+func TestWalletMethods(t *testing.T) {
+	s := NewServer(&fakeWallet{}, "", testUser, testPassword)
+
+	// amounts are decoded as BTC
+	var unspent []map[string]any
+	_, resp := rpc(t, s, `{"id":1,"method":"listunspent"}`)
+	if err := json.Unmarshal(resp.Result, &unspent); err != nil || len(unspent) != 1 ||
+		unspent[0]["txid"] != "aa" || unspent[0]["confirmations"] != 10.0 || unspent[0]["amount"] != 1.0 {
+		t.Fatalf("listunspent is %s, error %v", resp.Result, err)
+	}
+	_, resp = rpc(t, s, `{"id":1,"method":"listunspent","params":{"minconf":0,"addresses":["`+recipientAddress+`"]}}`)
+	if string(resp.Result) != `[]` {
+		t.Fatalf("listunspent of another address is %s", resp.Result)
+	}
+
+	// the oldest transaction is the first, send amount and fee are negative
+	var transactions []map[string]any
+	_, resp = rpc(t, s, `{"id":1,"method":"listtransactions"}`)
+	if err := json.Unmarshal(resp.Result, &transactions); err != nil || len(transactions) != 2 || transactions[0]["txid"] != "aa" ||
+		transactions[1]["category"] != "send" || transactions[1]["amount"] != -0.0001 || transactions[1]["fee"] != -0.00000141 {
+		t.Fatalf("listtransactions is %s, error %v", resp.Result, err)
+	}
+	_, resp = rpc(t, s, `{"id":1,"method":"listtransactions","params":{"count":1,"skip":1}}`)
+	if err := json.Unmarshal(resp.Result, &transactions); err != nil || len(transactions) != 1 || transactions[0]["txid"] != "aa" {
+		t.Fatalf("listtransactions with skip is %s, error %v", resp.Result, err)
+	}
+
+	var tx map[string]any
+	_, resp = rpc(t, s, `{"id":1,"method":"gettransaction","params":{"txid":"cc"}}`)
+	err := json.Unmarshal(resp.Result, &tx)
+	if details, _ := tx["details"].([]any); err != nil || tx["hex"] != "hex-cc" || len(details) != 1 {
+		t.Fatalf("gettransaction is %s, error %v", resp.Result, err)
+	}
+}
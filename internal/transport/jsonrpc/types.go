@@ -0,0 +1,154 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+// Amount is satoshi amount which is encoded in BTC with 8 decimals like bitcoind does.
+type Amount int64
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(btcutil.Amount(a).ToBTC(), 'f', 8, 64)), nil
+}
+
+type UnspentResult struct {
+	TxID          string `json:"txid"`
+	Vout          int    `json:"vout"`
+	Address       string `json:"address"`
+	ScriptPubKey  string `json:"scriptPubKey"`
+	Amount        Amount `json:"amount"`
+	Confirmations int    `json:"confirmations"`
+	Spendable     bool   `json:"spendable"`
+	Solvable      bool   `json:"solvable"`
+	Safe          bool   `json:"safe"`
+}
+
+type TransactionResult struct {
+	Address         string              `json:"address,omitempty"`
+	Category        string              `json:"category"`
+	Amount          Amount              `json:"amount"`
+	Fee             *Amount             `json:"fee,omitempty"` // only for send category
+	Confirmations   int                 `json:"confirmations"`
+	BlockHash       string              `json:"blockhash,omitempty"`
+	BlockHeight     int                 `json:"blockheight,omitempty"`
+	BlockTime       int64               `json:"blocktime,omitempty"`
+	TxID            string              `json:"txid"`
+	WalletConflicts []string            `json:"walletconflicts"`
+	Time            int64               `json:"time"`
+	TimeReceived    int64               `json:"timereceived"`
+	Abandoned       *bool               `json:"abandoned,omitempty"`
+	Details         []TransactionDetail `json:"details,omitempty"` // gettransaction only
+	Hex             string              `json:"hex,omitempty"`     // gettransaction only
+}
+
+type TransactionDetail struct {
+	Address  string  `json:"address,omitempty"`
+	Category string  `json:"category"`
+	Amount   Amount  `json:"amount"`
+	Fee      *Amount `json:"fee,omitempty"`
+}
+
+type ProcessPSBTResult struct {
+	PSBT     string `json:"psbt"`
+	Complete bool   `json:"complete"`
+	Hex      string `json:"hex,omitempty"`
+}
+
+func newTransactionResult(entry entities.HistoryEntry, walletAddress string) TransactionResult {
+	result := TransactionResult{
+		Address:         walletAddress,
+		Category:        "receive",
+		Amount:          Amount(entry.Amount),
+		Confirmations:   entry.Confirmations,
+		TxID:            entry.TxID,
+		WalletConflicts: []string{},
+		Time:            entry.Time,
+		TimeReceived:    entry.Time,
+	}
+
+	if entry.Amount < 0 {
+		// bitcoind reports send amount and fee as negative values, amount without fee
+		fee := Amount(-entry.Fee)
+		result.Category = "send"
+		result.Amount = Amount(entry.Amount + entry.Fee)
+		result.Fee = &fee
+		abandoned := false
+		result.Abandoned = &abandoned
+	}
+
+	switch entry.State {
+	case entities.TxStateReplaced:
+		// conflicted transactions have negative confirmations in bitcoind
+		result.Confirmations = -1
+		result.WalletConflicts = []string{entry.ReplacedBy}
+	case entities.TxStateDropped:
+		result.Confirmations = -1
+	}
+
+	return result
+}
+
+// positionalParams converts params (array or object) to positional ones.
+func positionalParams(raw json.RawMessage, names []string) (params []json.RawMessage, err error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	if raw[0] == '[' {
+		if err = json.Unmarshal(raw, &params); err != nil {
+			return nil, fmt.Errorf("params must be an array or an object: %w", err)
+		}
+		return params, nil
+	}
+
+	var named map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &named); err != nil {
+		return nil, fmt.Errorf("params must be an array or an object: %w", err)
+	}
+
+	for idx, name := range names {
+		value, ok := named[name]
+		if !ok {
+			continue
+		}
+		for len(params) <= idx {
+			params = append(params, nil)
+		}
+		params[idx] = value
+		delete(named, name)
+	}
+
+	for name := range named {
+		return nil, fmt.Errorf("unknown named parameter %s", name)
+	}
+
+	return params, nil
+}
+
+// param decodes optional parameter into value, value isn't changed if parameter is missing or null.
+func param(params []json.RawMessage, idx int, value any) error {
+	if idx >= len(params) || params[idx] == nil || bytes.Equal(params[idx], []byte("null")) {
+		return nil
+	}
+
+	if err := json.Unmarshal(params[idx], value); err != nil {
+		return &Error{Code: codeTypeError, Message: fmt.Sprintf("parameter %d: %s", idx+1, err)}
+	}
+
+	return nil
+}
+
+func requiredParam(params []json.RawMessage, idx int, name string, value any) error {
+	if idx >= len(params) || params[idx] == nil || bytes.Equal(params[idx], []byte("null")) {
+		return &Error{Code: codeInvalidParameter, Message: fmt.Sprintf("missing required parameter %s", name)}
+	}
+
+	return param(params, idx, value)
+}
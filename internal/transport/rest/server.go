@@ -0,0 +1,252 @@
+package rest
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/domains/transaction"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/metrics"
+	"github.com/tatun2000/golang-lib/pkg/validator"
+	"github.com/tatun2000/golang-lib/pkg/wrap"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+type (
+	IWalletService interface {
+		GetWalletAddress(ctx context.Context) (result string, err error)
+		GetWalletBalance(ctx context.Context) (confirmed, unconfirmed int64, err error)
+		GetWalletUTXOs(ctx context.Context) (result entities.TxOutputs, err error)
+		GetHistory(ctx context.Context) (result []entities.HistoryEntry, err error)
+		PrepareSend(ctx context.Context, address string, amount int64, subtractFee bool) (preview entities.TxPreview, err error)
+		Broadcast(ctx context.Context, preview entities.TxPreview) (txid string, err error)
+		Release(preview entities.TxPreview)
+		SignPSBT(ctx context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (result string, signed int, complete bool, err error)
+	}
+
+	Server struct {
+		walletService IWalletService
+		token         string
+		server        *http.Server
+	}
+)
+
+// NewServer creates REST API server, every request except OpenAPI spec and metrics must have header
+// "Authorization: Bearer <token>".
+func NewServer(walletService IWalletService, listen, token string) *Server {
+	s := &Server{
+		walletService: walletService,
+		token:         token,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/openapi.yaml", s.handleOpenAPI)
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.Handle("GET /api/v1/address", s.authorize(s.handleAddress))
+	mux.Handle("GET /api/v1/balance", s.authorize(s.handleBalance))
+	mux.Handle("GET /api/v1/utxos", s.authorize(s.handleUTXOs))
+	mux.Handle("GET /api/v1/history", s.authorize(s.handleHistory))
+	mux.Handle("POST /api/v1/send", s.authorize(s.handleSend))
+	mux.Handle("POST /api/v1/psbt/sign", s.authorize(s.handleSignPSBT))
+
+	s.server = &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// Run serves requests until ctx is done, then waits for active requests.
+func (s *Server) Run(ctx context.Context) (err error) {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errCh:
+		return wrap.Wrap(err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err = s.server.Shutdown(shutdownCtx); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s *Server) authorize(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec) //nolint:errcheck // client can be disconnected
+}
+
+func (s *Server) handleAddress(w http.ResponseWriter, r *http.Request) {
+	address, err := s.walletService.GetWalletAddress(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entities.AddressResult{Address: address})
+}
+
+func (s *Server) handleBalance(w http.ResponseWriter, r *http.Request) {
+	confirmed, unconfirmed, err := s.walletService.GetWalletBalance(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entities.BalanceResult{
+		Confirmed:   confirmed,
+		Unconfirmed: unconfirmed,
+	})
+}
+
+func (s *Server) handleUTXOs(w http.ResponseWriter, r *http.Request) {
+	utxos, err := s.walletService.GetWalletUTXOs(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, utxos)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := s.walletService.GetHistory(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
+	var req entities.SendRequest
+	if err := readRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateTestnetAddress(req.Address); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	preview, err := s.walletService.PrepareSend(r.Context(), req.Address, req.Amount, req.SubtractFee)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	result := entities.SendResult{Transaction: preview}
+	if req.DryRun {
+		s.walletService.Release(preview)
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	if _, err = s.walletService.Broadcast(r.Context(), preview); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	result.Broadcasted = true
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleSignPSBT(w http.ResponseWriter, r *http.Request) {
+	var req entities.SignPSBTRequest
+	if err := readRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sigHashType, err := transaction.ParseSigHashType(req.SighashType)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	signedPSBT, signed, complete, err := s.walletService.SignPSBT(r.Context(), req.PSBT, sigHashType, req.Finalize)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entities.SignPSBTResult{
+		PSBT:     signedPSBT,
+		Signed:   signed,
+		Complete: complete,
+	})
+}
+
+// readRequest decodes JSON body and validates it.
+func readRequest(w http.ResponseWriter, r *http.Request, req any) (err error) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(req); err != nil {
+		return wrap.Wrap(fmt.Errorf("invalid JSON body: %w", err))
+	}
+
+	if err = validator.Validator.StructCtx(r.Context(), req); err != nil {
+		return wrap.Wrap(err)
+	}
+
+	return nil
+}
+
+func validateTestnetAddress(address string) (err error) {
+	decoded, err := btcutil.DecodeAddress(address, &chaincfg.TestNet3Params)
+	if err != nil || !decoded.IsForNet(&chaincfg.TestNet3Params) {
+		return wrap.Wrap(fmt.Errorf("invalid testnet address %q", address))
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result) //nolint:errcheck // client can be disconnected
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		slog.Error("REST request failed", "status", status, "error", err)
+	}
+	writeJSON(w, status, entities.ErrorResult{Error: err.Error()})
+}
@@ -0,0 +1,257 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/tatun2000/bitcoin-testnet-wallet/internal/entities"
+)
+
+const (
+	testToken        = "secret"
+	recipientAddress = "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+	mainnetAddress   = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+)
+
+var errWallet = errors.New("wallet error")
+
+// fakeWallet returns err from every call and records sends and signed PSBTs.
+type fakeWallet struct {
+	err          error
+	broadcastErr error
+
+	prepared    []entities.SendRequest
+	broadcasted []entities.TxPreview
+	released    []entities.TxPreview
+	sigHashType txscript.SigHashType
+}
+
+func (f *fakeWallet) GetWalletAddress(context.Context) (string, error) {
+	return recipientAddress, f.err
+}
+
+func (f *fakeWallet) GetWalletBalance(context.Context) (confirmed, unconfirmed int64, err error) {
+	return 1_000, 200, f.err
+}
+
+func (f *fakeWallet) GetWalletUTXOs(context.Context) (entities.TxOutputs, error) {
+	return entities.TxOutputs{{TxID: "aa", Vout: 1, Value: 1_000}}, f.err
+}
+
+func (f *fakeWallet) GetHistory(context.Context) ([]entities.HistoryEntry, error) {
+	return []entities.HistoryEntry{{TxID: "aa"}}, f.err
+}
+
+func (f *fakeWallet) PrepareSend(_ context.Context, address string, amount int64, subtractFee bool) (entities.TxPreview, error) {
+	f.prepared = append(f.prepared, entities.SendRequest{Address: address, Amount: amount, SubtractFee: subtractFee})
+	return entities.TxPreview{TxID: "bb", Fee: 141}, f.err
+}
+
+func (f *fakeWallet) Broadcast(_ context.Context, preview entities.TxPreview) (string, error) {
+	f.broadcasted = append(f.broadcasted, preview)
+	return preview.TxID, f.broadcastErr
+}
+
+func (f *fakeWallet) Release(preview entities.TxPreview) {
+	f.released = append(f.released, preview)
+}
+
+func (f *fakeWallet) SignPSBT(_ context.Context, b64PSBT string, sigHashType txscript.SigHashType, finalize bool) (string, int, bool, error) {
+	f.sigHashType = sigHashType
+	return b64PSBT, 1, finalize, f.err
+}
+
+// call sends request to the server handler and decodes JSON response into result if it is set.
+func call(t *testing.T, s *Server, method, path, token, body string, result any) (status int) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(recorder, req)
+
+	if result != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, recorder.Body.String(), err)
+		}
+	}
+
+	return recorder.Code
+}
+
+func TestAuthorization(t *testing.T) {
+	s := NewServer(&fakeWallet{}, "", testToken)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/address"},
+		{http.MethodGet, "/api/v1/balance"},
+		{http.MethodGet, "/api/v1/utxos"},
+		{http.MethodGet, "/api/v1/history"},
+		{http.MethodPost, "/api/v1/send"},
+		{http.MethodPost, "/api/v1/psbt/sign"},
+	} {
+		for _, token := range []string{"", "wrong", testToken + "x"} {
+			var result entities.ErrorResult
+			if status := call(t, s, route.method, route.path, token, "{}", &result); status != http.StatusUnauthorized || result.Error == "" {
+				t.Fatalf("%s %s with token %q: status %d, error %q, want 401", route.method, route.path, token, status, result.Error)
+			}
+		}
+	}
+
+	// the spec and metrics don't need token
+	for _, path := range []string{"/api/v1/openapi.yaml", "/metrics"} {
+		if status := call(t, s, http.MethodGet, path, "", "", nil); status != http.StatusOK {
+			t.Fatalf("GET %s: status %d, want 200", path, status)
+		}
+	}
+}
+
+func TestReadHandlers(t *testing.T) {
+	wallet := &fakeWallet{}
+	s := NewServer(wallet, "", testToken)
+
+	var address entities.AddressResult
+	if status := call(t, s, http.MethodGet, "/api/v1/address", testToken, "", &address); status != http.StatusOK || address.Address != recipientAddress {
+		t.Fatalf("address: status %d, result %+v", status, address)
+	}
+	var balance entities.BalanceResult
+	if status := call(t, s, http.MethodGet, "/api/v1/balance", testToken, "", &balance); status != http.StatusOK || balance != (entities.BalanceResult{Confirmed: 1_000, Unconfirmed: 200}) {
+		t.Fatalf("balance: status %d, result %+v", status, balance)
+	}
+	var utxos entities.TxOutputs
+	if status := call(t, s, http.MethodGet, "/api/v1/utxos", testToken, "", &utxos); status != http.StatusOK || len(utxos) != 1 || utxos[0].Vout != 1 {
+		t.Fatalf("utxos: status %d, result %+v", status, utxos)
+	}
+	var history []entities.HistoryEntry
+	if status := call(t, s, http.MethodGet, "/api/v1/history", testToken, "", &history); status != http.StatusOK || len(history) != 1 {
+		t.Fatalf("history: status %d, result %+v", status, history)
+	}
+
+	// wallet errors are internal errors
+	wallet.err = errWallet
+	for _, path := range []string{"/api/v1/address", "/api/v1/balance", "/api/v1/utxos", "/api/v1/history"} {
+		var result entities.ErrorResult
+		if status := call(t, s, http.MethodGet, path, testToken, "", &result); status != http.StatusInternalServerError || result.Error != errWallet.Error() {
+			t.Fatalf("GET %s: status %d, error %q, want 500", path, status, result.Error)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		body          string
+		err           error
+		broadcastErr  error
+		wantStatus    int
+		wantPrepared  bool
+		wantBroadcast bool
+		wantReleased  bool
+	}{
+		{
+			name:          "broadcasted",
+			body:          `{"address":"` + recipientAddress + `","amount":1000,"subtract_fee":true}`,
+			wantStatus:    http.StatusOK,
+			wantPrepared:  true,
+			wantBroadcast: true,
+		},
+		{
+			name:         "dry run releases UTXOs",
+			body:         `{"address":"` + recipientAddress + `","amount":1000,"dry_run":true}`,
+			wantStatus:   http.StatusOK,
+			wantPrepared: true,
+			wantReleased: true,
+		},
+		{name: "invalid JSON", body: `{"address":`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", body: `{"address":"` + recipientAddress + `","amount":1000,"fee":1}`, wantStatus: http.StatusBadRequest},
+		{name: "missing address", body: `{"amount":1000}`, wantStatus: http.StatusBadRequest},
+		{name: "zero amount", body: `{"address":"` + recipientAddress + `","amount":0}`, wantStatus: http.StatusBadRequest},
+		{name: "mainnet address", body: `{"address":"` + mainnetAddress + `","amount":1000}`, wantStatus: http.StatusBadRequest},
+		{
+			name:         "wallet error",
+			body:         `{"address":"` + recipientAddress + `","amount":1000}`,
+			err:          errWallet,
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantPrepared: true,
+		},
+		{
+			name:          "broadcast error",
+			body:          `{"address":"` + recipientAddress + `","amount":1000}`,
+			broadcastErr:  errWallet,
+			wantStatus:    http.StatusBadGateway,
+			wantPrepared:  true,
+			wantBroadcast: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			wallet := &fakeWallet{err: tc.err, broadcastErr: tc.broadcastErr}
+			s := NewServer(wallet, "", testToken)
+
+			var result entities.SendResult
+			status := call(t, s, http.MethodPost, "/api/v1/send", testToken, tc.body, &result)
+			if status != tc.wantStatus {
+				t.Fatalf("status %d, want %d", status, tc.wantStatus)
+			}
+			if prepared := len(wallet.prepared) == 1; prepared != tc.wantPrepared {
+				t.Fatalf("prepared %+v, want %t", wallet.prepared, tc.wantPrepared)
+			}
+			if broadcasted := len(wallet.broadcasted) == 1; broadcasted != tc.wantBroadcast {
+				t.Fatalf("broadcasted %+v, want %t", wallet.broadcasted, tc.wantBroadcast)
+			}
+			if released := len(wallet.released) == 1; released != tc.wantReleased {
+				t.Fatalf("released %+v, want %t", wallet.released, tc.wantReleased)
+			}
+			if status == http.StatusOK && (result.Transaction.TxID != "bb" || result.Broadcasted != tc.wantBroadcast) {
+				t.Fatalf("result is %+v", result)
+			}
+		})
+	}
+
+	// the request is passed as is
+	wallet := &fakeWallet{}
+	call(t, NewServer(wallet, "", testToken), http.MethodPost, "/api/v1/send", testToken, `{"address":"`+recipientAddress+`","amount":1000,"subtract_fee":true}`, nil)
+	if want := (entities.SendRequest{Address: recipientAddress, Amount: 1000, SubtractFee: true}); wallet.prepared[0] != want {
+		t.Fatalf("prepared %+v, want %+v", wallet.prepared[0], want)
+	}
+}
+
+func TestSignPSBT(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		want       txscript.SigHashType
+	}{
+		{name: "default sighash", body: `{"psbt":"cHNidP8=","finalize":true}`, wantStatus: http.StatusOK, want: txscript.SigHashAll},
+		{name: "allowed sighash", body: `{"psbt":"cHNidP8=","sighash_type":"NONE|ANYONECANPAY"}`, wantStatus: http.StatusOK, want: txscript.SigHashNone | txscript.SigHashAnyOneCanPay},
+		{name: "invalid sighash", body: `{"psbt":"cHNidP8=","sighash_type":"ANYONECANPAY"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing PSBT", body: `{"finalize":true}`, wantStatus: http.StatusBadRequest},
+		{name: "not base64", body: `{"psbt":"not base64"}`, wantStatus: http.StatusBadRequest},
+		{name: "wallet error", body: `{"psbt":"cHNidP8="}`, err: errWallet, wantStatus: http.StatusUnprocessableEntity, want: txscript.SigHashAll},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			wallet := &fakeWallet{err: tc.err}
+			s := NewServer(wallet, "", testToken)
+
+			var result entities.SignPSBTResult
+			if status := call(t, s, http.MethodPost, "/api/v1/psbt/sign", testToken, tc.body, &result); status != tc.wantStatus {
+				t.Fatalf("status %d, want %d", status, tc.wantStatus)
+			}
+			if wallet.sigHashType != tc.want {
+				t.Fatalf("signed with sighash %#x, want %#x", wallet.sigHashType, tc.want)
+			}
+			if tc.wantStatus == http.StatusOK && (result.PSBT != "cHNidP8=" || result.Signed != 1) {
+				t.Fatalf("result is %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,1516 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: wallet/v1/wallet.proto
+
+package walletv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TxDirection int32
+
+const (
+	TxDirection_TX_DIRECTION_UNSPECIFIED   TxDirection = 0
+	TxDirection_TX_DIRECTION_INCOMING      TxDirection = 1
+	TxDirection_TX_DIRECTION_OUTGOING      TxDirection = 2
+	TxDirection_TX_DIRECTION_SELF_TRANSFER TxDirection = 3
+)
+
+// Enum value maps for TxDirection.
+var (
+	TxDirection_name = map[int32]string{
+		0: "TX_DIRECTION_UNSPECIFIED",
+		1: "TX_DIRECTION_INCOMING",
+		2: "TX_DIRECTION_OUTGOING",
+		3: "TX_DIRECTION_SELF_TRANSFER",
+	}
+	TxDirection_value = map[string]int32{
+		"TX_DIRECTION_UNSPECIFIED":   0,
+		"TX_DIRECTION_INCOMING":      1,
+		"TX_DIRECTION_OUTGOING":      2,
+		"TX_DIRECTION_SELF_TRANSFER": 3,
+	}
+)
+
+func (x TxDirection) Enum() *TxDirection {
+	p := new(TxDirection)
+	*p = x
+	return p
+}
+
+func (x TxDirection) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TxDirection) Descriptor() protoreflect.EnumDescriptor {
+	return file_wallet_v1_wallet_proto_enumTypes[0].Descriptor()
+}
+
+func (TxDirection) Type() protoreflect.EnumType {
+	return &file_wallet_v1_wallet_proto_enumTypes[0]
+}
+
+func (x TxDirection) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TxDirection.Descriptor instead.
+func (TxDirection) EnumDescriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{0}
+}
+
+type TxState int32
+
+const (
+	TxState_TX_STATE_UNSPECIFIED TxState = 0
+	TxState_TX_STATE_MEMPOOL     TxState = 1
+	TxState_TX_STATE_CONFIRMED   TxState = 2
+	TxState_TX_STATE_REPLACED    TxState = 3
+	TxState_TX_STATE_DROPPED     TxState = 4
+)
+
+// Enum value maps for TxState.
+var (
+	TxState_name = map[int32]string{
+		0: "TX_STATE_UNSPECIFIED",
+		1: "TX_STATE_MEMPOOL",
+		2: "TX_STATE_CONFIRMED",
+		3: "TX_STATE_REPLACED",
+		4: "TX_STATE_DROPPED",
+	}
+	TxState_value = map[string]int32{
+		"TX_STATE_UNSPECIFIED": 0,
+		"TX_STATE_MEMPOOL":     1,
+		"TX_STATE_CONFIRMED":   2,
+		"TX_STATE_REPLACED":    3,
+		"TX_STATE_DROPPED":     4,
+	}
+)
+
+func (x TxState) Enum() *TxState {
+	p := new(TxState)
+	*p = x
+	return p
+}
+
+func (x TxState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TxState) Descriptor() protoreflect.EnumDescriptor {
+	return file_wallet_v1_wallet_proto_enumTypes[1].Descriptor()
+}
+
+func (TxState) Type() protoreflect.EnumType {
+	return &file_wallet_v1_wallet_proto_enumTypes[1]
+}
+
+func (x TxState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TxState.Descriptor instead.
+func (TxState) EnumDescriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{1}
+}
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED     EventType = 0
+	EventType_EVENT_TYPE_NEW_TRANSACTION EventType = 1
+	EventType_EVENT_TYPE_CONFIRMATION    EventType = 2
+	EventType_EVENT_TYPE_BALANCE_CHANGE  EventType = 3
+	// Sent transaction is replaced by another one (RBF).
+	EventType_EVENT_TYPE_REPLACED EventType = 4
+	// Sent transaction is evicted from mempool.
+	EventType_EVENT_TYPE_DROPPED EventType = 5
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_NEW_TRANSACTION",
+		2: "EVENT_TYPE_CONFIRMATION",
+		3: "EVENT_TYPE_BALANCE_CHANGE",
+		4: "EVENT_TYPE_REPLACED",
+		5: "EVENT_TYPE_DROPPED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED":     0,
+		"EVENT_TYPE_NEW_TRANSACTION": 1,
+		"EVENT_TYPE_CONFIRMATION":    2,
+		"EVENT_TYPE_BALANCE_CHANGE":  3,
+		"EVENT_TYPE_REPLACED":        4,
+		"EVENT_TYPE_DROPPED":         5,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_wallet_v1_wallet_proto_enumTypes[2].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_wallet_v1_wallet_proto_enumTypes[2]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{2}
+}
+
+type GetAddressRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAddressRequest) Reset() {
+	*x = GetAddressRequest{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAddressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAddressRequest) ProtoMessage() {}
+
+func (x *GetAddressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAddressRequest.ProtoReflect.Descriptor instead.
+func (*GetAddressRequest) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{0}
+}
+
+type GetAddressResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAddressResponse) Reset() {
+	*x = GetAddressResponse{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAddressResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAddressResponse) ProtoMessage() {}
+
+func (x *GetAddressResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAddressResponse.ProtoReflect.Descriptor instead.
+func (*GetAddressResponse) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetAddressResponse) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type GetBalanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceRequest) Reset() {
+	*x = GetBalanceRequest{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceRequest) ProtoMessage() {}
+
+func (x *GetBalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetBalanceRequest) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{2}
+}
+
+type GetBalanceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Confirmed     int64                  `protobuf:"varint,1,opt,name=confirmed,proto3" json:"confirmed,omitempty"`
+	Unconfirmed   int64                  `protobuf:"varint,2,opt,name=unconfirmed,proto3" json:"unconfirmed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceResponse) Reset() {
+	*x = GetBalanceResponse{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceResponse) ProtoMessage() {}
+
+func (x *GetBalanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceResponse.ProtoReflect.Descriptor instead.
+func (*GetBalanceResponse) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetBalanceResponse) GetConfirmed() int64 {
+	if x != nil {
+		return x.Confirmed
+	}
+	return 0
+}
+
+func (x *GetBalanceResponse) GetUnconfirmed() int64 {
+	if x != nil {
+		return x.Unconfirmed
+	}
+	return 0
+}
+
+type TxStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Confirmed     bool                   `protobuf:"varint,1,opt,name=confirmed,proto3" json:"confirmed,omitempty"`
+	BlockHeight   int64                  `protobuf:"varint,2,opt,name=block_height,json=blockHeight,proto3" json:"block_height,omitempty"`
+	BlockHash     string                 `protobuf:"bytes,3,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockTime     int64                  `protobuf:"varint,4,opt,name=block_time,json=blockTime,proto3" json:"block_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxStatus) Reset() {
+	*x = TxStatus{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxStatus) ProtoMessage() {}
+
+func (x *TxStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxStatus.ProtoReflect.Descriptor instead.
+func (*TxStatus) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TxStatus) GetConfirmed() bool {
+	if x != nil {
+		return x.Confirmed
+	}
+	return false
+}
+
+func (x *TxStatus) GetBlockHeight() int64 {
+	if x != nil {
+		return x.BlockHeight
+	}
+	return 0
+}
+
+func (x *TxStatus) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *TxStatus) GetBlockTime() int64 {
+	if x != nil {
+		return x.BlockTime
+	}
+	return 0
+}
+
+type UTXO struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Txid          string                 `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	Vout          uint32                 `protobuf:"varint,2,opt,name=vout,proto3" json:"vout,omitempty"`
+	Value         int64                  `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	Status        *TxStatus              `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UTXO) Reset() {
+	*x = UTXO{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UTXO) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UTXO) ProtoMessage() {}
+
+func (x *UTXO) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UTXO.ProtoReflect.Descriptor instead.
+func (*UTXO) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UTXO) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *UTXO) GetVout() uint32 {
+	if x != nil {
+		return x.Vout
+	}
+	return 0
+}
+
+func (x *UTXO) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *UTXO) GetStatus() *TxStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type ListUTXOsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUTXOsRequest) Reset() {
+	*x = ListUTXOsRequest{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUTXOsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUTXOsRequest) ProtoMessage() {}
+
+func (x *ListUTXOsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUTXOsRequest.ProtoReflect.Descriptor instead.
+func (*ListUTXOsRequest) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{6}
+}
+
+type ListUTXOsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Utxos         []*UTXO                `protobuf:"bytes,1,rep,name=utxos,proto3" json:"utxos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUTXOsResponse) Reset() {
+	*x = ListUTXOsResponse{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUTXOsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUTXOsResponse) ProtoMessage() {}
+
+func (x *ListUTXOsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUTXOsResponse.ProtoReflect.Descriptor instead.
+func (*ListUTXOsResponse) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListUTXOsResponse) GetUtxos() []*UTXO {
+	if x != nil {
+		return x.Utxos
+	}
+	return nil
+}
+
+type HistoryEntry struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Txid  string                 `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	// Unix time, 0 if unknown.
+	Time int64 `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	// Wallet balance change, negative for outgoing.
+	Amount        int64       `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Direction     TxDirection `protobuf:"varint,4,opt,name=direction,proto3,enum=wallet.v1.TxDirection" json:"direction,omitempty"`
+	Fee           int64       `protobuf:"varint,5,opt,name=fee,proto3" json:"fee,omitempty"`
+	Confirmations int64       `protobuf:"varint,6,opt,name=confirmations,proto3" json:"confirmations,omitempty"`
+	State         TxState     `protobuf:"varint,7,opt,name=state,proto3,enum=wallet.v1.TxState" json:"state,omitempty"`
+	ReplacedBy    string      `protobuf:"bytes,8,opt,name=replaced_by,json=replacedBy,proto3" json:"replaced_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HistoryEntry) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetDirection() TxDirection {
+	if x != nil {
+		return x.Direction
+	}
+	return TxDirection_TX_DIRECTION_UNSPECIFIED
+}
+
+func (x *HistoryEntry) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetConfirmations() int64 {
+	if x != nil {
+		return x.Confirmations
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetState() TxState {
+	if x != nil {
+		return x.State
+	}
+	return TxState_TX_STATE_UNSPECIFIED
+}
+
+func (x *HistoryEntry) GetReplacedBy() string {
+	if x != nil {
+		return x.ReplacedBy
+	}
+	return ""
+}
+
+type GetHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryRequest) Reset() {
+	*x = GetHistoryRequest{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryRequest) ProtoMessage() {}
+
+func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{9}
+}
+
+type GetHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*HistoryEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryResponse) Reset() {
+	*x = GetHistoryResponse{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryResponse) ProtoMessage() {}
+
+func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetHistoryResponse) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type SendRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Amount  int64                  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Recipient receives amount - fee.
+	SubtractFee bool `protobuf:"varint,3,opt,name=subtract_fee,json=subtractFee,proto3" json:"subtract_fee,omitempty"`
+	// Build and sign transaction without broadcasting.
+	DryRun        bool `protobuf:"varint,4,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendRequest) Reset() {
+	*x = SendRequest{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendRequest) ProtoMessage() {}
+
+func (x *SendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendRequest.ProtoReflect.Descriptor instead.
+func (*SendRequest) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SendRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *SendRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *SendRequest) GetSubtractFee() bool {
+	if x != nil {
+		return x.SubtractFee
+	}
+	return false
+}
+
+func (x *SendRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type TxInput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Txid          string                 `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	Vout          uint32                 `protobuf:"varint,2,opt,name=vout,proto3" json:"vout,omitempty"`
+	Address       string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Value         int64                  `protobuf:"varint,4,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxInput) Reset() {
+	*x = TxInput{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxInput) ProtoMessage() {}
+
+func (x *TxInput) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxInput.ProtoReflect.Descriptor instead.
+func (*TxInput) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TxInput) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *TxInput) GetVout() uint32 {
+	if x != nil {
+		return x.Vout
+	}
+	return 0
+}
+
+func (x *TxInput) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *TxInput) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type TxOutput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Value         int64                  `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	IsChange      bool                   `protobuf:"varint,3,opt,name=is_change,json=isChange,proto3" json:"is_change,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxOutput) Reset() {
+	*x = TxOutput{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxOutput) ProtoMessage() {}
+
+func (x *TxOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxOutput.ProtoReflect.Descriptor instead.
+func (*TxOutput) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TxOutput) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *TxOutput) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *TxOutput) GetIsChange() bool {
+	if x != nil {
+		return x.IsChange
+	}
+	return false
+}
+
+type SendResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Txid    string                 `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	Inputs  []*TxInput             `protobuf:"bytes,2,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	Outputs []*TxOutput            `protobuf:"bytes,3,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	Vsize   int64                  `protobuf:"varint,4,opt,name=vsize,proto3" json:"vsize,omitempty"`
+	Fee     int64                  `protobuf:"varint,5,opt,name=fee,proto3" json:"fee,omitempty"`
+	// sat/vbyte
+	FeeRate       float64 `protobuf:"fixed64,6,opt,name=fee_rate,json=feeRate,proto3" json:"fee_rate,omitempty"`
+	Hex           string  `protobuf:"bytes,7,opt,name=hex,proto3" json:"hex,omitempty"`
+	Broadcasted   bool    `protobuf:"varint,8,opt,name=broadcasted,proto3" json:"broadcasted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendResponse) Reset() {
+	*x = SendResponse{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendResponse) ProtoMessage() {}
+
+func (x *SendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendResponse.ProtoReflect.Descriptor instead.
+func (*SendResponse) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SendResponse) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *SendResponse) GetInputs() []*TxInput {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *SendResponse) GetOutputs() []*TxOutput {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *SendResponse) GetVsize() int64 {
+	if x != nil {
+		return x.Vsize
+	}
+	return 0
+}
+
+func (x *SendResponse) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *SendResponse) GetFeeRate() float64 {
+	if x != nil {
+		return x.FeeRate
+	}
+	return 0
+}
+
+func (x *SendResponse) GetHex() string {
+	if x != nil {
+		return x.Hex
+	}
+	return ""
+}
+
+func (x *SendResponse) GetBroadcasted() bool {
+	if x != nil {
+		return x.Broadcasted
+	}
+	return false
+}
+
+type SignPSBTRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// PSBT in base64.
+	Psbt          string `protobuf:"bytes,1,opt,name=psbt,proto3" json:"psbt,omitempty"`
+	Finalize      bool   `protobuf:"varint,2,opt,name=finalize,proto3" json:"finalize,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignPSBTRequest) Reset() {
+	*x = SignPSBTRequest{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignPSBTRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignPSBTRequest) ProtoMessage() {}
+
+func (x *SignPSBTRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignPSBTRequest.ProtoReflect.Descriptor instead.
+func (*SignPSBTRequest) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SignPSBTRequest) GetPsbt() string {
+	if x != nil {
+		return x.Psbt
+	}
+	return ""
+}
+
+func (x *SignPSBTRequest) GetFinalize() bool {
+	if x != nil {
+		return x.Finalize
+	}
+	return false
+}
+
+type SignPSBTResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Psbt  string                 `protobuf:"bytes,1,opt,name=psbt,proto3" json:"psbt,omitempty"`
+	// Number of inputs signed by the wallet.
+	Signed int32 `protobuf:"varint,2,opt,name=signed,proto3" json:"signed,omitempty"`
+	// All inputs are finalized.
+	Complete      bool `protobuf:"varint,3,opt,name=complete,proto3" json:"complete,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignPSBTResponse) Reset() {
+	*x = SignPSBTResponse{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignPSBTResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignPSBTResponse) ProtoMessage() {}
+
+func (x *SignPSBTResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignPSBTResponse.ProtoReflect.Descriptor instead.
+func (*SignPSBTResponse) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SignPSBTResponse) GetPsbt() string {
+	if x != nil {
+		return x.Psbt
+	}
+	return ""
+}
+
+func (x *SignPSBTResponse) GetSigned() int32 {
+	if x != nil {
+		return x.Signed
+	}
+	return 0
+}
+
+func (x *SignPSBTResponse) GetComplete() bool {
+	if x != nil {
+		return x.Complete
+	}
+	return false
+}
+
+type SubscribeEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Event types to receive, all types if empty.
+	Types         []EventType `protobuf:"varint,1,rep,packed,name=types,proto3,enum=wallet.v1.EventType" json:"types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeEventsRequest) Reset() {
+	*x = SubscribeEventsRequest{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeEventsRequest) ProtoMessage() {}
+
+func (x *SubscribeEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeEventsRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeEventsRequest) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SubscribeEventsRequest) GetTypes() []EventType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type WalletEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  EventType              `protobuf:"varint,1,opt,name=type,proto3,enum=wallet.v1.EventType" json:"type,omitempty"`
+	// Detection time, Unix.
+	Time int64 `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	// Transaction events only.
+	Txid          string `protobuf:"bytes,3,opt,name=txid,proto3" json:"txid,omitempty"`
+	Amount        int64  `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Confirmations int64  `protobuf:"varint,5,opt,name=confirmations,proto3" json:"confirmations,omitempty"`
+	// Balance change event only.
+	Balance *GetBalanceResponse `protobuf:"bytes,6,opt,name=balance,proto3" json:"balance,omitempty"`
+	// Replaced event only.
+	ReplacedBy    string `protobuf:"bytes,7,opt,name=replaced_by,json=replacedBy,proto3" json:"replaced_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WalletEvent) Reset() {
+	*x = WalletEvent{}
+	mi := &file_wallet_v1_wallet_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WalletEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WalletEvent) ProtoMessage() {}
+
+func (x *WalletEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_wallet_v1_wallet_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WalletEvent.ProtoReflect.Descriptor instead.
+func (*WalletEvent) Descriptor() ([]byte, []int) {
+	return file_wallet_v1_wallet_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *WalletEvent) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *WalletEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *WalletEvent) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *WalletEvent) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *WalletEvent) GetConfirmations() int64 {
+	if x != nil {
+		return x.Confirmations
+	}
+	return 0
+}
+
+func (x *WalletEvent) GetBalance() *GetBalanceResponse {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *WalletEvent) GetReplacedBy() string {
+	if x != nil {
+		return x.ReplacedBy
+	}
+	return ""
+}
+
+var File_wallet_v1_wallet_proto protoreflect.FileDescriptor
+
+const file_wallet_v1_wallet_proto_rawDesc = "" +
+	"\n" +
+	"\x16wallet/v1/wallet.proto\x12\twallet.v1\"\x13\n" +
+	"\x11GetAddressRequest\".\n" +
+	"\x12GetAddressResponse\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\x13\n" +
+	"\x11GetBalanceRequest\"T\n" +
+	"\x12GetBalanceResponse\x12\x1c\n" +
+	"\tconfirmed\x18\x01 \x01(\x03R\tconfirmed\x12 \n" +
+	"\vunconfirmed\x18\x02 \x01(\x03R\vunconfirmed\"\x89\x01\n" +
+	"\bTxStatus\x12\x1c\n" +
+	"\tconfirmed\x18\x01 \x01(\bR\tconfirmed\x12!\n" +
+	"\fblock_height\x18\x02 \x01(\x03R\vblockHeight\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\x03 \x01(\tR\tblockHash\x12\x1d\n" +
+	"\n" +
+	"block_time\x18\x04 \x01(\x03R\tblockTime\"q\n" +
+	"\x04UTXO\x12\x12\n" +
+	"\x04txid\x18\x01 \x01(\tR\x04txid\x12\x12\n" +
+	"\x04vout\x18\x02 \x01(\rR\x04vout\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x03R\x05value\x12+\n" +
+	"\x06status\x18\x04 \x01(\v2\x13.wallet.v1.TxStatusR\x06status\"\x12\n" +
+	"\x10ListUTXOsRequest\":\n" +
+	"\x11ListUTXOsResponse\x12%\n" +
+	"\x05utxos\x18\x01 \x03(\v2\x0f.wallet.v1.UTXOR\x05utxos\"\x87\x02\n" +
+	"\fHistoryEntry\x12\x12\n" +
+	"\x04txid\x18\x01 \x01(\tR\x04txid\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x03R\x06amount\x124\n" +
+	"\tdirection\x18\x04 \x01(\x0e2\x16.wallet.v1.TxDirectionR\tdirection\x12\x10\n" +
+	"\x03fee\x18\x05 \x01(\x03R\x03fee\x12$\n" +
+	"\rconfirmations\x18\x06 \x01(\x03R\rconfirmations\x12(\n" +
+	"\x05state\x18\a \x01(\x0e2\x12.wallet.v1.TxStateR\x05state\x12\x1f\n" +
+	"\vreplaced_by\x18\b \x01(\tR\n" +
+	"replacedBy\"\x13\n" +
+	"\x11GetHistoryRequest\"G\n" +
+	"\x12GetHistoryResponse\x121\n" +
+	"\aentries\x18\x01 \x03(\v2\x17.wallet.v1.HistoryEntryR\aentries\"{\n" +
+	"\vSendRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\x12!\n" +
+	"\fsubtract_fee\x18\x03 \x01(\bR\vsubtractFee\x12\x17\n" +
+	"\adry_run\x18\x04 \x01(\bR\x06dryRun\"a\n" +
+	"\aTxInput\x12\x12\n" +
+	"\x04txid\x18\x01 \x01(\tR\x04txid\x12\x12\n" +
+	"\x04vout\x18\x02 \x01(\rR\x04vout\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\x03R\x05value\"W\n" +
+	"\bTxOutput\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value\x12\x1b\n" +
+	"\tis_change\x18\x03 \x01(\bR\bisChange\"\xf4\x01\n" +
+	"\fSendResponse\x12\x12\n" +
+	"\x04txid\x18\x01 \x01(\tR\x04txid\x12*\n" +
+	"\x06inputs\x18\x02 \x03(\v2\x12.wallet.v1.TxInputR\x06inputs\x12-\n" +
+	"\aoutputs\x18\x03 \x03(\v2\x13.wallet.v1.TxOutputR\aoutputs\x12\x14\n" +
+	"\x05vsize\x18\x04 \x01(\x03R\x05vsize\x12\x10\n" +
+	"\x03fee\x18\x05 \x01(\x03R\x03fee\x12\x19\n" +
+	"\bfee_rate\x18\x06 \x01(\x01R\afeeRate\x12\x10\n" +
+	"\x03hex\x18\a \x01(\tR\x03hex\x12 \n" +
+	"\vbroadcasted\x18\b \x01(\bR\vbroadcasted\"A\n" +
+	"\x0fSignPSBTRequest\x12\x12\n" +
+	"\x04psbt\x18\x01 \x01(\tR\x04psbt\x12\x1a\n" +
+	"\bfinalize\x18\x02 \x01(\bR\bfinalize\"Z\n" +
+	"\x10SignPSBTResponse\x12\x12\n" +
+	"\x04psbt\x18\x01 \x01(\tR\x04psbt\x12\x16\n" +
+	"\x06signed\x18\x02 \x01(\x05R\x06signed\x12\x1a\n" +
+	"\bcomplete\x18\x03 \x01(\bR\bcomplete\"D\n" +
+	"\x16SubscribeEventsRequest\x12*\n" +
+	"\x05types\x18\x01 \x03(\x0e2\x14.wallet.v1.EventTypeR\x05types\"\xf7\x01\n" +
+	"\vWalletEvent\x12(\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x14.wallet.v1.EventTypeR\x04type\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time\x12\x12\n" +
+	"\x04txid\x18\x03 \x01(\tR\x04txid\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x03R\x06amount\x12$\n" +
+	"\rconfirmations\x18\x05 \x01(\x03R\rconfirmations\x127\n" +
+	"\abalance\x18\x06 \x01(\v2\x1d.wallet.v1.GetBalanceResponseR\abalance\x12\x1f\n" +
+	"\vreplaced_by\x18\a \x01(\tR\n" +
+	"replacedBy*\x81\x01\n" +
+	"\vTxDirection\x12\x1c\n" +
+	"\x18TX_DIRECTION_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15TX_DIRECTION_INCOMING\x10\x01\x12\x19\n" +
+	"\x15TX_DIRECTION_OUTGOING\x10\x02\x12\x1e\n" +
+	"\x1aTX_DIRECTION_SELF_TRANSFER\x10\x03*~\n" +
+	"\aTxState\x12\x18\n" +
+	"\x14TX_STATE_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10TX_STATE_MEMPOOL\x10\x01\x12\x16\n" +
+	"\x12TX_STATE_CONFIRMED\x10\x02\x12\x15\n" +
+	"\x11TX_STATE_REPLACED\x10\x03\x12\x14\n" +
+	"\x10TX_STATE_DROPPED\x10\x04*\xb4\x01\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aEVENT_TYPE_NEW_TRANSACTION\x10\x01\x12\x1b\n" +
+	"\x17EVENT_TYPE_CONFIRMATION\x10\x02\x12\x1d\n" +
+	"\x19EVENT_TYPE_BALANCE_CHANGE\x10\x03\x12\x17\n" +
+	"\x13EVENT_TYPE_REPLACED\x10\x04\x12\x16\n" +
+	"\x12EVENT_TYPE_DROPPED\x10\x052\x86\x04\n" +
+	"\rWalletService\x12I\n" +
+	"\n" +
+	"GetAddress\x12\x1c.wallet.v1.GetAddressRequest\x1a\x1d.wallet.v1.GetAddressResponse\x12I\n" +
+	"\n" +
+	"GetBalance\x12\x1c.wallet.v1.GetBalanceRequest\x1a\x1d.wallet.v1.GetBalanceResponse\x12F\n" +
+	"\tListUTXOs\x12\x1b.wallet.v1.ListUTXOsRequest\x1a\x1c.wallet.v1.ListUTXOsResponse\x12I\n" +
+	"\n" +
+	"GetHistory\x12\x1c.wallet.v1.GetHistoryRequest\x1a\x1d.wallet.v1.GetHistoryResponse\x127\n" +
+	"\x04Send\x12\x16.wallet.v1.SendRequest\x1a\x17.wallet.v1.SendResponse\x12C\n" +
+	"\bSignPSBT\x12\x1a.wallet.v1.SignPSBTRequest\x1a\x1b.wallet.v1.SignPSBTResponse\x12N\n" +
+	"\x0fSubscribeEvents\x12!.wallet.v1.SubscribeEventsRequest\x1a\x16.wallet.v1.WalletEvent0\x01BHZFgithub.com/tatun2000/bitcoin-testnet-wallet/pkg/api/wallet/v1;walletv1b\x06proto3"
+
+var (
+	file_wallet_v1_wallet_proto_rawDescOnce sync.Once
+	file_wallet_v1_wallet_proto_rawDescData []byte
+)
+
+func file_wallet_v1_wallet_proto_rawDescGZIP() []byte {
+	file_wallet_v1_wallet_proto_rawDescOnce.Do(func() {
+		file_wallet_v1_wallet_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_wallet_v1_wallet_proto_rawDesc), len(file_wallet_v1_wallet_proto_rawDesc)))
+	})
+	return file_wallet_v1_wallet_proto_rawDescData
+}
+
+var file_wallet_v1_wallet_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_wallet_v1_wallet_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_wallet_v1_wallet_proto_goTypes = []any{
+	(TxDirection)(0),               // 0: wallet.v1.TxDirection
+	(TxState)(0),                   // 1: wallet.v1.TxState
+	(EventType)(0),                 // 2: wallet.v1.EventType
+	(*GetAddressRequest)(nil),      // 3: wallet.v1.GetAddressRequest
+	(*GetAddressResponse)(nil),     // 4: wallet.v1.GetAddressResponse
+	(*GetBalanceRequest)(nil),      // 5: wallet.v1.GetBalanceRequest
+	(*GetBalanceResponse)(nil),     // 6: wallet.v1.GetBalanceResponse
+	(*TxStatus)(nil),               // 7: wallet.v1.TxStatus
+	(*UTXO)(nil),                   // 8: wallet.v1.UTXO
+	(*ListUTXOsRequest)(nil),       // 9: wallet.v1.ListUTXOsRequest
+	(*ListUTXOsResponse)(nil),      // 10: wallet.v1.ListUTXOsResponse
+	(*HistoryEntry)(nil),           // 11: wallet.v1.HistoryEntry
+	(*GetHistoryRequest)(nil),      // 12: wallet.v1.GetHistoryRequest
+	(*GetHistoryResponse)(nil),     // 13: wallet.v1.GetHistoryResponse
+	(*SendRequest)(nil),            // 14: wallet.v1.SendRequest
+	(*TxInput)(nil),                // 15: wallet.v1.TxInput
+	(*TxOutput)(nil),               // 16: wallet.v1.TxOutput
+	(*SendResponse)(nil),           // 17: wallet.v1.SendResponse
+	(*SignPSBTRequest)(nil),        // 18: wallet.v1.SignPSBTRequest
+	(*SignPSBTResponse)(nil),       // 19: wallet.v1.SignPSBTResponse
+	(*SubscribeEventsRequest)(nil), // 20: wallet.v1.SubscribeEventsRequest
+	(*WalletEvent)(nil),            // 21: wallet.v1.WalletEvent
+}
+var file_wallet_v1_wallet_proto_depIdxs = []int32{
+	7,  // 0: wallet.v1.UTXO.status:type_name -> wallet.v1.TxStatus
+	8,  // 1: wallet.v1.ListUTXOsResponse.utxos:type_name -> wallet.v1.UTXO
+	0,  // 2: wallet.v1.HistoryEntry.direction:type_name -> wallet.v1.TxDirection
+	1,  // 3: wallet.v1.HistoryEntry.state:type_name -> wallet.v1.TxState
+	11, // 4: wallet.v1.GetHistoryResponse.entries:type_name -> wallet.v1.HistoryEntry
+	15, // 5: wallet.v1.SendResponse.inputs:type_name -> wallet.v1.TxInput
+	16, // 6: wallet.v1.SendResponse.outputs:type_name -> wallet.v1.TxOutput
+	2,  // 7: wallet.v1.SubscribeEventsRequest.types:type_name -> wallet.v1.EventType
+	2,  // 8: wallet.v1.WalletEvent.type:type_name -> wallet.v1.EventType
+	6,  // 9: wallet.v1.WalletEvent.balance:type_name -> wallet.v1.GetBalanceResponse
+	3,  // 10: wallet.v1.WalletService.GetAddress:input_type -> wallet.v1.GetAddressRequest
+	5,  // 11: wallet.v1.WalletService.GetBalance:input_type -> wallet.v1.GetBalanceRequest
+	9,  // 12: wallet.v1.WalletService.ListUTXOs:input_type -> wallet.v1.ListUTXOsRequest
+	12, // 13: wallet.v1.WalletService.GetHistory:input_type -> wallet.v1.GetHistoryRequest
+	14, // 14: wallet.v1.WalletService.Send:input_type -> wallet.v1.SendRequest
+	18, // 15: wallet.v1.WalletService.SignPSBT:input_type -> wallet.v1.SignPSBTRequest
+	20, // 16: wallet.v1.WalletService.SubscribeEvents:input_type -> wallet.v1.SubscribeEventsRequest
+	4,  // 17: wallet.v1.WalletService.GetAddress:output_type -> wallet.v1.GetAddressResponse
+	6,  // 18: wallet.v1.WalletService.GetBalance:output_type -> wallet.v1.GetBalanceResponse
+	10, // 19: wallet.v1.WalletService.ListUTXOs:output_type -> wallet.v1.ListUTXOsResponse
+	13, // 20: wallet.v1.WalletService.GetHistory:output_type -> wallet.v1.GetHistoryResponse
+	17, // 21: wallet.v1.WalletService.Send:output_type -> wallet.v1.SendResponse
+	19, // 22: wallet.v1.WalletService.SignPSBT:output_type -> wallet.v1.SignPSBTResponse
+	21, // 23: wallet.v1.WalletService.SubscribeEvents:output_type -> wallet.v1.WalletEvent
+	17, // [17:24] is the sub-list for method output_type
+	10, // [10:17] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_wallet_v1_wallet_proto_init() }
+func file_wallet_v1_wallet_proto_init() {
+	if File_wallet_v1_wallet_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_wallet_v1_wallet_proto_rawDesc), len(file_wallet_v1_wallet_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_wallet_v1_wallet_proto_goTypes,
+		DependencyIndexes: file_wallet_v1_wallet_proto_depIdxs,
+		EnumInfos:         file_wallet_v1_wallet_proto_enumTypes,
+		MessageInfos:      file_wallet_v1_wallet_proto_msgTypes,
+	}.Build()
+	File_wallet_v1_wallet_proto = out.File
+	file_wallet_v1_wallet_proto_goTypes = nil
+	file_wallet_v1_wallet_proto_depIdxs = nil
+}